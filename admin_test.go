@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Vikuuu/Chirpy/internal/api"
+	"github.com/Vikuuu/Chirpy/internal/config"
+	"github.com/Vikuuu/Chirpy/internal/seed"
+	"github.com/Vikuuu/Chirpy/internal/service"
+)
+
+func TestBannedWords(t *testing.T) {
+	h := newHarness(t)
+	u := h.signUp("walt@example.com")
+	admin := h.signUp("admin@example.com").as(service.RoleAdmin)
+	moderator := h.signUp("mod@example.com").as(service.RoleModerator)
+
+	var words []api.Word
+	admin.do("GET", "/admin/words").expect(http.StatusOK).decode(&words)
+	assert.Len(t, words, 3)
+
+	var word api.Word
+	admin.do("POST", "/admin/words").json(api.WordRequest{Word: "Meth"}).expect(http.StatusOK).decode(&word)
+	assert.Equal(t, "meth", word.Word)
+	assert.Equal(t, "mask", word.Action, "words are masked unless told otherwise")
+	assert.Equal(t, "blue ****", u.post("blue meth").Body)
+
+	admin.do("POST", "/admin/words").json(api.WordRequest{Word: "meth", Action: "flag"}).expect(http.StatusOK)
+	assert.Equal(t, "cooking meth again", u.post("cooking meth again").Body, "flagged words are kept")
+	var reports []api.Report
+	moderator.do("GET", "/admin/reports").expect(http.StatusOK).decode(&reports)
+	require.Len(t, reports, 1, "and the chirp queued for review")
+	assert.Equal(t, "profanity", reports[0].Reason)
+
+	admin.do("DELETE", "/admin/words/METH").expect(http.StatusNoContent)
+	assert.Equal(t, "the meth is gone", u.post("the meth is gone").Body)
+	admin.do("DELETE", "/admin/words/meth").expect(http.StatusNotFound)
+
+	tests := []struct {
+		name     string
+		caller   *user
+		method   string
+		path     string
+		body     any
+		expected int
+	}{
+		{name: "List As User", caller: u, method: "GET", path: "/admin/words", expected: http.StatusForbidden},
+		{name: "List As Moderator", caller: moderator, method: "GET", path: "/admin/words", expected: http.StatusForbidden},
+		{name: "Put As Moderator", caller: moderator, method: "POST", path: "/admin/words", body: api.WordRequest{Word: "meth"}, expected: http.StatusForbidden},
+		{name: "Delete As User", caller: u, method: "DELETE", path: "/admin/words/fornax", expected: http.StatusForbidden},
+		{name: "Missing Word", caller: admin, method: "POST", path: "/admin/words", body: api.WordRequest{}, expected: http.StatusBadRequest},
+		{name: "Unknown Action", caller: admin, method: "POST", path: "/admin/words", body: api.WordRequest{Word: "meth", Action: "shout"}, expected: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.caller.do(tt.method, tt.path)
+			if tt.body != nil {
+				req.json(tt.body)
+			}
+			req.expect(tt.expected).problem()
+		})
+	}
+}
+
+func TestBannedWordsReloadAcrossInstances(t *testing.T) {
+	h := newHarness(t)
+	admin := h.signUp("admin@example.com").as(service.RoleAdmin)
+	other := newTestConfig(t)
+	other.useStore(h.cfg.db)
+	other.wordsReloadInterval = 10 * time.Millisecond
+	require.NoError(t, other.loadBannedWords(context.Background()))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		other.reloadBannedWords(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	admin.do("POST", "/admin/words").json(api.WordRequest{Word: "meth"}).expect(http.StatusOK)
+	assert.Eventually(t, func() bool {
+		return other.filter.Apply("blue meth").Text == "blue ****"
+	}, 5*time.Second, 10*time.Millisecond, "an edit through one instance reaches the others")
+
+	admin.do("DELETE", "/admin/words/meth").expect(http.StatusNoContent)
+	assert.Eventually(t, func() bool {
+		return other.filter.Apply("blue meth").Text == "blue meth"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestPolkaWebhook(t *testing.T) {
+	h := newHarness(t)
+	u := h.signUp("walt@example.com")
+
+	upgrade := func(id uuid.UUID) api.PolkaWebhook {
+		var w api.PolkaWebhook
+		w.Event = "user.upgraded"
+		w.Data.UserID = id
+		return w
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		body     any
+		expected int
+	}{
+		{name: "No Key", body: upgrade(u.ID), expected: http.StatusUnauthorized},
+		{name: "Wrong Key", key: "nope", body: upgrade(u.ID), expected: http.StatusUnauthorized},
+		{name: "Other Event", key: "polka-key", body: api.PolkaWebhook{Event: "user.payment_failed"}, expected: http.StatusNoContent},
+		{name: "Unknown User", key: "polka-key", body: upgrade(uuid.New()), expected: http.StatusNotFound},
+		{name: "Malformed Body", key: "polka-key", body: `{"event":`, expected: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := h.do("POST", "/api/polka/webhooks").json(tt.body)
+			if tt.key != "" {
+				req.apiKey(tt.key)
+			}
+			req.expect(tt.expected)
+		})
+	}
+
+	assert.False(t, u.login().IsChirpyRed)
+	h.do("POST", "/api/polka/webhooks").apiKey("polka-key").json(upgrade(u.ID)).expect(http.StatusNoContent)
+	assert.True(t, u.login().IsChirpyRed)
+}
+
+// reset asks for a confirmation token for params and resets with it.
+func (u *user) reset(query string, params api.ResetRequest) api.ResetSummary {
+	var confirmation api.ResetConfirmation
+	u.do("POST", "/admin/reset"+query).json(params).expect(http.StatusAccepted).decode(&confirmation)
+	params.Confirm = confirmation.ConfirmationToken
+	var summary api.ResetSummary
+	u.do("POST", "/admin/reset"+query).json(params).expect(http.StatusOK).decode(&summary)
+	return summary
+}
+
+func TestReset(t *testing.T) {
+	h := newHarness(t)
+	admin := h.signUp("admin@example.com").as(service.RoleAdmin)
+	walt := h.signUp("walt@example.com")
+	jesse := h.signUp("jesse@example.org")
+	walt.post("remember my name")
+	jesse.post("yeah science")
+
+	h.do("POST", "/admin/reset").json(api.ResetRequest{Scope: "all"}).expect(http.StatusUnauthorized)
+	walt.do("POST", "/admin/reset").json(api.ResetRequest{Scope: "all"}).expect(http.StatusForbidden)
+
+	var confirmation api.ResetConfirmation
+	admin.do("POST", "/admin/reset").json(api.ResetRequest{Scope: "chirps"}).expect(http.StatusAccepted).decode(&confirmation)
+	assert.Equal(t, "chirps", confirmation.Scope)
+	assert.NotEmpty(t, confirmation.ConfirmationToken)
+	assert.True(t, confirmation.ExpiresAt.After(time.Now()))
+	assert.Len(t, h.chirps(nil, "/api/chirps"), 2, "nothing is deleted without confirmation")
+
+	p := admin.do("POST", "/admin/reset").
+		json(api.ResetRequest{Scope: "all", Confirm: confirmation.ConfirmationToken}).
+		expect(http.StatusBadRequest).
+		problem()
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, "confirm", p.Errors[0].Field, "a token only confirms the scope it was made for")
+	assert.Len(t, h.chirps(nil, "/api/chirps"), 2)
+
+	summary := admin.reset("", api.ResetRequest{Scope: "chirps"})
+	assert.Equal(t, api.ResetCounts{Chirps: 2}, summary.Deleted)
+	assert.Nil(t, summary.Metrics)
+	assert.Empty(t, h.chirps(nil, "/api/chirps"))
+
+	summary = admin.reset("", api.ResetRequest{Scope: "tokens"})
+	assert.Equal(t, int64(3), summary.Deleted.RefreshTokens)
+	h.do("POST", "/api/refresh").bearer(walt.RefreshToken).expect(http.StatusUnauthorized)
+	walt.login()
+	admin.login()
+
+	jesse.post("magnets!")
+	summary = admin.reset("", api.ResetRequest{Scope: "users", Pattern: "*@example.org"})
+	assert.Equal(t, api.ResetCounts{Users: 1, Chirps: 1}, summary.Deleted)
+	assert.Equal(t, "*@example.org", summary.Pattern)
+	h.do("POST", "/api/login").json(api.LoginRequest{Email: jesse.Email, Password: testPassword}).expect(http.StatusUnauthorized)
+	walt.login()
+
+	h.do("GET", "/app/").expect(http.StatusOK)
+	summary = admin.reset("", api.ResetRequest{Scope: "metrics"})
+	assert.Equal(t, api.ResetCounts{}, summary.Deleted)
+	assert.Equal(t, &api.ResetMetrics{FileserverHits: 1}, summary.Metrics)
+	assert.Zero(t, h.cfg.fileserverHits.Load())
+
+	summary = admin.reset("", api.ResetRequest{Scope: "all"})
+	assert.Equal(t, int64(1), summary.Deleted.Users, "the admin resetting stays")
+	h.do("POST", "/api/login").json(api.LoginRequest{Email: walt.Email, Password: testPassword}).expect(http.StatusUnauthorized)
+	h.register("walt@example.com")
+	admin.login()
+	summary = admin.reset("", api.ResetRequest{Scope: "all"})
+	assert.Equal(t, int64(1), summary.Deleted.Users, "the same admin can reset again")
+	summary = admin.reset("", api.ResetRequest{Scope: "users", Pattern: "*@example.com"})
+	assert.Zero(t, summary.Deleted.Users, "not even when their email matches")
+	admin.login()
+
+	prod := newHarness(t, func(cfg *apiConfig) { cfg.platform = config.PlatformProduction })
+	prodAdmin := prod.signUp("admin@example.com").as(service.RoleAdmin)
+	p = prodAdmin.do("POST", "/admin/reset").json(api.ResetRequest{Scope: "all"}).expect(http.StatusForbidden).problem()
+	assert.Equal(t, codeForbidden, p.Code)
+	prodAdmin.login()
+}
+
+func TestResetErrors(t *testing.T) {
+	h := newHarness(t)
+	admin := h.signUp("admin@example.com").as(service.RoleAdmin)
+
+	tests := []struct {
+		name   string
+		query  string
+		params api.ResetRequest
+		field  string
+	}{
+		{name: "Missing Scope", params: api.ResetRequest{}, field: "scope"},
+		{name: "Unknown Scope", params: api.ResetRequest{Scope: "everything"}, field: "scope"},
+		{name: "Users Without Pattern", params: api.ResetRequest{Scope: "users"}, field: "pattern"},
+		{name: "Bad Pattern", params: api.ResetRequest{Scope: "users", Pattern: "["}, field: "pattern"},
+		{name: "Pattern Outside Users", params: api.ResetRequest{Scope: "chirps", Pattern: "*"}, field: "pattern"},
+		{name: "Bad Confirmation", params: api.ResetRequest{Scope: "all", Confirm: "123.abc"}, field: "confirm"},
+		{name: "Seed Outside All", query: "?seed=1", params: api.ResetRequest{Scope: "chirps"}, field: "seed"},
+		{name: "Seed Not A Number", query: "?seed=abc", params: api.ResetRequest{Scope: "all"}, field: "seed"},
+		{name: "Negative Seed Users", query: "?seed=1&users=-1", params: api.ResetRequest{Scope: "all"}, field: "users"},
+		{name: "Too Many Seed Chirps", query: "?seed=1&chirps=1000000", params: api.ResetRequest{Scope: "all"}, field: "chirps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := admin.do("POST", "/admin/reset"+tt.query).json(tt.params).expect(http.StatusBadRequest).problem()
+			assert.Equal(t, codeValidationFailed, p.Code)
+			require.Len(t, p.Errors, 1)
+			assert.Equal(t, tt.field, p.Errors[0].Field)
+		})
+	}
+	admin.login()
+}
+
+func TestResetWithSeed(t *testing.T) {
+	seeded := func(h *harness) api.Seeded {
+		admin := h.signUp("admin@example.com").as(service.RoleAdmin)
+		summary := admin.reset("?seed=42&users=10&chirps=50", api.ResetRequest{Scope: "all"})
+		require.NotNil(t, summary.Seeded)
+		return *summary.Seeded
+	}
+	h := newHarness(t)
+	h.signUp("walt@example.com")
+
+	got := seeded(h)
+	assert.Equal(t, api.Seeded{Seed: 42, Users: 10, ChirpyRed: got.ChirpyRed, Follows: got.Follows, Chirps: 50, Password: seed.DefaultPassword}, got)
+	chirps := h.chirps(nil, "/api/chirps")
+	assert.Len(t, chirps, 50)
+	h.do("POST", "/api/login").json(api.LoginRequest{Email: "walt@example.com", Password: testPassword}).expect(http.StatusUnauthorized)
+	sample := seed.Generate(seed.Options{Seed: 42, Users: 10}).Users[0]
+	h.do("POST", "/api/login").json(api.LoginRequest{Email: sample.Email, Password: seed.DefaultPassword}).expect(http.StatusOK)
+
+	other := newHarness(t)
+	assert.Equal(t, got, seeded(other))
+	assert.Equal(t, chirps, other.chirps(nil, "/api/chirps"), "a seed always makes the same data")
+}
+
+func TestStaticAndHealth(t *testing.T) {
+	h := newHarness(t)
+	require.NoError(t, os.WriteFile(filepath.Join(h.root, "index.html"), []byte("<h1>Chirpy</h1>"), 0o644))
+
+	h.do("GET", "/app/").expect(http.StatusOK)
+	h.do("GET", "/app/missing.html").expect(http.StatusNotFound)
+	assert.Equal(t, int32(2), h.cfg.fileserverHits.Load())
+
+	for _, path := range []string{"/api/livez", "/api/readyz", "/api/healthz", "/metrics", "/admin/metrics"} {
+		h.do("GET", path).expect(http.StatusOK)
+	}
+}
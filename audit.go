@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/api"
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/service"
+)
+
+// withOrigin records where the request came from in its context, for the
+// audit events the service records while handling it.
+func (cfg *apiConfig) withOrigin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := service.WithOrigin(r.Context(), service.Origin{
+			IP:        cfg.clientIP(r),
+			UserAgent: r.UserAgent(),
+			RequestID: requestIDFrom(r.Context()),
+		})
+		next(w, r.WithContext(ctx))
+	}
+}
+
+func (cfg *apiConfig) handlerListAuditEvents(w http.ResponseWriter, r *http.Request) error {
+	if _, err := cfg.authorizeRole(r, service.RoleAdmin); err != nil {
+		return err
+	}
+
+	q, err := auditQuery(r.URL.Query())
+	if err != nil {
+		return err
+	}
+	page, err := cfg.svc.AuditEvents(r.Context(), q)
+	if err != nil {
+		return err
+	}
+
+	return respondWithJSON(w, http.StatusOK, api.NewAuditEvents(page.Events, page.Next))
+}
+
+// handlerExportAuditEvents streams every audit event the filters of
+// GET /admin/audit-events select as JSON lines, newest first. However long
+// the log, each query has queryTimeout, and each page the server's write
+// timeout, to finish in.
+func (cfg *apiConfig) handlerExportAuditEvents(queryTimeout time.Duration) apiHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		authCtx := r.Context()
+		if queryTimeout > 0 {
+			var cancel context.CancelFunc
+			authCtx, cancel = context.WithTimeout(authCtx, queryTimeout)
+			defer cancel()
+		}
+		if _, err := cfg.authorizeRole(r.WithContext(authCtx), service.RoleAdmin); err != nil {
+			return err
+		}
+
+		q, err := auditQuery(r.URL.Query())
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-events-%s.jsonl"`, time.Now().UTC().Format("20060102T150405Z")))
+		rc := http.NewResponseController(w)
+		enc := json.NewEncoder(w)
+		written := false
+		err = cfg.svc.ExportAuditEvents(r.Context(), q, queryTimeout, func(events []database.AuditEvent) error {
+			if cfg.writeTimeout > 0 {
+				err := rc.SetWriteDeadline(time.Now().Add(cfg.writeTimeout))
+				if err != nil && !errors.Is(err, http.ErrNotSupported) {
+					return fmt.Errorf("extending write deadline: %w", err)
+				}
+			}
+			for _, e := range events {
+				written = true
+				if err := enc.Encode(api.NewAuditEvent(e)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		switch {
+		case err == nil || !written:
+			return err
+		default:
+			// Too late for an error response: cut the download short, so
+			// that the client can tell it is incomplete.
+			slog.ErrorContext(r.Context(), "exporting audit events", "err", err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+// auditQuery parses the filters of the audit event endpoints.
+func auditQuery(values url.Values) (service.AuditQuery, error) {
+	var fields []fieldError
+	q := service.AuditQuery{
+		Action:     values.Get("action"),
+		TargetType: values.Get("target_type"),
+		TargetID:   values.Get("target_id"),
+		Cursor:     values.Get("cursor"),
+	}
+	if s := values.Get("actor_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			fields = append(fields, fieldError{Field: "actor_id", Code: "invalid_id", Message: "actor_id is not a valid id"})
+		}
+		q.ActorID = uuid.NullUUID{UUID: id, Valid: err == nil}
+	}
+	parseTime := func(name string, into *time.Time) {
+		s := values.Get(name)
+		if s == "" {
+			return
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			fields = append(fields, fieldError{Field: name, Code: "invalid_time", Message: name + " must be an RFC 3339 time such as 2024-01-02T15:04:05Z"})
+			return
+		}
+		*into = t
+	}
+	parseTime("since", &q.Since)
+	parseTime("until", &q.Until)
+	if s := values.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			fields = append(fields, fieldError{
+				Field:   "limit",
+				Code:    "out_of_range",
+				Message: fmt.Sprintf("limit must be a number from 1 to %d", service.MaxAuditLimit),
+			})
+		}
+		q.Limit = n
+	}
+	if len(fields) > 0 {
+		return service.AuditQuery{}, errFields(fields...)
+	}
+	return q, nil
+}
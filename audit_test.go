@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Vikuuu/Chirpy/internal/api"
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/service"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// auditEvents lists the audit events query selects, as admin.
+func (u *user) auditEvents(query url.Values) api.AuditEvents {
+	u.h.t.Helper()
+	var page api.AuditEvents
+	u.do("GET", "/admin/audit-events?"+query.Encode()).expect(http.StatusOK).decode(&page)
+	return page
+}
+
+func actions(events []api.AuditEvent) []string {
+	var out []string
+	for _, e := range events {
+		out = append(out, e.Action)
+	}
+	return out
+}
+
+func TestAuditLog(t *testing.T) {
+	h := newHarness(t)
+	admin := h.signUp("admin@example.com").as(service.RoleAdmin)
+	walt := h.signUp("walt@example.com")
+
+	h.do("POST", "/api/login").json(api.LoginRequest{Email: "walt@example.com", Password: "not-it"}).expect(http.StatusUnauthorized)
+	h.do("POST", "/api/login").json(api.LoginRequest{Email: "nobody@example.com", Password: "not-it"}).expect(http.StatusUnauthorized)
+	walt.do("PUT", "/api/users").
+		json(api.UpdateUserRequest{Email: "heisenberg@example.com", Password: "new-password"}).
+		expect(http.StatusOK)
+	h.do("POST", "/api/refresh").bearer(walt.RefreshToken).expect(http.StatusOK)
+	h.do("POST", "/api/revoke").bearer(walt.RefreshToken).expect(http.StatusNoContent)
+	c := walt.post("say my name")
+	walt.do("DELETE", "/api/chirps/"+c.ID.String()).expect(http.StatusNoContent)
+	var upgrade api.PolkaWebhook
+	upgrade.Event = "user.upgraded"
+	upgrade.Data.UserID = walt.ID
+	h.do("POST", "/api/polka/webhooks").apiKey("polka-key").json(upgrade).expect(http.StatusNoContent)
+	admin.do("POST", "/admin/words").json(api.WordRequest{Word: "meth"}).expect(http.StatusOK)
+
+	byWalt := admin.auditEvents(url.Values{"actor_id": {walt.ID.String()}}).Events
+	assert.Equal(t, []string{
+		service.AuditChirpDelete,
+		service.AuditRevoke,
+		service.AuditRefresh,
+		service.AuditUserUpdate,
+		service.AuditLogin,
+	}, actions(byWalt), "newest first")
+	for _, e := range byWalt {
+		assert.NotEmpty(t, e.IP, e.Action)
+		assert.Equal(t, "Go-http-client/1.1", e.UserAgent, e.Action)
+		assert.NotEmpty(t, e.RequestID, e.Action)
+	}
+	var update struct {
+		Email    struct{ Old, New string }
+		Password struct{ Redacted bool }
+	}
+	require.NoError(t, json.Unmarshal(byWalt[3].Details, &update))
+	assert.NotContains(t, string(byWalt[3].Details), "@example.com", "addresses aren't kept")
+	assert.Len(t, update.Email.New, 16)
+	assert.NotEqual(t, update.Email.Old, update.Email.New)
+	assert.True(t, update.Password.Redacted)
+
+	failed := admin.auditEvents(url.Values{"action": {service.AuditLoginFailed}}).Events
+	require.Len(t, failed, 2)
+	assert.Nil(t, failed[0].ActorID)
+	var details []map[string]string
+	for _, e := range failed {
+		var d map[string]string
+		require.NoError(t, json.Unmarshal(e.Details, &d))
+		assert.NotContains(t, string(e.Details), "@example.com", "addresses aren't kept")
+		assert.Len(t, d["email_fingerprint"], 16)
+		details = append(details, d)
+	}
+	assert.Equal(t, "unknown_email", details[0]["reason"])
+	assert.Equal(t, walt.ID.String(), failed[1].TargetID)
+	assert.Equal(t, "wrong_password", details[1]["reason"])
+	assert.NotEqual(t, details[0]["email_fingerprint"], details[1]["email_fingerprint"])
+	assert.Equal(t, update.Email.Old, details[1]["email_fingerprint"], "the same address has the same fingerprint")
+
+	aboutWalt := admin.auditEvents(url.Values{"target_type": {service.AuditTargetUser}, "target_id": {walt.ID.String()}}).Events
+	assert.Equal(t, []string{service.AuditUserUpgrade, service.AuditUserUpdate, service.AuditLoginFailed}, actions(aboutWalt))
+	assert.Equal(t, []string{service.AuditPutBannedWord}, actions(admin.auditEvents(url.Values{"actor_id": {admin.ID.String()}, "action": {service.AuditPutBannedWord}}).Events))
+
+	// Paging through the log finds every event once.
+	all := admin.auditEvents(url.Values{}).Events
+	var paged []api.AuditEvent
+	query := url.Values{"limit": {"3"}}
+	for {
+		page := admin.auditEvents(query)
+		paged = append(paged, page.Events...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+	assert.Equal(t, all, paged)
+
+	resp := admin.do("GET", "/admin/audit-events/export").expect(http.StatusOK)
+	assert.Equal(t, "application/x-ndjson", resp.header.Get("Content-Type"))
+	assert.Contains(t, resp.header.Get("Content-Disposition"), "attachment")
+	lines := bytes.Split(bytes.TrimSuffix(resp.body, []byte("\n")), []byte("\n"))
+	var exported []api.AuditEvent
+	for _, line := range lines {
+		var e api.AuditEvent
+		require.NoError(t, json.Unmarshal(line, &e), "line: %s", line)
+		exported = append(exported, e)
+	}
+	assert.Equal(t, all, exported)
+	for _, secret := range []string{testPassword, "new-password", "not-it", walt.RefreshToken} {
+		assert.NotContains(t, string(resp.body), secret, "the log never holds secrets")
+	}
+
+	resp = admin.do("GET", "/admin/audit-events/export?action=nothing").expect(http.StatusOK)
+	assert.Empty(t, resp.body)
+}
+
+func TestAuditLogErrors(t *testing.T) {
+	h := newHarness(t)
+	u := h.signUp("walt@example.com")
+	moderator := h.signUp("mod@example.com").as(service.RoleModerator)
+	admin := h.signUp("admin@example.com").as(service.RoleAdmin)
+
+	tests := []struct {
+		name     string
+		caller   *user
+		path     string
+		expected int
+		field    string
+	}{
+		{name: "As User", caller: u, path: "/admin/audit-events", expected: http.StatusForbidden},
+		{name: "As Moderator", caller: moderator, path: "/admin/audit-events", expected: http.StatusForbidden},
+		{name: "Export As Moderator", caller: moderator, path: "/admin/audit-events/export", expected: http.StatusForbidden},
+		{name: "Bad Actor", caller: admin, path: "/admin/audit-events?actor_id=walt", expected: http.StatusBadRequest, field: "actor_id"},
+		{name: "Bad Since", caller: admin, path: "/admin/audit-events?since=yesterday", expected: http.StatusBadRequest, field: "since"},
+		{name: "Until Before Since", caller: admin, path: "/admin/audit-events?since=2024-02-01T00:00:00Z&until=2024-01-01T00:00:00Z", expected: http.StatusBadRequest, field: "until"},
+		{name: "Zero Limit", caller: admin, path: "/admin/audit-events?limit=0", expected: http.StatusBadRequest, field: "limit"},
+		{name: "Limit Too High", caller: admin, path: "/admin/audit-events?limit=5000", expected: http.StatusBadRequest, field: "limit"},
+		{name: "Bad Cursor", caller: admin, path: "/admin/audit-events?cursor=nope", expected: http.StatusBadRequest, field: "cursor"},
+		{name: "Export Bad Cursor", caller: admin, path: "/admin/audit-events/export?cursor=nope", expected: http.StatusBadRequest, field: "cursor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.caller.do("GET", tt.path).expect(tt.expected).problem()
+			if tt.field != "" {
+				require.Len(t, p.Errors, 1)
+				assert.Equal(t, tt.field, p.Errors[0].Field)
+			}
+		})
+	}
+}
+
+// slowAuditStore is a store that takes a while to list audit events.
+type slowAuditStore struct {
+	*store.Memory
+	delay time.Duration
+}
+
+func (s slowAuditStore) ListAuditEvents(ctx context.Context, arg database.ListAuditEventsParams) ([]database.AuditEvent, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(s.delay):
+	}
+	return s.Memory.ListAuditEvents(ctx, arg)
+}
+
+func TestExportLongAuditLog(t *testing.T) {
+	ctx := context.Background()
+	h := newHarness(t)
+	st := slowAuditStore{Memory: store.NewMemory(), delay: 100 * time.Millisecond}
+	h.cfg.useStore(st)
+	admin := h.signUp("admin@example.com").as(service.RoleAdmin)
+
+	// The export takes longer than both timeouts, but no page does.
+	h.cfg.queryTimeouts = queryTimeouts{"": 250 * time.Millisecond}
+	h.cfg.writeTimeout = 250 * time.Millisecond
+	h.srv = httptest.NewUnstartedServer(h.cfg.routes(h.root))
+	h.srv.Config.WriteTimeout = h.cfg.writeTimeout
+	h.srv.Start()
+	t.Cleanup(h.srv.Close)
+	for range 3*service.MaxAuditLimit + 500 {
+		_, err := st.Memory.CreateAuditEvent(ctx, database.CreateAuditEventParams{Action: service.AuditAdminReset, Details: json.RawMessage("{}")})
+		require.NoError(t, err)
+	}
+	all, err := st.Memory.ListAuditEvents(ctx, database.ListAuditEventsParams{Limit: 10 * service.MaxAuditLimit})
+	require.NoError(t, err)
+
+	resp := admin.do("GET", "/admin/audit-events/export").expect(http.StatusOK)
+	lines := bytes.Split(bytes.TrimSuffix(resp.body, []byte("\n")), []byte("\n"))
+	require.Len(t, lines, len(all))
+	var last api.AuditEvent
+	require.NoError(t, json.Unmarshal(lines[len(lines)-1], &last))
+	assert.Equal(t, all[len(all)-1].ID, last.ID)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/auth"
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/logging"
+	"github.com/Vikuuu/Chirpy/internal/service"
+)
+
+// authenticate validates the bearer token on r and loads the user it was
+// issued to. Suspended and banned users are refused even while their token is
+// still valid.
+func (cfg *apiConfig) authenticate(r *http.Request) (database.User, error) {
+	jwtToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return database.User{}, errUnauthorized("Unauthorized", err)
+	}
+
+	userID, err := auth.ValidateJWT(jwtToken, cfg.secret)
+	if err != nil {
+		return database.User{}, errUnauthorized("Unauthorized", err)
+	}
+	logging.Add(r.Context(), slog.String("user_id", userID.String()))
+
+	return cfg.svc.ActiveUser(r.Context(), userID)
+}
+
+// authorizeRole authenticates r and checks that the user holds one of roles.
+func (cfg *apiConfig) authorizeRole(r *http.Request, roles ...string) (database.User, error) {
+	user, err := cfg.authenticate(r)
+	if err != nil {
+		return database.User{}, err
+	}
+
+	if err := service.CheckRole(user, roles...); err != nil {
+		return database.User{}, err
+	}
+	return user, nil
+}
+
+// viewerID returns the user a valid bearer token on r belongs to. Endpoints
+// that are public but personalise results use it; a missing or bad token just
+// means an anonymous viewer.
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.NullUUID {
+	jwtToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+
+	userID, err := auth.ValidateJWT(jwtToken, cfg.secret)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	logging.Add(r.Context(), slog.String("user_id", userID.String()))
+	return uuid.NullUUID{UUID: userID, Valid: true}
+}
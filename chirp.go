@@ -1,247 +1,84 @@
 package main
 
 import (
-	"context"
-	"database/sql"
-	"encoding/json"
-	"log"
 	"net/http"
-	"time"
 
 	"github.com/google/uuid"
 
-	"github.com/Vikuuu/Chirpy/internal/auth"
-	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/api"
+	"github.com/Vikuuu/Chirpy/internal/service"
 )
 
-type parameters struct {
-	Body string `json:"body"`
-	// UserID uuid.UUID `json:"user_id"`
-}
-
-type respBody struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
-}
-
-func (cfg *apiConfig) handlerPostChirp(w http.ResponseWriter, r *http.Request) {
-	jwtToken, err := auth.GetBearerToken(r.Header)
+func (cfg *apiConfig) handlerPostChirp(w http.ResponseWriter, r *http.Request) error {
+	user, err := cfg.authenticate(r)
 	if err != nil {
-		log.Printf("error getting jwtToken: %s", err)
-		respondWithError(w, 401, "Unauthorized")
-		return
+		return err
 	}
 
-	userID, err := auth.ValidateJWT(jwtToken, cfg.secret)
-	if err != nil {
-		log.Printf("error validating token: %s", err)
-		respondWithError(w, 401, "Unauthorized")
-		return
+	payload := api.CreateChirpRequest{}
+	if err := decodeJSON(w, r, &payload); err != nil {
+		return err
 	}
 
-	decoder := json.NewDecoder(r.Body)
-	payload := parameters{}
-	err = decoder.Decode(&payload)
+	dat, err := cfg.svc.PostChirp(r.Context(), user.ID, payload.Body)
 	if err != nil {
-		log.Printf("Error decoding JSON: %s", err)
-		w.WriteHeader(500)
-		return
-	}
-	if len(payload.Body) > 140 {
-		errResp := "chirp is too long"
-		respondWithError(w, 400, errResp)
-		return
-	}
-	payload.Body = badWordReplacement(payload.Body)
-
-	dat, err := cfg.db.CreateChirpForUser(context.Background(), database.CreateChirpForUserParams{
-		Body:   payload.Body,
-		UserID: userID,
-	})
-	if err != nil {
-		log.Fatalf("Error creating chirp: %s", err)
-		w.WriteHeader(500)
-		return
-	}
-
-	respPayload := respBody{
-		ID:        dat.ID,
-		CreatedAt: dat.CreatedAt,
-		UpdatedAt: dat.UpdatedAt,
-		Body:      dat.Body,
-		UserID:    dat.UserID,
+		return err
 	}
+	cfg.metrics.ChirpCreated()
 
-	respondWithJSON(w, 201, respPayload)
+	return respondWithJSON(w, http.StatusCreated, api.NewChirp(dat))
 }
 
-func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, r *http.Request) {
-	var data []database.Chirp
-	var err error
-	authorIDString := r.URL.Query().Get("author_id")
-	sort := r.URL.Query().Get("sort")
-
-	if authorIDString == "" && sort == "" {
-		// GET http://localhost:8080/api/chirp
-
-		data, err = cfg.db.GetChirps(context.Background())
-		if err != nil {
-			log.Fatalf("Error retrieving chirp: %s", err)
-			w.WriteHeader(500)
-			return
-		}
-	} else if authorIDString != "" && sort == "" {
-		// GET http://localhost:8080/api/chirp?author_id=1
-
-		authorID, err := uuid.Parse(authorIDString)
-		if err != nil {
-			log.Fatalf("Error parsing uuid string: %s", err)
-			w.WriteHeader(500)
-			return
-		}
-		data, err = cfg.db.GetChirpsForAuthor(context.Background(), authorID)
-		if err != nil {
-			log.Fatalf("Error retrieving chirp: %s", err)
-			w.WriteHeader(500)
-			return
-		}
-	} else if authorIDString != "" && sort != "" {
-		// GET http://localhost:8080/api/chirps?sort=asc&author_id=2
-
-		authorID, err := uuid.Parse(authorIDString)
-		if err != nil {
-			log.Fatalf("Error parsing uuid string: %s", err)
-			w.WriteHeader(500)
-			return
-		}
-		data, err = cfg.db.GetSortedChirpsForAuthor(context.Background(), database.GetSortedChirpsForAuthorParams{
-			UserID: authorID,
-			Sort:   sort,
-		})
-		if err != nil {
-			log.Fatalf("Error retrieving chirp: %s", err)
-			w.WriteHeader(500)
-			return
-		}
-	} else if authorIDString == "" && sort != "" {
-		// GET http://localhost:8080/api/chirps?sort=asc
-		// GET http://localhost:8080/api/chirps?sort=desc
-		data, err = cfg.db.GetSortedChirps(context.Background(), sort)
-		if err != nil {
-			log.Fatalf("Error retrieving chirp: %s", err)
-			w.WriteHeader(500)
-			return
-		}
-	} else {
-		log.Fatalf("Unexpected Query Parameters")
-		w.WriteHeader(http.StatusBadRequest)
-		return
+func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, r *http.Request) error {
+	query := service.ChirpQuery{
+		ViewerID: cfg.viewerID(r),
+		Sort:     r.URL.Query().Get("sort"),
 	}
-
-	var resp []respBody
-	for _, chirp := range data {
-		i := respBody{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
+	if s := r.URL.Query().Get("author_id"); s != "" {
+		authorID, err := uuid.Parse(s)
+		if err != nil {
+			return errFields(fieldError{Field: "author_id", Code: "invalid_id", Message: "author_id is not a valid id"})
 		}
-		resp = append(resp, i)
+		query.AuthorID = uuid.NullUUID{UUID: authorID, Valid: true}
 	}
 
-	respondWithJSON(w, 200, resp)
-}
-
-func (cfg *apiConfig) handlerGetChirp(w http.ResponseWriter, r *http.Request) {
-	pat := r.PathValue("chirpID")
-	chirpID, err := uuid.Parse(pat)
-	if err != nil {
-		log.Fatalf("error parsing the id: %s", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	dat, err := cfg.db.GetChirp(context.Background(), chirpID)
+	data, err := cfg.svc.Chirps(r.Context(), query)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			respondWithError(w, 404, "Not Found")
-			return
-		} else {
-			log.Fatalf("error fetching the chirp: %s", err)
-			w.WriteHeader(http.StatusNotFound)
-			return
-		}
-	}
-
-	respPayload := respBody{
-		ID:        dat.ID,
-		CreatedAt: dat.CreatedAt,
-		UpdatedAt: dat.UpdatedAt,
-		Body:      dat.Body,
-		UserID:    dat.UserID,
+		return err
 	}
 
-	respondWithJSON(w, 200, respPayload)
+	return respondWithJSON(w, http.StatusOK, api.NewChirps(data))
 }
 
-func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request) {
+func (cfg *apiConfig) handlerGetChirp(w http.ResponseWriter, r *http.Request) error {
 	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
-		log.Fatalf("error parsing the id: %s", err)
-		w.WriteHeader(500)
-		return
+		return errValidation("chirp id is not a valid id")
 	}
 
-	jwtToken, err := auth.GetBearerToken(r.Header)
+	dat, err := cfg.svc.Chirp(r.Context(), chirpID)
 	if err != nil {
-		log.Printf("error getting jwtToken: %s", err)
-		respondWithError(w, 401, "Unauthorized")
-		return
+		return err
 	}
 
-	userID, err := auth.ValidateJWT(jwtToken, cfg.secret)
-	if err != nil {
-		log.Printf("error validating token: %s", err)
-		respondWithError(w, 401, "Unauthorized")
-		return
-	}
+	return respondWithJSON(w, http.StatusOK, api.NewChirp(dat))
+}
 
-	// check if the author of chirp and the logged in user are same?
-	chirp, err := cfg.db.GetChirp(context.Background(), chirpID)
+func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request) error {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			respondWithError(w, 404, "Not Found")
-			return
-		} else {
-			log.Fatalf("error getting chirp: %s", err)
-			w.WriteHeader(500)
-			return
-		}
+		return errValidation("chirp id is not a valid id")
 	}
 
-	if userID != chirp.UserID {
-		respondWithError(w, 403, "You don't have the permission to delete  this chirp")
-		return
+	user, err := cfg.authenticate(r)
+	if err != nil {
+		return err
 	}
 
-	// if the user is the chirp author
-	err = cfg.db.DeleteChirp(context.Background(), database.DeleteChirpParams{
-		UserID: userID,
-		ID:     chirpID,
-	})
-	if err != nil {
-		if err == sql.ErrNoRows {
-			respondWithError(w, 404, "chirp does not exist")
-			return
-		} else {
-			log.Fatalf("error deleting chirp: %s", err)
-			w.WriteHeader(500)
-			return
-		}
+	if err := cfg.svc.DeleteChirp(r.Context(), user.ID, chirpID); err != nil {
+		return err
 	}
 
 	w.WriteHeader(http.StatusNoContent)
+	return nil
 }
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Vikuuu/Chirpy/internal/api"
+	"github.com/Vikuuu/Chirpy/internal/service"
+)
+
+func TestPostChirp(t *testing.T) {
+	h := newHarness(t)
+	u := h.signUp("walt@example.com")
+
+	c := u.post("I am the one who knocks")
+	assert.Equal(t, "I am the one who knocks", c.Body)
+	assert.Equal(t, u.ID, c.UserID)
+
+	masked := u.post("what a kerfuffle this is")
+	assert.Equal(t, "what a ********* this is", masked.Body)
+
+	tests := []struct {
+		name     string
+		token    string
+		body     any
+		expected int
+		code     string
+	}{
+		{name: "No Token", body: api.CreateChirpRequest{Body: "hello there"}, expected: http.StatusUnauthorized, code: codeUnauthorized},
+		{name: "Bad Token", token: "not-a-jwt", body: api.CreateChirpRequest{Body: "hello there"}, expected: http.StatusUnauthorized, code: codeUnauthorized},
+		{name: "Empty Body", token: u.Token, body: api.CreateChirpRequest{}, expected: http.StatusBadRequest, code: codeValidationFailed},
+		{name: "Too Long", token: u.Token, body: api.CreateChirpRequest{Body: strings.Repeat("a", 141)}, expected: http.StatusBadRequest, code: codeValidationFailed},
+		{name: "Malformed Body", token: u.Token, body: `{"body":`, expected: http.StatusBadRequest, code: codeMalformedBody},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := h.do("POST", "/api/chirps").json(tt.body)
+			if tt.token != "" {
+				req.bearer(tt.token)
+			}
+			p := req.expect(tt.expected).problem()
+			assert.Equal(t, tt.code, p.Code)
+		})
+	}
+}
+
+func TestPostChirpRejectedWord(t *testing.T) {
+	h := newHarness(t)
+	admin := h.signUp("admin@example.com").as(service.RoleAdmin)
+	u := h.signUp("walt@example.com")
+
+	admin.do("POST", "/admin/words").json(api.WordRequest{Word: "heisenberg", Action: "reject"}).expect(http.StatusOK)
+
+	p := u.do("POST", "/api/chirps").
+		json(api.CreateChirpRequest{Body: "say my name: Heisenberg"}).
+		expect(http.StatusUnprocessableEntity).
+		problem()
+	assert.Equal(t, codeProhibitedWords, p.Code)
+	assert.Empty(t, h.chirps(nil, "/api/chirps"))
+}
+
+func TestPostChirpSpam(t *testing.T) {
+	h := newHarness(t)
+	u := h.signUp("walt@example.com")
+	moderator := h.signUp("mod@example.com").as(service.RoleModerator)
+
+	u.post("buy my blue crystals today")
+	u.post("buy my blue crystals today")
+
+	var reports []api.Report
+	moderator.do("GET", "/admin/reports").expect(http.StatusOK).decode(&reports)
+	require.Len(t, reports, 1, "a repeated chirp is queued for review")
+	assert.Equal(t, "spam", reports[0].Reason)
+
+	p := u.do("POST", "/api/chirps").
+		json(api.CreateChirpRequest{Body: "buy my blue crystals today"}).
+		expect(http.StatusUnprocessableEntity).
+		problem()
+	assert.Equal(t, codeSpam, p.Code)
+}
+
+func TestGetChirps(t *testing.T) {
+	h := newHarness(t)
+	walt := h.signUp("walt@example.com")
+	jesse := h.signUp("jesse@example.com")
+
+	walt.post("we need to cook")
+	jesse.post("yeah science!")
+	walt.post("say my name")
+
+	tests := []struct {
+		name     string
+		path     string
+		expected []string
+	}{
+		{name: "All", path: "/api/chirps", expected: []string{"we need to cook", "yeah science!", "say my name"}},
+		{name: "Ascending", path: "/api/chirps?sort=asc", expected: []string{"we need to cook", "yeah science!", "say my name"}},
+		{name: "Descending", path: "/api/chirps?sort=desc", expected: []string{"say my name", "yeah science!", "we need to cook"}},
+		{name: "Author", path: "/api/chirps?author_id=" + walt.ID.String(), expected: []string{"we need to cook", "say my name"}},
+		{name: "Author Descending", path: "/api/chirps?sort=desc&author_id=" + walt.ID.String(), expected: []string{"say my name", "we need to cook"}},
+		{name: "Author Without Chirps", path: "/api/chirps?author_id=" + uuid.NewString(), expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, h.chirps(nil, tt.path))
+		})
+	}
+
+	p := h.do("GET", "/api/chirps?author_id=nope").expect(http.StatusBadRequest).problem()
+	assert.Equal(t, codeValidationFailed, p.Code)
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, "author_id", p.Errors[0].Field)
+}
+
+func TestGetChirp(t *testing.T) {
+	h := newHarness(t)
+	u := h.signUp("walt@example.com")
+	c := u.post("I am the danger")
+
+	var got api.Chirp
+	h.do("GET", "/api/chirps/"+c.ID.String()).expect(http.StatusOK).decode(&got)
+	assert.Equal(t, c, got)
+
+	h.do("GET", "/api/chirps/"+uuid.NewString()).expect(http.StatusNotFound)
+	h.do("GET", "/api/chirps/not-a-uuid").expect(http.StatusBadRequest)
+}
+
+func TestDeleteChirp(t *testing.T) {
+	h := newHarness(t)
+	walt := h.signUp("walt@example.com")
+	jesse := h.signUp("jesse@example.com")
+	c := walt.post("tread lightly")
+	path := "/api/chirps/" + c.ID.String()
+
+	h.do("DELETE", path).expect(http.StatusUnauthorized)
+	p := jesse.do("DELETE", path).expect(http.StatusForbidden).problem()
+	assert.Equal(t, codeForbidden, p.Code)
+
+	walt.do("DELETE", path).expect(http.StatusNoContent)
+	h.do("GET", path).expect(http.StatusNotFound)
+	walt.do("DELETE", path).expect(http.StatusNotFound)
+	walt.do("DELETE", "/api/chirps/not-a-uuid").expect(http.StatusBadRequest)
+}
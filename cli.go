@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/Vikuuu/Chirpy/internal/config"
+	"github.com/Vikuuu/Chirpy/internal/logging"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// command is a subcommand of the chirpy binary. A command either runs or
+// groups further subcommands, as "users" groups "users create".
+type command struct {
+	name  string
+	args  string // positional arguments, for the usage line
+	short string
+	run   func(ctx context.Context, inv *invocation) error
+	sub   []command
+}
+
+// commands are listed in this order by help. The first is the default when
+// no subcommand is given.
+var commands = []command{
+	{name: "serve", short: "serve the API", run: runServe},
+	{name: "migrate", args: "up|down|status|redo", short: "apply or roll back schema migrations", run: runMigrate},
+	{name: "users", short: "manage accounts", sub: []command{
+		{name: "create", short: "create an account", run: runUsersCreate},
+		{name: "list", short: "list accounts", run: runUsersList},
+		{name: "promote", short: "change an account's role", run: runUsersPromote},
+		{name: "suspend", short: "suspend an account for a while", run: runUsersSuspend},
+		{name: "reset-password", short: "set a new password and sign the account out", run: runUsersResetPassword},
+	}},
+	{name: "tokens", short: "manage refresh tokens", sub: []command{
+		{name: "revoke-all", short: "revoke every refresh token of an account", run: runTokensRevokeAll},
+	}},
+	{name: "chirps", short: "manage chirps", sub: []command{
+		{name: "purge", short: "delete every chirp of an account", run: runChirpsPurge},
+	}},
+	{name: "seed", short: "fill a dev database with sample users and chirps", run: runSeed},
+}
+
+// invocation is one run of a command: its parsed arguments and where its
+// output goes.
+type invocation struct {
+	cmd    command
+	args   []string
+	flags  *flag.FlagSet
+	conf   config.Config
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// usageError is a mistake on the command line, reported with the usage.
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+// execute runs the subcommand named by args and returns the process's exit
+// status: 0 on success, 1 if the command failed and 2 if it was misused.
+func execute(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "help" {
+		printUsage(stdout, "chirpy", commands)
+		return 0
+	}
+
+	cmd, path := commands[0], "chirpy"
+	list := commands
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		i := commandIndex(list, args[0])
+		if i < 0 {
+			fmt.Fprintf(stderr, "%s: unknown command %q\n\n", path, args[0])
+			printUsage(stderr, path, list)
+			return 2
+		}
+		cmd, path, args = list[i], path+" "+list[i].name, args[1:]
+		if cmd.sub == nil {
+			break
+		}
+		list = cmd.sub
+	}
+	if cmd.run == nil {
+		fmt.Fprintf(stderr, "%s: missing subcommand\n\n", path)
+		printUsage(stderr, path, cmd.sub)
+		return 2
+	}
+
+	inv := &invocation{
+		cmd:    cmd,
+		args:   args,
+		flags:  flag.NewFlagSet(path, flag.ContinueOnError),
+		stdout: stdout,
+		stderr: stderr,
+	}
+	inv.flags.SetOutput(stderr)
+	inv.flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: %s [flags] %s\n\n%s.\n\nflags:\n", path, cmd.args, cmd.short)
+		inv.flags.PrintDefaults()
+	}
+
+	err := cmd.run(ctx, inv)
+	var usage usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.As(err, &usage):
+		fmt.Fprintf(stderr, "%s: %s\n", path, usage.msg)
+		inv.flags.Usage()
+		return 2
+	default:
+		fmt.Fprintf(stderr, "%s: %s\n", path, err)
+		return 1
+	}
+}
+
+func commandIndex(list []command, name string) int {
+	for i, c := range list {
+		if c.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func printUsage(w io.Writer, path string, list []command) {
+	fmt.Fprintf(w, "usage: %s <command> [flags] [args]\n\ncommands:\n", path)
+	for _, c := range list {
+		fmt.Fprintf(w, "  %-15s %s\n", c.name, c.short)
+	}
+	fmt.Fprintf(w, "\nRun \"%s <command> -h\" for a command's flags.\n", path)
+}
+
+// loadConfig parses the command's flags and the configuration, sets up
+// logging from it, and returns the positional arguments left over. validate
+// checks the part of the configuration the command needs.
+func (inv *invocation) loadConfig(validate func(config.Config) error) ([]string, error) {
+	conf, err := config.Load(inv.flags, inv.args, os.Environ())
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, usageError{err.Error()}
+	}
+	if err := validate(conf); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	inv.conf = conf
+	slog.SetDefault(logging.New(inv.stderr, logging.ParseLevel(conf.Log.Level)))
+	return inv.flags.Args(), nil
+}
+
+func runServe(ctx context.Context, inv *invocation) error {
+	printConfig := inv.flags.Bool("print-config", false, "print the effective configuration and exit")
+	args, err := inv.loadConfig(config.Config.Validate)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usageError{"unexpected arguments: " + strings.Join(args, " ")}
+	}
+	if *printConfig {
+		return inv.conf.Print(inv.stdout)
+	}
+	slog.Info("loaded config", "config", inv.conf)
+	return run(ctx, inv.conf)
+}
+
+// openStore opens the configured store for an operator command, refusing a
+// Postgres schema this build doesn't match. Closing it releases the
+// connection.
+func (inv *invocation) openStore(ctx context.Context) (store.Store, error) {
+	c := inv.conf.Database
+	if c.Store == config.StoreMemory {
+		return nil, errors.New("the memory store would be gone as soon as the command exits")
+	}
+	// Operators migrate with "chirpy migrate", not as a side effect.
+	c.AutoMigrate = false
+	st, _, err := newStore(ctx, c, nil)
+	return st, err
+}
+
+// noArgs parses the command's flags and the database configuration for a
+// command that takes no positional arguments.
+func (inv *invocation) noArgs() error {
+	args, err := inv.loadConfig(config.Config.ValidateDatabase)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usageError{"unexpected arguments: " + strings.Join(args, " ")}
+	}
+	return nil
+}
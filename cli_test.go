@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/service"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+func TestExecuteUsage(t *testing.T) {
+	t.Setenv("DB_URL", "postgres://localhost/chirpy")
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+		output   string
+	}{
+		{name: "Help", args: []string{"help"}, expected: 0, output: "commands:"},
+		{name: "Unknown Command", args: []string{"frobnicate"}, expected: 2, output: `unknown command "frobnicate"`},
+		{name: "Command Help", args: []string{"migrate", "-h"}, expected: 0, output: "usage: chirpy migrate"},
+		{name: "Missing Migration", args: []string{"migrate"}, expected: 2, output: "want exactly one of up, down, status or redo"},
+		{name: "Unknown Migration", args: []string{"migrate", "sideways"}, expected: 2, output: `unknown migration command "sideways"`},
+		{name: "Unknown Flag", args: []string{"migrate", "-frobnicate", "up"}, expected: 2, output: "flag provided but not defined"},
+		{name: "Invalid Config", args: []string{"serve"}, expected: 1, output: "auth.secret"},
+		{name: "Missing Subcommand", args: []string{"users"}, expected: 2, output: "chirpy users: missing subcommand"},
+		{name: "Unknown Subcommand", args: []string{"users", "frobnicate"}, expected: 2, output: `chirpy users: unknown command "frobnicate"`},
+		{name: "Subcommand Help", args: []string{"tokens", "revoke-all", "-h"}, expected: 0, output: "usage: chirpy tokens revoke-all"},
+		{name: "Unknown Role", args: []string{"users", "promote", "-user", "a@example.com", "-role", "king"}, expected: 2, output: `unknown role "king"`},
+		{name: "Invalid Email", args: []string{"users", "create", "-email", "nope"}, expected: 2, output: "email is not a valid email address"},
+		{name: "Long Password", args: []string{"users", "create", "-email", "a@example.com", "-password", strings.Repeat("é", 40)}, expected: 2, output: "password must be at most 72 bytes"},
+		{name: "Suspend Without Period", args: []string{"users", "suspend", "-user", "a@example.com"}, expected: 2, output: "-for must be a positive duration"},
+		{name: "Purge Unconfirmed", args: []string{"chirps", "purge", "-user", "a@example.com"}, expected: 2, output: "pass -yes to confirm"},
+		{name: "Migrate Without Postgres", args: []string{"migrate", "-store", "sqlite", "up"}, expected: 1, output: "only the postgres store is migrated"},
+		{name: "Operator Memory Store", args: []string{"users", "list", "-store", "memory"}, expected: 1, output: "memory store would be gone"},
+		{name: "Seed Outside Dev", args: []string{"seed"}, expected: 1, output: "only allowed on the dev platform"},
+		{name: "Seed Negative Count", args: []string{"seed", "-platform", "dev", "-chirps", "-1"}, expected: 2, output: "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			code := execute(context.Background(), tt.args, &out, &out)
+
+			assert.Equal(t, tt.expected, code)
+			assert.Contains(t, out.String(), tt.output)
+		})
+	}
+}
+
+func TestOperatorCommandsOnSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chirpy.db")
+	t.Setenv("DB_STORE", "sqlite")
+	t.Setenv("DB_PATH", path)
+	run := func(args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		code := execute(context.Background(), args, &out, &out)
+		require.Equal(t, 0, code, out.String())
+		return out.String()
+	}
+
+	run("users", "create", "-email", "a@example.com", "-password", "password123", "-role", "moderator")
+	run("users", "promote", "-user", "a@example.com")
+
+	out := run("users", "list")
+	assert.Contains(t, out, "a@example.com")
+	assert.Contains(t, out, "admin")
+
+	run("users", "suspend", "-user", "a@example.com", "-for", "72h", "-reason", "spam")
+	run("users", "reset-password", "-user", "a@example.com", "-password", "password456")
+	run("tokens", "revoke-all", "-user", "a@example.com")
+	run("chirps", "purge", "-user", "a@example.com", "-yes")
+
+	ctx := context.Background()
+	st, err := store.OpenSQLite(ctx, path)
+	require.NoError(t, err)
+	user, err := st.GetUser(ctx, "a@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, service.StatusSuspended, user.Status)
+	actions, err := st.ListModerationActionsForUser(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	assert.Equal(t, service.ActionSuspend, actions[0].Action)
+	assert.Equal(t, "spam", actions[0].Reason)
+	assert.False(t, actions[0].ModeratorID.Valid, "no moderator acted")
+
+	events, err := st.ListAuditEvents(ctx, database.ListAuditEventsParams{Limit: 100})
+	require.NoError(t, err)
+	var audited []string
+	for _, e := range slices.Backward(events) {
+		assert.False(t, e.ActorID.Valid, e.Action)
+		assert.Equal(t, user.ID.String(), e.TargetID, e.Action)
+		audited = append(audited, e.Action+" by "+e.UserAgent)
+	}
+	assert.Equal(t, []string{
+		service.AuditCreateUser + " by chirpy users create",
+		service.AuditSetRole + " by chirpy users promote",
+		service.AuditModerate + " by chirpy users suspend",
+		service.AuditResetPassword + " by chirpy users reset-password",
+		service.AuditRevokeSessions + " by chirpy tokens revoke-all",
+		service.AuditPurgeChirps + " by chirpy chirps purge",
+	}, audited)
+	require.NoError(t, st.Close())
+
+	t.Setenv("PLATFORM", "dev")
+	out = run("seed", "-users", "5", "-chirps", "20")
+	assert.Contains(t, out, "created 5 users")
+	assert.Contains(t, out, "20 chirps")
+	out = run("seed", "-users", "5", "-chirps", "20")
+	assert.Contains(t, out, "created 0 users", "seeding again adds nothing")
+}
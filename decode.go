@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/Vikuuu/Chirpy/internal/validate"
+)
+
+// maxBodyBytes caps the size of a JSON request body.
+const maxBodyBytes = 1 << 20
+
+const (
+	codeUnsupportedMediaType = "unsupported_media_type"
+	codeBodyTooLarge         = "body_too_large"
+)
+
+// decodeJSON reads the single JSON object in r's body into dst and checks it
+// against dst's validate tags. Fields dst doesn't declare are refused.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	return decodeBody(w, r, dst, false)
+}
+
+// decodeLenientJSON is decodeJSON for payloads we don't control, such as
+// webhooks, where a sender adding a field mustn't break delivery.
+func decodeLenientJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	return decodeBody(w, r, dst, true)
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, dst any, allowUnknown bool) error {
+	_, span := tracer.Start(r.Context(), "decode request body")
+	defer span.End()
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return &apiError{
+			Status:  http.StatusUnsupportedMediaType,
+			Code:    codeUnsupportedMediaType,
+			Message: "Content-Type must be application/json",
+		}
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if !allowUnknown {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return decodeError(err)
+		}
+		return &apiError{Status: http.StatusBadRequest, Code: codeMalformedBody, Message: "request body must contain a single JSON object"}
+	}
+
+	if errs := validate.Struct(dst); len(errs) > 0 {
+		fields := make([]fieldError, 0, len(errs))
+		for _, e := range errs {
+			fields = append(fields, fieldError{Field: e.Field, Code: e.Code, Message: e.Message})
+		}
+		return errFields(fields...)
+	}
+	return nil
+}
+
+// decodeError explains why a body couldn't be decoded.
+func decodeError(err error) error {
+	var (
+		typeErr *json.UnmarshalTypeError
+		maxErr  *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &maxErr):
+		return &apiError{
+			Status:  http.StatusRequestEntityTooLarge,
+			Code:    codeBodyTooLarge,
+			Message: fmt.Sprintf("request body must not be larger than %d bytes", maxErr.Limit),
+		}
+	case errors.Is(err, io.EOF):
+		return &apiError{Status: http.StatusBadRequest, Code: codeMalformedBody, Message: "request body is empty"}
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return errFields(fieldError{
+			Field:   typeErr.Field,
+			Code:    "invalid_type",
+			Message: fmt.Sprintf("%s must be %s", typeErr.Field, jsonKind(typeErr.Type)),
+		})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for this one.
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return errFields(fieldError{Field: field, Code: "unknown_field", Message: field + " is not a known field"})
+	default:
+		// Syntax errors, a truncated body, or a value that isn't an object.
+		return errMalformedBody()
+	}
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// jsonKind names a Go type the way a JSON client would think of it.
+func jsonKind(t reflect.Type) string {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return "a string"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Vikuuu/Chirpy/internal/api"
+	"github.com/Vikuuu/Chirpy/internal/metrics"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    int
+		code        string
+		field       string
+	}{
+		{name: "Valid", body: `{"email":"walt@breakingbad.com","password":"04234abcd"}`, expected: http.StatusOK},
+		{name: "Charset Parameter", contentType: "application/json; charset=utf-8", body: `{"email":"walt@breakingbad.com","password":"04234abcd"}`, expected: http.StatusOK},
+		{name: "Wrong Content Type", contentType: "text/plain", body: `{}`, expected: http.StatusUnsupportedMediaType, code: "unsupported_media_type"},
+		{name: "Empty Body", body: ``, expected: http.StatusBadRequest, code: "malformed_body"},
+		{name: "Syntax Error", body: `{"email":`, expected: http.StatusBadRequest, code: "malformed_body"},
+		{name: "Not An Object", body: `["walt"]`, expected: http.StatusBadRequest, code: "malformed_body"},
+		{name: "Trailing Data", body: `{"email":"walt@breakingbad.com","password":"04234abcd"} {}`, expected: http.StatusBadRequest, code: "malformed_body"},
+		{name: "Unknown Field", body: `{"email":"walt@breakingbad.com","password":"04234abcd","admin":true}`, expected: http.StatusBadRequest, code: "validation_failed", field: "admin"},
+		{name: "Wrong Type", body: `{"email":42,"password":"04234abcd"}`, expected: http.StatusBadRequest, code: "validation_failed", field: "email"},
+		{name: "Invalid Email", body: `{"email":"walt","password":"04234abcd"}`, expected: http.StatusBadRequest, code: "validation_failed", field: "email"},
+		{name: "Too Large", body: `{"email":"` + strings.Repeat("a", maxBodyBytes) + `"}`, expected: http.StatusRequestEntityTooLarge, code: "body_too_large"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := (&apiConfig{metrics: metrics.New()}).handle(func(w http.ResponseWriter, r *http.Request) error {
+				var params api.CreateUserRequest
+				if err := decodeJSON(w, r, &params); err != nil {
+					return err
+				}
+				w.WriteHeader(http.StatusOK)
+				return nil
+			})
+			req := httptest.NewRequest("POST", "/api/users", strings.NewReader(tt.body))
+			contentType := tt.contentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			req.Header.Set("Content-Type", contentType)
+			rec := httptest.NewRecorder()
+
+			h(rec, req)
+
+			assert.Equal(t, tt.expected, rec.Code)
+			if tt.code == "" {
+				return
+			}
+			p := decodeProblem(t, rec)
+			assert.Equal(t, tt.code, p.Code)
+			if tt.field != "" && assert.Len(t, p.Errors, 1) {
+				assert.Equal(t, tt.field, p.Errors[0].Field)
+			}
+		})
+	}
+}
+
+func TestDecodeLenientJSON(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/polka/webhooks", strings.NewReader(`{"event":"user.upgraded","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"},"sent_at":"now"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	var payload api.PolkaWebhook
+	err := decodeLenientJSON(httptest.NewRecorder(), req, &payload)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "user.upgraded", payload.Event)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/Vikuuu/Chirpy/internal/metrics"
+	"github.com/Vikuuu/Chirpy/internal/service"
+)
+
+// Stable problem codes. Clients branch on these, so they must not change
+// once published.
+const (
+	codeValidationFailed  = "validation_failed"
+	codeMalformedBody     = "malformed_body"
+	codeUnauthorized      = "unauthorized"
+	codeForbidden         = "forbidden"
+	codeAccountRestricted = "account_restricted"
+	codeNotFound          = "not_found"
+	codeMethodNotAllowed  = "method_not_allowed"
+	codeConflict          = "conflict"
+	codeProhibitedWords   = "prohibited_language"
+	codeSpam              = "spam_detected"
+	codeRateLimited       = "rate_limited"
+	codeTimeout           = "timeout"
+	codeInternal          = "internal_error"
+)
+
+// apiError is an error a handler returns to answer the request with a
+// specific status. Message is shown to the client as the problem detail; Err
+// is only logged.
+type apiError struct {
+	Status  int
+	Code    string
+	Message string
+	Fields  []fieldError
+	Err     error
+}
+
+func (e *apiError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %s", e.Status, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%d %s", e.Status, e.Message)
+}
+
+func (e *apiError) Unwrap() error {
+	return e.Err
+}
+
+func errValidation(msg string) error {
+	return &apiError{Status: http.StatusBadRequest, Code: codeValidationFailed, Message: msg}
+}
+
+// errFields reports one or more invalid request fields.
+func errFields(fields ...fieldError) error {
+	msg := "request has invalid fields"
+	if len(fields) == 1 {
+		msg = fields[0].Message
+	}
+	return &apiError{Status: http.StatusBadRequest, Code: codeValidationFailed, Message: msg, Fields: fields}
+}
+
+func errMalformedBody() error {
+	return &apiError{Status: http.StatusBadRequest, Code: codeMalformedBody, Message: "request body is not valid JSON"}
+}
+
+func errUnauthorized(msg string, err error) error {
+	return &apiError{Status: http.StatusUnauthorized, Code: codeUnauthorized, Message: msg, Err: err}
+}
+
+func errForbidden(msg string) error {
+	return &apiError{Status: http.StatusForbidden, Code: codeForbidden, Message: msg}
+}
+
+func errAccountRestricted(msg string) error {
+	return &apiError{Status: http.StatusForbidden, Code: codeAccountRestricted, Message: msg}
+}
+
+func errNotFound(msg string) error {
+	return &apiError{Status: http.StatusNotFound, Code: codeNotFound, Message: msg}
+}
+
+func errConflict(msg string) error {
+	return &apiError{Status: http.StatusConflict, Code: codeConflict, Message: msg}
+}
+
+func errUnprocessable(code, msg string) error {
+	return &apiError{Status: http.StatusUnprocessableEntity, Code: code, Message: msg}
+}
+
+// serviceProblem is the problem a request refused by the service layer is
+// answered with, or nil for a kind of refusal it doesn't know.
+func serviceProblem(e *service.Error) *apiError {
+	var status int
+	var code string
+	switch e.Kind {
+	case service.Invalid:
+		status, code = http.StatusBadRequest, codeValidationFailed
+	case service.Unauthenticated:
+		status, code = http.StatusUnauthorized, codeUnauthorized
+	case service.Forbidden:
+		status, code = http.StatusForbidden, codeForbidden
+	case service.Restricted:
+		status, code = http.StatusForbidden, codeAccountRestricted
+	case service.NotFound:
+		status, code = http.StatusNotFound, codeNotFound
+	case service.Conflict:
+		status, code = http.StatusConflict, codeConflict
+	case service.Prohibited:
+		status, code = http.StatusUnprocessableEntity, codeProhibitedWords
+	case service.Spam:
+		status, code = http.StatusUnprocessableEntity, codeSpam
+	default:
+		return nil
+	}
+
+	var fields []fieldError
+	for _, f := range e.Fields {
+		fields = append(fields, fieldError{Field: f.Field, Code: f.Code, Message: f.Message})
+	}
+	return &apiError{Status: status, Code: code, Message: e.Message, Fields: fields, Err: e.Err}
+}
+
+// errInternal is what any untyped error is reported as. The cause is never
+// shown to the client.
+var errInternal = &apiError{Status: http.StatusInternalServerError, Code: codeInternal, Message: "internal server error"}
+
+// errTimeout is what a request that ran out of time is reported as.
+var errTimeout = &apiError{Status: http.StatusServiceUnavailable, Code: codeTimeout, Message: "the request took too long"}
+
+// statusClientClosedRequest is the nginx convention for a request the client
+// gave up on before it was answered.
+const statusClientClosedRequest = 499
+
+// apiHandler is a handler that reports failure by returning an error instead
+// of writing the response itself.
+type apiHandler func(w http.ResponseWriter, r *http.Request) error
+
+// handle adapts h to an http.HandlerFunc. An *apiError, or a
+// *service.Error refusing the request, is answered with a problem carrying
+// its status and code; any other error is logged and answered with a 500
+// that doesn't leak details. Requests abandoned by the client or cut off by
+// their timeout are counted apart from real failures.
+func (cfg *apiConfig) handle(h apiHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := h(w, r)
+		if err == nil {
+			return
+		}
+
+		var svcErr *service.Error
+		if errors.As(err, &svcErr) {
+			if p := serviceProblem(svcErr); p != nil {
+				err = p
+			}
+		}
+		var apiErr *apiError
+		if errors.As(err, &apiErr) {
+			if apiErr.Err != nil {
+				slog.WarnContext(r.Context(), "request failed", "status", apiErr.Status, "err", err)
+			}
+			respondWithProblem(w, r, apiErr)
+			return
+		}
+
+		switch {
+		case errors.Is(err, context.Canceled) || r.Context().Err() == context.Canceled:
+			cfg.metrics.RequestError(metrics.ErrorCancelled)
+			slog.InfoContext(r.Context(), "request cancelled by client", "err", err)
+			// Nobody is listening; the status is only for the access log.
+			w.WriteHeader(statusClientClosedRequest)
+		case errors.Is(err, context.DeadlineExceeded) || r.Context().Err() == context.DeadlineExceeded:
+			cfg.metrics.RequestError(metrics.ErrorTimedOut)
+			slog.WarnContext(r.Context(), "request timed out", "err", err)
+			recordError(r.Context(), err)
+			respondWithProblem(w, r, errTimeout)
+		default:
+			cfg.metrics.RequestError(metrics.ErrorFailed)
+			slog.ErrorContext(r.Context(), "request failed", "err", err)
+			recordError(r.Context(), err)
+			respondWithProblem(w, r, errInternal)
+		}
+	}
+}
+
+// recoverPanics turns a panic in next into a logged 500 so that one bad
+// request can't take the server down.
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			slog.ErrorContext(r.Context(), "panic serving request", "panic", v, "stack", string(debug.Stack()))
+			respondWithProblem(w, r, errInternal)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
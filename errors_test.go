@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Vikuuu/Chirpy/internal/health"
+	"github.com/Vikuuu/Chirpy/internal/metrics"
+	"github.com/Vikuuu/Chirpy/internal/profanity"
+	"github.com/Vikuuu/Chirpy/internal/ratelimit"
+	"github.com/Vikuuu/Chirpy/internal/service"
+	"github.com/Vikuuu/Chirpy/internal/spam"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// downConnector is a database that is never reachable, so any handler that
+// gets as far as a query sees an error.
+type downConnector struct{}
+
+func (downConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, errors.New("database is down")
+}
+
+func (downConnector) Driver() driver.Driver { return nil }
+
+func newTestConfig(t *testing.T) *apiConfig {
+	db := sql.OpenDB(downConnector{})
+	t.Cleanup(func() { db.Close() })
+
+	cfg := &apiConfig{
+		secret:      "secret",
+		polkaKey:    "polka-key",
+		filter:      profanity.New(profanity.Options{}),
+		rateLimiter: ratelimit.NewMemoryStore(),
+		health:      health.New(time.Second),
+		metrics:     metrics.New(),
+	}
+	cfg.useStore(store.NewPostgres(db, nil))
+	cfg.health.Register("shutdown", cfg.checkShutdown)
+	return cfg
+}
+
+// useStore points cfg, and its service layer, at st.
+func (cfg *apiConfig) useStore(st store.Store) {
+	cfg.db = st
+	cfg.svc = service.New(st, service.Config{
+		Filter:        cfg.filter,
+		Spam:          spam.DefaultConfig(),
+		Secret:        cfg.secret,
+		DeletionGrace: cfg.deletionGrace,
+	})
+}
+
+func TestMalformedRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		header   http.Header
+		body     string
+		expected int
+		code     string
+	}{
+		{name: "Bad Chirp ID", method: "GET", path: "/api/chirps/not-a-uuid", expected: http.StatusBadRequest, code: "validation_failed"},
+		{name: "Bad Chirp ID On Delete", method: "DELETE", path: "/api/chirps/not-a-uuid", expected: http.StatusBadRequest, code: "validation_failed"},
+		{name: "Bad Author ID", method: "GET", path: "/api/chirps?author_id=nope", expected: http.StatusBadRequest, code: "validation_failed"},
+		{
+			name:     "Webhook Malformed JSON",
+			method:   "POST",
+			path:     "/api/polka/webhooks",
+			header:   http.Header{"Authorization": {"ApiKey polka-key"}},
+			body:     "{not json",
+			expected: http.StatusBadRequest,
+			code:     "malformed_body",
+		},
+		{name: "Webhook Missing API Key", method: "POST", path: "/api/polka/webhooks", body: "{}", expected: http.StatusUnauthorized, code: "unauthorized"},
+		{name: "Refresh Missing Token", method: "POST", path: "/api/refresh", expected: http.StatusUnauthorized, code: "unauthorized"},
+		{name: "Revoke Missing Token", method: "POST", path: "/api/revoke", expected: http.StatusUnauthorized, code: "unauthorized"},
+		{name: "Create User Malformed JSON", method: "POST", path: "/api/users", body: "[", expected: http.StatusBadRequest, code: "malformed_body"},
+		{name: "Login Malformed JSON", method: "POST", path: "/api/login", body: "[", expected: http.StatusBadRequest, code: "malformed_body"},
+		{name: "Post Chirp Without Token", method: "POST", path: "/api/chirps", body: `{"body":"hi"}`, expected: http.StatusUnauthorized, code: "unauthorized"},
+		{name: "Reset Without Dev Platform", method: "POST", path: "/admin/reset", expected: http.StatusForbidden, code: "forbidden"},
+		{name: "Database Down", method: "GET", path: "/api/chirps", expected: http.StatusInternalServerError, code: "internal_error"},
+	}
+
+	handler := newTestConfig(t).routes(".")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expected, rec.Code)
+			assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+			p := decodeProblem(t, rec)
+			assert.Equal(t, tt.code, p.Code)
+			assert.Equal(t, tt.expected, p.Status)
+			assert.Equal(t, rec.Header().Get("X-Request-ID"), p.RequestID)
+		})
+	}
+}
+
+func TestHandle(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+		message  string
+	}{
+		{name: "Validation", err: errValidation("bad input"), expected: http.StatusBadRequest, message: "bad input"},
+		{name: "Unauthorized", err: errUnauthorized("no token", errors.New("missing header")), expected: http.StatusUnauthorized, message: "no token"},
+		{name: "Forbidden", err: errForbidden("not yours"), expected: http.StatusForbidden, message: "not yours"},
+		{name: "Not Found", err: errNotFound("gone"), expected: http.StatusNotFound, message: "gone"},
+		{name: "Conflict", err: errConflict("taken"), expected: http.StatusConflict, message: "taken"},
+		{name: "Wrapped", err: fmt.Errorf("loading: %w", errNotFound("gone")), expected: http.StatusNotFound, message: "gone"},
+		{name: "Untyped", err: errors.New("pq: connection refused"), expected: http.StatusInternalServerError, message: "internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := (&apiConfig{metrics: metrics.New()}).handle(func(w http.ResponseWriter, r *http.Request) error {
+				return tt.err
+			})
+			rec := httptest.NewRecorder()
+
+			h(rec, httptest.NewRequest("GET", "/", nil))
+
+			assert.Equal(t, tt.expected, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.message)
+			assert.NotContains(t, rec.Body.String(), "pq:")
+		})
+	}
+}
+
+func TestRecoverPanics(t *testing.T) {
+	h := recoverPanics(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m map[string]int
+		m["boom"]++
+	}))
+	rec := httptest.NewRecorder()
+
+	assert.NotPanics(t, func() {
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	})
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestRecoverPanicsAbortHandler(t *testing.T) {
+	h := recoverPanics(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+}
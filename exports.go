@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/api"
+	"github.com/Vikuuu/Chirpy/internal/service"
+)
+
+// accountJobInterval is how often accounts past their grace period are
+// deleted and expired exports cleared away. Queued exports are built as soon
+// as they are asked for.
+const accountJobInterval = time.Minute
+
+// runAccountJobs deletes accounts and builds and expires data exports until
+// ctx is done.
+func (cfg *apiConfig) runAccountJobs(ctx context.Context) {
+	ticker := time.NewTicker(accountJobInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			if _, err := cfg.svc.PurgeDeletedAccounts(ctx, now); err != nil {
+				slog.ErrorContext(ctx, "deleting accounts", "err", err)
+			}
+			if _, err := cfg.svc.PurgeExpiredDataExports(ctx, now); err != nil {
+				slog.ErrorContext(ctx, "deleting expired data exports", "err", err)
+			}
+		case <-cfg.svc.ExportsQueued():
+		}
+		// Exports an error left pending are tried again on the next tick.
+		if _, err := cfg.svc.ProcessDataExports(ctx); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "building data exports", "err", err)
+		}
+	}
+}
+
+// handlerRequestExport queues an export of the caller's data, to be polled
+// at the Location it answers with until it is ready to download.
+func (cfg *apiConfig) handlerRequestExport(w http.ResponseWriter, r *http.Request) error {
+	user, err := cfg.authenticate(r)
+	if err != nil {
+		return err
+	}
+
+	export, err := cfg.svc.RequestDataExport(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", exportPath(export.ID))
+	return respondWithJSON(w, http.StatusAccepted, api.NewDataExport(export, exportPath(export.ID)+"/download"))
+}
+
+func (cfg *apiConfig) handlerGetExport(w http.ResponseWriter, r *http.Request) error {
+	exportID, err := uuid.Parse(r.PathValue("exportID"))
+	if err != nil {
+		return errValidation("export id is not a valid id")
+	}
+
+	user, err := cfg.authenticate(r)
+	if err != nil {
+		return err
+	}
+
+	export, err := cfg.svc.DataExport(r.Context(), user.ID, exportID)
+	if err != nil {
+		return err
+	}
+	return respondWithJSON(w, http.StatusOK, api.NewDataExport(export, exportPath(export.ID)+"/download"))
+}
+
+// handlerDownloadExport serves a ready export as a ZIP archive.
+func (cfg *apiConfig) handlerDownloadExport(w http.ResponseWriter, r *http.Request) error {
+	exportID, err := uuid.Parse(r.PathValue("exportID"))
+	if err != nil {
+		return errValidation("export id is not a valid id")
+	}
+
+	user, err := cfg.authenticate(r)
+	if err != nil {
+		return err
+	}
+
+	export, err := cfg.svc.DataExport(r.Context(), user.ID, exportID)
+	if err != nil {
+		return err
+	}
+	if export.Status != service.ExportReady {
+		return errConflict(fmt.Sprintf("export is %s", export.Status))
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="chirpy-export-%s.zip"`, export.CompletedAt.Time.UTC().Format("20060102T150405Z")))
+	w.Header().Set("Content-Length", strconv.Itoa(len(export.Archive)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(export.Archive)
+	return nil
+}
+
+func exportPath(id uuid.UUID) string {
+	return "/api/users/export/" + id.String()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Vikuuu/Chirpy/internal/api"
+	"github.com/Vikuuu/Chirpy/internal/service"
+)
+
+// readExport unzips an export archive, returning its files by name.
+func readExport(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		files[f.Name], err = io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+	}
+	return files
+}
+
+func TestDataExport(t *testing.T) {
+	ctx := context.Background()
+	h := newHarness(t)
+	h.do("POST", "/api/login").json(api.LoginRequest{Email: "walt@example.com", Password: "not-it"}).expect(http.StatusUnauthorized)
+	u := h.signUp("walt@example.com")
+	other := h.signUp("jesse@example.com")
+	u.post("say my name")
+	u.post("you're goddamn right")
+	other.post("yeah science")
+	h.do("POST", "/api/login").json(api.LoginRequest{Email: "walt@example.com", Password: "not-it"}).expect(http.StatusUnauthorized)
+
+	var queued api.DataExport
+	resp := u.do("POST", "/api/users/export").expect(http.StatusAccepted)
+	resp.decode(&queued)
+	assert.Equal(t, service.ExportPending, queued.Status)
+	assert.Empty(t, queued.DownloadURL)
+	location := resp.header.Get("Location")
+	assert.Equal(t, "/api/users/export/"+queued.ID.String(), location)
+
+	var again api.DataExport
+	u.do("POST", "/api/users/export").expect(http.StatusAccepted).decode(&again)
+	assert.Equal(t, queued.ID, again.ID, "one export is queued at a time")
+
+	u.do("GET", location+"/download").expect(http.StatusConflict)
+	other.do("GET", location).expect(http.StatusNotFound)
+	other.do("GET", location+"/download").expect(http.StatusNotFound)
+	h.do("GET", location).expect(http.StatusUnauthorized)
+
+	n, err := h.cfg.svc.ProcessDataExports(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	var ready api.DataExport
+	u.do("GET", location).expect(http.StatusOK).decode(&ready)
+	assert.Equal(t, service.ExportReady, ready.Status)
+	require.NotNil(t, ready.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), *ready.ExpiresAt, time.Minute)
+	assert.Equal(t, location+"/download", ready.DownloadURL)
+
+	resp = u.do("GET", ready.DownloadURL).expect(http.StatusOK)
+	assert.Equal(t, "application/zip", resp.header.Get("Content-Type"))
+	assert.Contains(t, resp.header.Get("Content-Disposition"), "attachment")
+	files := readExport(t, resp.body)
+	require.Len(t, files, 4)
+
+	var profile map[string]any
+	require.NoError(t, json.Unmarshal(files["profile.json"], &profile))
+	assert.Equal(t, "walt@example.com", profile["email"])
+	assert.NotContains(t, profile, "hashed_password")
+
+	var chirps []struct{ Body string }
+	require.NoError(t, json.Unmarshal(files["chirps.json"], &chirps))
+	assert.Equal(t, []struct{ Body string }{{"say my name"}, {"you're goddamn right"}}, chirps)
+
+	var sessions []struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(files["sessions.json"], &sessions))
+	require.Len(t, sessions, 1)
+	assert.NotContains(t, string(files["sessions.json"]), u.RefreshToken)
+
+	var events []api.AuditEvent
+	require.NoError(t, json.Unmarshal(files["audit_events.json"], &events))
+	assert.Equal(t, []string{
+		service.AuditUserExport,
+		service.AuditLoginFailed,
+		service.AuditLogin,
+	}, actions(events), "the failed login before signing up wasn't against this account")
+	assert.Equal(t, sessions[0].ID, events[2].TargetID)
+	for name, data := range files {
+		assert.NotContains(t, string(data), "jesse", name)
+	}
+
+	var next api.DataExport
+	u.do("POST", "/api/users/export").expect(http.StatusAccepted).decode(&next)
+	assert.NotEqual(t, queued.ID, next.ID, "a finished export isn't reused")
+
+	purged, err := h.cfg.svc.PurgeExpiredDataExports(ctx, time.Now().Add(8*24*time.Hour))
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, purged)
+	u.do("GET", location).expect(http.StatusNotFound)
+
+	h.do("POST", "/api/users/export").expect(http.StatusUnauthorized)
+}
+
+func TestAccountJobsBuildQueuedExports(t *testing.T) {
+	h := newHarness(t)
+	u := h.signUp("walt@example.com")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.cfg.runAccountJobs(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	var export api.DataExport
+	u.do("POST", "/api/users/export").expect(http.StatusAccepted).decode(&export)
+	assert.Eventually(t, func() bool {
+		var got api.DataExport
+		u.do("GET", "/api/users/export/"+export.ID.String()).expect(http.StatusOK).decode(&got)
+		return got.Status == service.ExportReady
+	}, 5*time.Second, 10*time.Millisecond, "built without waiting for a tick")
+}
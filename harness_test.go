@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Vikuuu/Chirpy/internal/api"
+	"github.com/Vikuuu/Chirpy/internal/config"
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/service"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// testPassword is the password harness users sign up with.
+const testPassword = "password123"
+
+// harness serves the whole API over HTTP from an in-memory store, so that
+// tests can drive it as a client would without a database. Static files are
+// served from root.
+type harness struct {
+	t    *testing.T
+	cfg  *apiConfig
+	srv  *httptest.Server
+	root string
+}
+
+// newHarness starts a server for the test on the dev platform. configure, if
+// given, may adjust the config before the server starts.
+func newHarness(t *testing.T, configure ...func(*apiConfig)) *harness {
+	t.Helper()
+	cfg := newTestConfig(t)
+	cfg.platform = config.PlatformDev
+	for _, f := range configure {
+		f(cfg)
+	}
+	cfg.useStore(store.NewMemory())
+	require.NoError(t, cfg.loadBannedWords(context.Background()))
+
+	root := t.TempDir()
+	srv := httptest.NewServer(cfg.routes(root))
+	t.Cleanup(srv.Close)
+	return &harness{t: t, cfg: cfg, srv: srv, root: root}
+}
+
+// request is a request being built against the harness.
+type request struct {
+	h      *harness
+	method string
+	path   string
+	header http.Header
+	body   io.Reader
+}
+
+// do starts a request for path, which may include a query.
+func (h *harness) do(method, path string) *request {
+	return &request{h: h, method: method, path: path, header: http.Header{}}
+}
+
+// json sends v as the JSON body. A string is sent as is, so that tests can
+// send malformed JSON.
+func (r *request) json(v any) *request {
+	r.header.Set("Content-Type", "application/json")
+	if s, ok := v.(string); ok {
+		r.body = bytes.NewBufferString(s)
+		return r
+	}
+	data, err := json.Marshal(v)
+	require.NoError(r.h.t, err)
+	r.body = bytes.NewReader(data)
+	return r
+}
+
+// bearer authenticates the request with token.
+func (r *request) bearer(token string) *request {
+	r.header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+// apiKey authenticates the request as Polka does.
+func (r *request) apiKey(key string) *request {
+	r.header.Set("Authorization", "ApiKey "+key)
+	return r
+}
+
+// expect sends the request and fails the test unless it is answered with
+// status.
+func (r *request) expect(status int) *response {
+	t := r.h.t
+	t.Helper()
+	req, err := http.NewRequest(r.method, r.h.srv.URL+r.path, r.body)
+	require.NoError(t, err)
+	req.Header = r.header
+
+	resp, err := r.h.srv.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, status, resp.StatusCode, "%s %s answered %s", r.method, r.path, body)
+	return &response{t: t, header: resp.Header, body: body}
+}
+
+// response is a response the test has checked the status of.
+type response struct {
+	t      *testing.T
+	header http.Header
+	body   []byte
+}
+
+// decode unmarshals the body into v.
+func (r *response) decode(v any) {
+	r.t.Helper()
+	require.NoError(r.t, json.Unmarshal(r.body, v), "body: %s", r.body)
+}
+
+// problem decodes an error response, checking it is a problem document.
+func (r *response) problem() problem {
+	r.t.Helper()
+	require.Equal(r.t, "application/problem+json", r.header.Get("Content-Type"))
+	var p problem
+	r.decode(&p)
+	return p
+}
+
+// user is an account signed up through the API.
+type user struct {
+	h *harness
+	api.Login
+	password string
+}
+
+// register signs up email with testPassword.
+func (h *harness) register(email string) *user {
+	h.t.Helper()
+	u := &user{h: h, password: testPassword}
+	h.do("POST", "/api/users").
+		json(api.CreateUserRequest{Email: email, Password: testPassword}).
+		expect(http.StatusCreated).
+		decode(&u.User)
+	return u
+}
+
+// login signs u in, keeping the tokens for later requests.
+func (u *user) login() *user {
+	u.h.t.Helper()
+	u.h.do("POST", "/api/login").
+		json(api.LoginRequest{Email: u.Email, Password: u.password}).
+		expect(http.StatusOK).
+		decode(&u.Login)
+	return u
+}
+
+// signUp registers email and logs in.
+func (h *harness) signUp(email string) *user {
+	h.t.Helper()
+	return h.register(email).login()
+}
+
+// as gives u role. There is no API for it; operators use the CLI.
+func (u *user) as(role string) *user {
+	u.h.t.Helper()
+	_, err := u.h.cfg.db.SetUserRole(context.Background(), database.SetUserRoleParams{Role: role, ID: u.ID})
+	require.NoError(u.h.t, err)
+	return u
+}
+
+// do starts a request authenticated as u.
+func (u *user) do(method, path string) *request {
+	return u.h.do(method, path).bearer(u.Token)
+}
+
+// post chirps body as u.
+func (u *user) post(body string) api.Chirp {
+	u.h.t.Helper()
+	var c api.Chirp
+	u.do("POST", "/api/chirps").
+		json(api.CreateChirpRequest{Body: body}).
+		expect(http.StatusCreated).
+		decode(&c)
+	return c
+}
+
+// reportChirp reports c as u.
+func (u *user) reportChirp(c api.Chirp, reason string) api.Report {
+	u.h.t.Helper()
+	var r api.Report
+	u.do("POST", "/api/reports").
+		json(api.CreateReportRequest{TargetType: service.ReportTargetChirp, ChirpID: c.ID, Reason: reason}).
+		expect(http.StatusCreated).
+		decode(&r)
+	return r
+}
+
+// chirps lists the chirps at path as seen by viewer, or anonymously if
+// viewer is nil, and returns their bodies.
+func (h *harness) chirps(viewer *user, path string) []string {
+	h.t.Helper()
+	req := h.do("GET", path)
+	if viewer != nil {
+		req = viewer.do("GET", path)
+	}
+	var chirps []api.Chirp
+	req.expect(http.StatusOK).decode(&chirps)
+	bodies := []string{}
+	for _, c := range chirps {
+		bodies = append(bodies, c.Body)
+	}
+	return bodies
+}
@@ -0,0 +1,8 @@
+// Package api defines the JSON documents the HTTP API accepts and returns.
+//
+// Responses are built only through the mappers in this package, never by
+// encoding database rows directly, so that a column added to a table (a
+// password hash, a moderator's identity) can't leak into a response by
+// accident. Mappers for lists always return a non-nil slice so that an empty
+// list encodes as [] rather than null.
+package api
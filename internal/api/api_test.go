@@ -0,0 +1,114 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+)
+
+func encode(t *testing.T, v any) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	var out map[string]any
+	require.NoError(t, json.Unmarshal(data, &out))
+	return out
+}
+
+func TestNewUserHidesPrivateFields(t *testing.T) {
+	user := database.User{
+		ID:             uuid.New(),
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		Email:          "walt@breakingbad.com",
+		HashedPassword: "$2a$10$secret",
+		Role:           "admin",
+		Status:         "shadow_banned",
+		SuspendedUntil: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+
+	tests := []struct {
+		name    string
+		payload any
+	}{
+		{name: "User", payload: NewUser(user)},
+		{name: "Login", payload: Login{User: NewUser(user), Token: "t", RefreshToken: "r"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encode(t, tt.payload)
+			assert.Equal(t, "walt@breakingbad.com", got["email"])
+			for _, key := range []string{"hashed_password", "HashedPassword", "role", "status", "suspended_until"} {
+				assert.NotContains(t, got, key)
+			}
+		})
+	}
+}
+
+func TestEmptyListsEncodeAsArrays(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload any
+	}{
+		{name: "Chirps", payload: NewChirps(nil)},
+		{name: "Words", payload: NewWords(nil)},
+		{name: "Reports", payload: NewReports(nil, true)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.payload)
+			require.NoError(t, err)
+			assert.JSONEq(t, "[]", string(data))
+		})
+	}
+}
+
+func TestNewReportHidesReporter(t *testing.T) {
+	report := database.Report{
+		ID:         uuid.New(),
+		ReporterID: uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		TargetType: "user",
+		UserID:     uuid.New(),
+		Reason:     "spam",
+		Status:     "open",
+	}
+
+	assert.NotContains(t, encode(t, NewReport(report, false)), "reporter_id")
+	assert.Contains(t, encode(t, NewReport(report, true)), "reporter_id")
+}
+
+func TestNewAuditEvents(t *testing.T) {
+	data, err := json.Marshal(NewAuditEvents(nil, ""))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"events": []}`, string(data), "an empty page is an empty list, with no cursor")
+
+	got := encode(t, NewAuditEvent(database.AuditEvent{ID: uuid.New(), Action: "auth.login_failed", Details: json.RawMessage(`{}`)}))
+	assert.Contains(t, got, "actor_id")
+	assert.Nil(t, got["actor_id"], "events without an actor say so")
+}
+
+func TestNewDataExport(t *testing.T) {
+	pending := encode(t, NewDataExport(database.DataExport{ID: uuid.New(), Status: "pending"}, "/download"))
+	assert.NotContains(t, pending, "download_url", "a pending export can't be downloaded yet")
+	assert.NotContains(t, pending, "completed_at")
+	assert.NotContains(t, pending, "archive", "the archive is only downloaded")
+
+	ready := encode(t, NewDataExport(database.DataExport{
+		ID:          uuid.New(),
+		Status:      "ready",
+		CompletedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		ExpiresAt:   sql.NullTime{Time: time.Now(), Valid: true},
+		Archive:     []byte("PK"),
+	}, "/download"))
+	assert.Equal(t, "/download", ready["download_url"])
+	assert.Contains(t, ready, "expires_at")
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+)
+
+// AuditEvent is an entry of the audit log. ActorID is null for events no
+// signed-in user caused, such as failed logins and webhooks.
+type AuditEvent struct {
+	ID         uuid.UUID       `json:"id"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Action     string          `json:"action"`
+	ActorID    *uuid.UUID      `json:"actor_id"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"user_agent"`
+	RequestID  string          `json:"request_id"`
+	Details    json.RawMessage `json:"details"`
+}
+
+// NewAuditEvent maps an audit event row.
+func NewAuditEvent(e database.AuditEvent) AuditEvent {
+	resp := AuditEvent{
+		ID:         e.ID,
+		CreatedAt:  e.CreatedAt,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		IP:         e.Ip,
+		UserAgent:  e.UserAgent,
+		RequestID:  e.RequestID,
+		Details:    e.Details,
+	}
+	if e.ActorID.Valid {
+		resp.ActorID = &e.ActorID.UUID
+	}
+	return resp
+}
+
+// AuditEvents is a page of GET /admin/audit-events, newest first. NextCursor,
+// passed back as ?cursor=, fetches the page after it.
+type AuditEvents struct {
+	Events     []AuditEvent `json:"events"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// NewAuditEvents maps a page of audit event rows.
+func NewAuditEvents(es []database.AuditEvent, next string) AuditEvents {
+	out := make([]AuditEvent, 0, len(es))
+	for _, e := range es {
+		out = append(out, NewAuditEvent(e))
+	}
+	return AuditEvents{Events: out, NextCursor: next}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+)
+
+// CreateChirpRequest is the body of POST /api/chirps.
+type CreateChirpRequest struct {
+	Body string `json:"body" validate:"required,max=140"`
+}
+
+// Chirp is the public view of a chirp.
+type Chirp struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Body      string    `json:"body"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+// NewChirp maps a chirp row.
+func NewChirp(c database.Chirp) Chirp {
+	return Chirp{
+		ID:        c.ID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		Body:      c.Body,
+		UserID:    c.UserID,
+	}
+}
+
+// NewChirps maps chirp rows.
+func NewChirps(cs []database.Chirp) []Chirp {
+	out := make([]Chirp, 0, len(cs))
+	for _, c := range cs {
+		out = append(out, NewChirp(c))
+	}
+	return out
+}
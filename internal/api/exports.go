@@ -0,0 +1,40 @@
+package api
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+)
+
+// DataExport is an export of a user's data. DownloadURL is set once it is
+// ready, until it expires.
+type DataExport struct {
+	ID          uuid.UUID  `json:"id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
+
+// NewDataExport maps a data export row, which can be downloaded from
+// downloadURL if it is ready.
+func NewDataExport(e database.DataExport, downloadURL string) DataExport {
+	resp := DataExport{
+		ID:        e.ID,
+		Status:    e.Status,
+		CreatedAt: e.CreatedAt,
+	}
+	if e.CompletedAt.Valid {
+		resp.CompletedAt = &e.CompletedAt.Time
+	}
+	if e.ExpiresAt.Valid {
+		resp.ExpiresAt = &e.ExpiresAt.Time
+	}
+	if e.Status == "ready" {
+		resp.DownloadURL = downloadURL
+	}
+	return resp
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+)
+
+// ModerationRequest is the body of the moderation and dismissal endpoints.
+type ModerationRequest struct {
+	Action   string `json:"action"`
+	Reason   string `json:"reason" validate:"required,max=500"`
+	Duration string `json:"duration"`
+}
+
+// ModerationAction is an action a moderator took against an account.
+type ModerationAction struct {
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ReportID      *uuid.UUID `json:"report_id,omitempty"`
+	ModeratorID   *uuid.UUID `json:"moderator_id,omitempty"`
+	Action        string     `json:"action"`
+	TargetUserID  uuid.UUID  `json:"target_user_id"`
+	TargetChirpID *uuid.UUID `json:"target_chirp_id,omitempty"`
+	Reason        string     `json:"reason"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+}
+
+// NewModerationAction maps an action row. The moderator is only disclosed to
+// other moderators, so withModerator must be false for anyone else.
+func NewModerationAction(a database.ModerationAction, withModerator bool) ModerationAction {
+	resp := ModerationAction{
+		ID:           a.ID,
+		CreatedAt:    a.CreatedAt,
+		Action:       a.Action,
+		TargetUserID: a.TargetUserID,
+		Reason:       a.Reason,
+	}
+	if a.ReportID.Valid {
+		resp.ReportID = &a.ReportID.UUID
+	}
+	if withModerator && a.ModeratorID.Valid {
+		resp.ModeratorID = &a.ModeratorID.UUID
+	}
+	if a.TargetChirpID.Valid {
+		resp.TargetChirpID = &a.TargetChirpID.UUID
+	}
+	if a.ExpiresAt.Valid {
+		resp.ExpiresAt = &a.ExpiresAt.Time
+	}
+	return resp
+}
+
+// ModerationResult is the response to acting on a report.
+type ModerationResult struct {
+	Report Report           `json:"report"`
+	Action ModerationAction `json:"action"`
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+)
+
+// CreateReportRequest is the body of POST /api/reports.
+type CreateReportRequest struct {
+	TargetType string    `json:"target_type" validate:"required,oneof=chirp user"`
+	ChirpID    uuid.UUID `json:"chirp_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Reason     string    `json:"reason" validate:"required,oneof=spam harassment hate violence sexual impersonation profanity other"`
+	Details    string    `json:"details" validate:"max=500"`
+}
+
+// Report is a report on a chirp or user and its outcome.
+type Report struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ReporterID *uuid.UUID `json:"reporter_id,omitempty"`
+	TargetType string     `json:"target_type"`
+	ChirpID    *uuid.UUID `json:"chirp_id,omitempty"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+	Status     string     `json:"status"`
+	Resolution string     `json:"resolution,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// NewReport maps a report row. The reporter is only disclosed to moderators,
+// so withReporter must be false for anyone else.
+func NewReport(r database.Report, withReporter bool) Report {
+	resp := Report{
+		ID:         r.ID,
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+		TargetType: r.TargetType,
+		UserID:     r.UserID,
+		Reason:     r.Reason,
+		Details:    r.Details,
+		Status:     r.Status,
+		Resolution: r.Resolution,
+	}
+	if withReporter && r.ReporterID.Valid {
+		resp.ReporterID = &r.ReporterID.UUID
+	}
+	if r.ChirpID.Valid {
+		resp.ChirpID = &r.ChirpID.UUID
+	}
+	if r.ResolvedAt.Valid {
+		resp.ResolvedAt = &r.ResolvedAt.Time
+	}
+	return resp
+}
+
+// NewReports maps report rows.
+func NewReports(rs []database.Report, withReporter bool) []Report {
+	out := make([]Report, 0, len(rs))
+	for _, r := range rs {
+		out = append(out, NewReport(r, withReporter))
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+package api
+
+import "time"
+
+// ResetRequest is the body of POST /admin/reset. Sent without Confirm, it
+// deletes nothing and is answered with a ResetConfirmation; sent again with
+// that token as Confirm, it resets.
+type ResetRequest struct {
+	Scope   string `json:"scope" validate:"required"`
+	Pattern string `json:"pattern" validate:"max=254"`
+	Confirm string `json:"confirm"`
+}
+
+// ResetConfirmation is the token that confirms a reset.
+type ResetConfirmation struct {
+	Scope             string    `json:"scope"`
+	Pattern           string    `json:"pattern,omitempty"`
+	ConfirmationToken string    `json:"confirmation_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// ResetSummary is what a reset deleted, and the sample data made after it
+// when asked for with ?seed=.
+type ResetSummary struct {
+	Scope   string        `json:"scope"`
+	Pattern string        `json:"pattern,omitempty"`
+	Deleted ResetCounts   `json:"deleted"`
+	Metrics *ResetMetrics `json:"metrics,omitempty"`
+	Seeded  *Seeded       `json:"seeded,omitempty"`
+}
+
+// ResetCounts are the rows a reset deleted.
+type ResetCounts struct {
+	Users         int64 `json:"users"`
+	Chirps        int64 `json:"chirps"`
+	RefreshTokens int64 `json:"refresh_tokens"`
+}
+
+// ResetMetrics are the counters a reset cleared, as they were.
+type ResetMetrics struct {
+	FileserverHits int32 `json:"fileserver_hits"`
+}
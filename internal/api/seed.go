@@ -0,0 +1,12 @@
+package api
+
+// Seeded is the sample data POST /admin/reset?seed= made, and the password
+// its users sign in with.
+type Seeded struct {
+	Seed      uint64 `json:"seed"`
+	Users     int    `json:"users"`
+	ChirpyRed int    `json:"chirpy_red"`
+	Follows   int    `json:"follows"`
+	Chirps    int    `json:"chirps"`
+	Password  string `json:"password"`
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+)
+
+// CreateUserRequest is the body of POST /api/users.
+type CreateUserRequest struct {
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"required,maxbytes=72"`
+}
+
+// LoginRequest is the body of POST /api/login.
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,max=254"`
+	Password string `json:"password" validate:"required,maxbytes=72"`
+}
+
+// UpdateUserRequest is the body of PUT /api/users.
+type UpdateUserRequest struct {
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"required,maxbytes=72"`
+}
+
+// User is the public view of an account.
+type User struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Email       string    `json:"email"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+// NewUser maps a user row.
+func NewUser(u database.User) User {
+	return User{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		Email:       u.Email,
+		IsChirpyRed: u.IsChirpyRed,
+	}
+}
+
+// NewCreatedUser maps the row returned when a user signs up.
+func NewCreatedUser(u database.CreateUserRow) User {
+	return User{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		Email:       u.Email,
+		IsChirpyRed: u.IsChirpyRed,
+	}
+}
+
+// Login is the response to a successful login.
+type Login struct {
+	User
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh is the response to POST /api/refresh.
+type Refresh struct {
+	Token string `json:"token"`
+}
+
+// UpdatedUser is the response to PUT /api/users.
+type UpdatedUser struct {
+	Email string `json:"email"`
+}
+
+// DeleteUserRequest is the body of DELETE /api/users, which asks for the
+// password again.
+type DeleteUserRequest struct {
+	Password string `json:"password" validate:"required,maxbytes=72"`
+}
+
+// DeletedUser is the response to DELETE /api/users when the account is only
+// scheduled for deletion; logging in before DeleteAfter restores it.
+type DeletedUser struct {
+	DeleteAfter time.Time `json:"delete_after"`
+}
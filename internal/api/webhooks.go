@@ -0,0 +1,11 @@
+package api
+
+import "github.com/google/uuid"
+
+// PolkaWebhook is the body of POST /api/polka/webhooks.
+type PolkaWebhook struct {
+	Event string `json:"event" validate:"required"`
+	Data  struct {
+		UserID uuid.UUID `json:"user_id"`
+	} `json:"data"`
+}
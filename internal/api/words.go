@@ -0,0 +1,40 @@
+package api
+
+import (
+	"time"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+)
+
+// WordRequest is the body of POST /admin/words.
+type WordRequest struct {
+	Word   string `json:"word" validate:"required,max=64"`
+	Action string `json:"action" validate:"oneof=mask reject flag"`
+}
+
+// Word is a banned word and what the filter does with it.
+type Word struct {
+	Word      string    `json:"word"`
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewWord maps a banned word row.
+func NewWord(w database.BannedWord) Word {
+	return Word{
+		Word:      w.Word,
+		Action:    w.Action,
+		CreatedAt: w.CreatedAt,
+		UpdatedAt: w.UpdatedAt,
+	}
+}
+
+// NewWords maps banned word rows.
+func NewWords(ws []database.BannedWord) []Word {
+	out := make([]Word, 0, len(ws))
+	for _, w := range ws {
+		out = append(out, NewWord(w))
+	}
+	return out
+}
@@ -1,18 +1,31 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"net/http"
 	"strings"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
+// MaxPasswordBytes is the longest password bcrypt hashes, in bytes.
+const MaxPasswordBytes = 72
+
+// tracer spans the bcrypt calls, which are slow on purpose.
+var tracer = otel.Tracer("github.com/Vikuuu/Chirpy/internal/auth")
+
+func HashPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracer.Start(ctx, "auth.HashPassword")
+	defer span.End()
+
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		return "", err
 	}
 
@@ -20,7 +33,10 @@ func HashPassword(password string) (string, error) {
 	return pass, nil
 }
 
-func CheckPasswordHash(password, hash string) error {
+func CheckPasswordHash(ctx context.Context, password, hash string) error {
+	_, span := tracer.Start(ctx, "auth.CheckPasswordHash")
+	defer span.End()
+
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	if err != nil {
 		return err
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"testing"
 
 	"golang.org/x/crypto/bcrypt"
@@ -10,7 +11,7 @@ func TestHashPassword(t *testing.T) {
 	password := "mysecurepassword"
 
 	// Call the HashPassword function
-	funcHashPassword, err := HashPassword(password)
+	funcHashPassword, err := HashPassword(context.Background(), password)
 	if err != nil {
 		t.Fatalf("HashPassword returned an error: %v", err)
 	}
@@ -37,7 +38,7 @@ func TestCheckPasswordHash(t *testing.T) {
 	}
 
 	// Call the CheckPasswordHash function to verify the password against the hash
-	err = CheckPasswordHash(password, string(hash))
+	err = CheckPasswordHash(context.Background(), password, string(hash))
 	if err != nil {
 		t.Fatal("CheckPasswordHash returned an error, the password does not match the hash")
 	}
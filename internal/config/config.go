@@ -0,0 +1,428 @@
+// Package config loads the server's settings.
+//
+// Each setting is read from, in increasing order of precedence: its default,
+// the YAML or TOML file named by -config or CONFIG_FILE, its environment
+// variable, and its command-line flag. The result is validated as a whole so
+// that a bad deploy fails at startup rather than on the first request that
+// needs the missing value. Commands that only need part of it, such as
+// migrations, validate just that part.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Vikuuu/Chirpy/internal/ratelimit"
+	"github.com/Vikuuu/Chirpy/internal/spam"
+)
+
+// Platforms accepted in Config.Platform. Destructive admin endpoints only
+// work on dev.
+const (
+	PlatformDev        = "dev"
+	PlatformProduction = "production"
+)
+
+// Stores accepted in Database.Store.
+const (
+	StorePostgres = "postgres"
+	StoreSQLite   = "sqlite"
+	StoreMemory   = "memory"
+)
+
+// minSecretLength is the shortest JWT signing secret accepted, in bytes;
+// HS256 wants at least 256 bits of key.
+const minSecretLength = 32
+
+// Config is the server's configuration.
+type Config struct {
+	Server    Server    `yaml:"server" toml:"server"`
+	Database  Database  `yaml:"database" toml:"database"`
+	Auth      Auth      `yaml:"auth" toml:"auth"`
+	Platform  string    `yaml:"platform" toml:"platform"`
+	Log       Log       `yaml:"log" toml:"log"`
+	Tracing   Tracing   `yaml:"tracing" toml:"tracing"`
+	Queries   Queries   `yaml:"queries" toml:"queries"`
+	RateLimit RateLimit `yaml:"rate_limit" toml:"rate_limit"`
+	Profanity Profanity `yaml:"profanity" toml:"profanity"`
+	Spam      Spam      `yaml:"spam" toml:"spam"`
+	Accounts  Accounts  `yaml:"accounts" toml:"accounts"`
+}
+
+// Server configures the HTTP server.
+type Server struct {
+	Port int `yaml:"port" toml:"port"`
+	// Root is the directory the web app under /app/ is served from.
+	Root string `yaml:"root" toml:"root"`
+	// TrustProxy believes X-Forwarded-For for the client address.
+	TrustProxy      bool          `yaml:"trust_proxy" toml:"trust_proxy"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	ShutdownDelay   time.Duration `yaml:"shutdown_delay" toml:"shutdown_delay"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+}
+
+// Database configures where data is kept.
+type Database struct {
+	// Store is "postgres", or "sqlite" or "memory" to run without one.
+	Store string `yaml:"store" toml:"store"`
+	// URL is the Postgres connection URL.
+	URL string `yaml:"url" toml:"url"`
+	// Path is the SQLite database file.
+	Path string `yaml:"path" toml:"path"`
+	// AutoMigrate applies pending migrations before serving.
+	AutoMigrate bool `yaml:"auto_migrate" toml:"auto_migrate"`
+	// AllowNewerSchema serves against a schema migrated further than this
+	// build knows, as during a rolling deploy whose migrations the previous
+	// build can live with.
+	AllowNewerSchema bool `yaml:"allow_newer_schema" toml:"allow_newer_schema"`
+}
+
+// Auth holds the credentials the server checks requests against.
+type Auth struct {
+	// Secret signs access tokens.
+	Secret string `yaml:"secret" toml:"secret"`
+	// PolkaKey authenticates Polka's webhooks.
+	PolkaKey string `yaml:"polka_key" toml:"polka_key"`
+}
+
+// Log configures logging.
+type Log struct {
+	Level string `yaml:"level" toml:"level"`
+}
+
+// Tracing configures where spans are exported.
+type Tracing struct {
+	// Exporter is "otlp", "console" or "none". Left empty it is "otlp" if
+	// an endpoint is set and "none" otherwise.
+	Exporter     string `yaml:"exporter" toml:"exporter"`
+	OTLPEndpoint string `yaml:"otlp_endpoint" toml:"otlp_endpoint"`
+}
+
+// Queries bounds how long a request's database work may take. Zero disables
+// the timeout.
+type Queries struct {
+	Timeout time.Duration `yaml:"timeout" toml:"timeout"`
+	// Routes overrides Timeout for named routes, e.g. "chirps_list".
+	Routes map[string]time.Duration `yaml:"routes" toml:"routes"`
+}
+
+// RateLimit configures request rate limiting.
+type RateLimit struct {
+	// Store is "memory", or "postgres" to share limits between instances.
+	Store string `yaml:"store" toml:"store"`
+	// Routes holds the policy for each limited route, e.g.
+	// "anonymous=10/1m,user=30/1m".
+	Routes map[string]string `yaml:"routes" toml:"routes"`
+}
+
+// Profanity configures how banned words are masked.
+type Profanity struct {
+	// Mask is the character masked words are replaced with.
+	Mask      string `yaml:"mask" toml:"mask"`
+	KeepFirst bool   `yaml:"keep_first" toml:"keep_first"`
+	// ReloadInterval is how often the banned words are read again, to pick
+	// up edits made through other instances.
+	ReloadInterval time.Duration `yaml:"reload_interval" toml:"reload_interval"`
+}
+
+// Spam configures spam detection; see spam.Config. Thresholds are written
+// "review=N,reject=M".
+type Spam struct {
+	Window       time.Duration `yaml:"window" toml:"window"`
+	NearDistance int           `yaml:"near_distance" toml:"near_distance"`
+	Duplicates   string        `yaml:"duplicates" toml:"duplicates"`
+	LinkOnly     string        `yaml:"link_only" toml:"link_only"`
+	Mentions     string        `yaml:"mentions" toml:"mentions"`
+}
+
+// Accounts configures what happens to accounts their owners delete.
+type Accounts struct {
+	// DeletionGracePeriod is how long a deleted account can still be
+	// restored by logging in. Zero deletes it at once.
+	DeletionGracePeriod time.Duration `yaml:"deletion_grace_period" toml:"deletion_grace_period"`
+}
+
+// Default returns the configuration used for anything left unset.
+func Default() Config {
+	s := spam.DefaultConfig()
+	return Config{
+		Server: Server{
+			Port:            8080,
+			Root:            ".",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     120 * time.Second,
+			ShutdownTimeout: 20 * time.Second,
+		},
+		Database: Database{Store: StorePostgres, Path: "chirpy.db"},
+		Platform: PlatformProduction,
+		Log:      Log{Level: "info"},
+		Queries:  Queries{Timeout: 5 * time.Second, Routes: map[string]time.Duration{}},
+		RateLimit: RateLimit{
+			Store: "memory",
+			Routes: map[string]string{
+				"chirps_create": "anonymous=10/1m,user=30/1m,red=120/1m,token=10/1m",
+				"users_create":  "anonymous=10/1h,user=10/1h,red=10/1h,token=10/1h",
+				"login":         "anonymous=10/1m,user=10/1m,red=10/1m,token=10/1m",
+			},
+		},
+		Spam: Spam{
+			Window:       s.Window,
+			NearDistance: s.NearDistance,
+			Duplicates:   s.Duplicates.String(),
+			LinkOnly:     s.LinkOnly.String(),
+			Mentions:     s.Mentions.String(),
+		},
+		Profanity: Profanity{ReloadInterval: 10 * time.Second},
+		Accounts:  Accounts{DeletionGracePeriod: 30 * 24 * time.Hour},
+	}
+}
+
+// Load reads the configuration from the file, environ (as returned by
+// os.Environ) and the flags in args. Its flags are defined on fs, which may
+// already hold flags of the caller's. Values that can't be parsed are errors;
+// whether the whole is usable is for Validate or ValidateDatabase to say.
+func Load(fs *flag.FlagSet, args, environ []string) (Config, error) {
+	c := Default()
+	settings := c.settings()
+	env := envMap(environ)
+
+	// Flags are parsed first, to find -config, but applied last.
+	var (
+		file  string
+		flags [][2]string
+	)
+	fs.StringVar(&file, "config", env["CONFIG_FILE"], "YAML or TOML config `file`")
+	for _, s := range settings {
+		if s.flag == "" {
+			continue
+		}
+		record := func(v string) error {
+			flags = append(flags, [2]string{s.flag, v})
+			return nil
+		}
+		if _, ok := s.value.(*boolValue); ok {
+			fs.BoolFunc(s.flag, s.usage, record)
+		} else {
+			fs.Func(s.flag, s.usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	if file != "" {
+		if err := c.readFile(file); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := env[s.env]; ok && s.env != "" {
+			if err := s.value.Set(v); err != nil {
+				return Config{}, fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+	for key, v := range env {
+		if route, ok := strings.CutPrefix(key, "QUERY_TIMEOUT_"); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return Config{}, fmt.Errorf("%s: %w", key, err)
+			}
+			c.Queries.Routes[strings.ToLower(route)] = d
+		}
+		if route, ok := strings.CutPrefix(key, "RATE_LIMIT_"); ok && key != "RATE_LIMIT_STORE" {
+			c.RateLimit.Routes[strings.ToLower(route)] = v
+		}
+	}
+
+	for _, f := range flags {
+		for _, s := range settings {
+			if s.flag == f[0] {
+				if err := s.value.Set(f[1]); err != nil {
+					return Config{}, fmt.Errorf("-%s: %w", f[0], err)
+				}
+			}
+		}
+	}
+
+	if c.Tracing.Exporter == "" {
+		c.Tracing.Exporter = "none"
+		if c.Tracing.OTLPEndpoint != "" {
+			c.Tracing.Exporter = "otlp"
+		}
+	}
+
+	return c, nil
+}
+
+func envMap(environ []string) map[string]string {
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if key, val, ok := strings.Cut(kv, "="); ok && val != "" {
+			env[key] = val
+		}
+	}
+	return env
+}
+
+// readFile decodes the file at path over c, picking the format from its
+// extension. Keys the file doesn't mention keep their current values, and
+// keys Config doesn't know are refused so that typos don't go unnoticed.
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("%s: unknown key %s", path, undecoded[0])
+		}
+	default:
+		return fmt.Errorf("%s: unsupported config format %q, want .yaml, .yml or .toml", path, ext)
+	}
+	return nil
+}
+
+// ValidateDatabase reports whether the configured store can be opened.
+func (c Config) ValidateDatabase() error {
+	switch c.Database.Store {
+	case StorePostgres:
+		if c.Database.URL == "" {
+			return errors.New("database.url: is required")
+		}
+		if u, err := url.Parse(c.Database.URL); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			return errors.New("database.url: must be a postgres:// URL")
+		}
+	case StoreSQLite:
+		if c.Database.Path == "" {
+			return errors.New("database.path: is required")
+		}
+	case StoreMemory:
+	default:
+		return fmt.Errorf("database.store: must be %s, %s or %s", StorePostgres, StoreSQLite, StoreMemory)
+	}
+	return nil
+}
+
+// Validate reports every setting that is missing or malformed.
+func (c Config) Validate() error {
+	var errs []error
+	fail := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		fail("server.port", "%d is not a TCP port", c.Server.Port)
+	}
+	if c.Server.Root == "" {
+		fail("server.root", "is required")
+	}
+	for key, d := range map[string]time.Duration{
+		"server.read_timeout":            c.Server.ReadTimeout,
+		"server.write_timeout":           c.Server.WriteTimeout,
+		"server.idle_timeout":            c.Server.IdleTimeout,
+		"server.shutdown_delay":          c.Server.ShutdownDelay,
+		"server.shutdown_timeout":        c.Server.ShutdownTimeout,
+		"queries.timeout":                c.Queries.Timeout,
+		"spam.window":                    c.Spam.Window,
+		"accounts.deletion_grace_period": c.Accounts.DeletionGracePeriod,
+	} {
+		if d < 0 {
+			fail(key, "must not be negative")
+		}
+	}
+
+	if err := c.ValidateDatabase(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(c.Auth.Secret) < minSecretLength {
+		fail("auth.secret", "must be at least %d bytes", minSecretLength)
+	}
+	if !slices.Contains([]string{PlatformDev, PlatformProduction}, c.Platform) {
+		fail("platform", "must be %s or %s", PlatformDev, PlatformProduction)
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		fail("log.level", "%q is not a level", c.Log.Level)
+	}
+	if !slices.Contains([]string{"none", "otlp", "console"}, c.Tracing.Exporter) {
+		fail("tracing.exporter", "must be none, otlp or console")
+	}
+	if c.Tracing.OTLPEndpoint != "" {
+		if u, err := url.Parse(c.Tracing.OTLPEndpoint); err != nil || u.Host == "" {
+			fail("tracing.otlp_endpoint", "must be an absolute URL")
+		}
+	}
+
+	for route, d := range c.Queries.Routes {
+		if d < 0 {
+			fail("queries.routes."+route, "must not be negative")
+		}
+	}
+
+	if c.RateLimit.Store != "memory" && c.RateLimit.Store != "postgres" {
+		fail("rate_limit.store", "must be memory or postgres")
+	} else if c.RateLimit.Store == "postgres" && c.Database.Store != StorePostgres {
+		fail("rate_limit.store", "postgres needs database.store %s", StorePostgres)
+	}
+	for route, policy := range c.RateLimit.Routes {
+		if _, err := ratelimit.ParsePolicy(policy); err != nil {
+			fail("rate_limit.routes."+route, "%s", err)
+		}
+	}
+
+	if utf8.RuneCountInString(c.Profanity.Mask) > 1 {
+		fail("profanity.mask", "must be a single character")
+	}
+	if c.Profanity.ReloadInterval <= 0 {
+		fail("profanity.reload_interval", "must be positive")
+	}
+
+	if c.Spam.NearDistance < 0 || c.Spam.NearDistance > 64 {
+		fail("spam.near_distance", "must be between 0 and 64")
+	}
+	for key, t := range map[string]string{
+		"spam.duplicates": c.Spam.Duplicates,
+		"spam.link_only":  c.Spam.LinkOnly,
+		"spam.mentions":   c.Spam.Mentions,
+	} {
+		if _, err := spam.ParseThreshold(t); err != nil {
+			fail(key, "%s", err)
+		}
+	}
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
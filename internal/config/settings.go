@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// setting is one scalar configuration value and the names it can be set by.
+type setting struct {
+	key   string // as printed and in the config file, e.g. "server.port"
+	env   string
+	flag  string // empty for secrets, which shouldn't show up in ps
+	usage string
+	value value
+	// redact hides the value, or the sensitive part of it, when printed.
+	redact func(string) string
+}
+
+// value is a setting's storage, as in flag.Value.
+type value interface {
+	Set(string) error
+	String() string
+}
+
+// settings binds every scalar setting to its field in c.
+func (c *Config) settings() []setting {
+	return []setting{
+		{key: "server.port", env: "PORT", flag: "port", usage: "port to listen on", value: (*intValue)(&c.Server.Port)},
+		{key: "server.root", env: "FILEPATH_ROOT", flag: "root", usage: "directory the web app is served from", value: (*stringValue)(&c.Server.Root)},
+		{key: "server.trust_proxy", env: "TRUST_PROXY", flag: "trust-proxy", usage: "believe X-Forwarded-For", value: (*boolValue)(&c.Server.TrustProxy)},
+		{key: "server.read_timeout", env: "HTTP_READ_TIMEOUT", flag: "read-timeout", usage: "time allowed to read a request", value: (*durationValue)(&c.Server.ReadTimeout)},
+		{key: "server.write_timeout", env: "HTTP_WRITE_TIMEOUT", flag: "write-timeout", usage: "time allowed to write a response", value: (*durationValue)(&c.Server.WriteTimeout)},
+		{key: "server.idle_timeout", env: "HTTP_IDLE_TIMEOUT", flag: "idle-timeout", usage: "how long idle keep-alive connections are kept", value: (*durationValue)(&c.Server.IdleTimeout)},
+		{key: "server.shutdown_delay", env: "SHUTDOWN_DELAY", flag: "shutdown-delay", usage: "how long readiness fails before the listener closes", value: (*durationValue)(&c.Server.ShutdownDelay)},
+		{key: "server.shutdown_timeout", env: "SHUTDOWN_TIMEOUT", flag: "shutdown-timeout", usage: "time allowed for in-flight requests to finish", value: (*durationValue)(&c.Server.ShutdownTimeout)},
+		{key: "database.store", env: "DB_STORE", flag: "store", usage: "postgres, sqlite or memory", value: (*stringValue)(&c.Database.Store)},
+		{key: "database.url", env: "DB_URL", usage: "Postgres connection URL", value: (*stringValue)(&c.Database.URL), redact: redactURL},
+		{key: "database.path", env: "DB_PATH", flag: "db-path", usage: "SQLite database file", value: (*stringValue)(&c.Database.Path)},
+		{key: "database.auto_migrate", env: "DB_AUTO_MIGRATE", flag: "auto-migrate", usage: "apply pending migrations before serving", value: (*boolValue)(&c.Database.AutoMigrate)},
+		{key: "database.allow_newer_schema", env: "DB_ALLOW_NEWER_SCHEMA", flag: "allow-newer-schema", usage: "serve against a schema migrated by a newer build", value: (*boolValue)(&c.Database.AllowNewerSchema)},
+		{key: "auth.secret", env: "SECRET", usage: "access token signing secret", value: (*stringValue)(&c.Auth.Secret), redact: redactAll},
+		{key: "auth.polka_key", env: "POLKA_KEY", usage: "Polka webhook API key", value: (*stringValue)(&c.Auth.PolkaKey), redact: redactAll},
+		{key: "platform", env: "PLATFORM", flag: "platform", usage: "dev or production", value: (*stringValue)(&c.Platform)},
+		{key: "log.level", env: "LOG_LEVEL", flag: "log-level", usage: "debug, info, warn or error", value: (*stringValue)(&c.Log.Level)},
+		{key: "tracing.exporter", env: "OTEL_TRACES_EXPORTER", flag: "trace-exporter", usage: "otlp, console or none", value: (*stringValue)(&c.Tracing.Exporter)},
+		{key: "tracing.otlp_endpoint", env: "OTEL_EXPORTER_OTLP_ENDPOINT", flag: "otlp-endpoint", usage: "OTLP/HTTP collector URL", value: (*stringValue)(&c.Tracing.OTLPEndpoint)},
+		{key: "queries.timeout", env: "QUERY_TIMEOUT", flag: "query-timeout", usage: "time allowed for a request's queries", value: (*durationValue)(&c.Queries.Timeout)},
+		{key: "rate_limit.store", env: "RATE_LIMIT_STORE", flag: "rate-limit-store", usage: "memory or postgres", value: (*stringValue)(&c.RateLimit.Store)},
+		{key: "profanity.mask", env: "PROFANITY_MASK", usage: "character masked words are replaced with", value: (*stringValue)(&c.Profanity.Mask)},
+		{key: "profanity.keep_first", env: "PROFANITY_KEEP_FIRST", usage: "keep the first letter of masked words", value: (*boolValue)(&c.Profanity.KeepFirst)},
+		{key: "profanity.reload_interval", env: "PROFANITY_RELOAD_INTERVAL", usage: "how often banned words are read again", value: (*durationValue)(&c.Profanity.ReloadInterval)},
+		{key: "spam.window", env: "SPAM_WINDOW", usage: "how far back chirps are compared", value: (*durationValue)(&c.Spam.Window)},
+		{key: "spam.near_distance", env: "SPAM_NEAR_DISTANCE", usage: "largest simhash distance of near-duplicates", value: (*intValue)(&c.Spam.NearDistance)},
+		{key: "spam.duplicates", env: "SPAM_DUPLICATES", usage: "duplicate chirp thresholds", value: (*stringValue)(&c.Spam.Duplicates)},
+		{key: "spam.link_only", env: "SPAM_LINK_ONLY", usage: "link-only chirp thresholds", value: (*stringValue)(&c.Spam.LinkOnly)},
+		{key: "spam.mentions", env: "SPAM_MENTIONS", usage: "mention count thresholds", value: (*stringValue)(&c.Spam.Mentions)},
+		{key: "accounts.deletion_grace_period", env: "ACCOUNT_DELETION_GRACE_PERIOD", flag: "deletion-grace-period", usage: "how long deleted accounts can be restored", value: (*durationValue)(&c.Accounts.DeletionGracePeriod)},
+	}
+}
+
+// entries lists every setting as it should be shown, secrets redacted.
+func (c Config) entries() [][2]string {
+	var out [][2]string
+	for _, s := range c.settings() {
+		v := s.value.String()
+		if s.redact != nil && v != "" {
+			v = s.redact(v)
+		}
+		out = append(out, [2]string{s.key, v})
+	}
+	for _, route := range sortedKeys(c.Queries.Routes) {
+		out = append(out, [2]string{"queries.routes." + route, c.Queries.Routes[route].String()})
+	}
+	for _, route := range sortedKeys(c.RateLimit.Routes) {
+		out = append(out, [2]string{"rate_limit.routes." + route, c.RateLimit.Routes[route]})
+	}
+	return out
+}
+
+// Print writes the effective configuration to w, one "key = value" line per
+// setting, with secrets redacted.
+func (c Config) Print(w io.Writer) error {
+	for _, e := range c.entries() {
+		if _, err := fmt.Fprintf(w, "%s = %s\n", e[0], e[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LogValue logs the effective configuration with secrets redacted.
+func (c Config) LogValue() slog.Value {
+	entries := c.entries()
+	attrs := make([]slog.Attr, len(entries))
+	for i, e := range entries {
+		attrs[i] = slog.String(e[0], e[1])
+	}
+	return slog.GroupValue(attrs...)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Redacted replaces secrets when the configuration is printed.
+const Redacted = "[REDACTED]"
+
+func redactAll(string) string { return Redacted }
+
+// redactURL keeps the host and database of a connection URL but not its
+// password.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return Redacted
+	}
+	return u.Redacted()
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+func (v *stringValue) String() string     { return string(*v) }
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("%q is not an integer", s)
+	}
+	*v = intValue(n)
+	return nil
+}
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a boolean", s)
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a duration", s)
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
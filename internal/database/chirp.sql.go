@@ -8,6 +8,7 @@ package database
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -17,7 +18,7 @@ INSERT INTO chirp (id, created_at, updated_at, body, user_id)
 VALUES (
     gen_random_uuid(), NOW(), NOW(), $1, $2
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, hidden_at
 `
 
 type CreateChirpForUserParams struct {
@@ -34,10 +35,23 @@ func (q *Queries) CreateChirpForUser(ctx context.Context, arg CreateChirpForUser
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.HiddenAt,
 	)
 	return i, err
 }
 
+const deleteAllChirps = `-- name: DeleteAllChirps :execrows
+DELETE FROM chirp
+`
+
+func (q *Queries) DeleteAllChirps(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAllChirps)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteChirp = `-- name: DeleteChirp :exec
 DELETE FROM chirp
 WHERE user_id = $1 AND id = $2
@@ -53,8 +67,21 @@ func (q *Queries) DeleteChirp(ctx context.Context, arg DeleteChirpParams) error
 	return err
 }
 
+const deleteChirpsForUser = `-- name: DeleteChirpsForUser :execrows
+DELETE FROM chirp
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteChirpsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteChirpsForUser, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, hidden_at
 FROM chirp
 WHERE id = $1
 `
@@ -68,18 +95,22 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.HiddenAt,
 	)
 	return i, err
 }
 
 const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT chirp.id, chirp.created_at, chirp.updated_at, chirp.body, chirp.user_id, chirp.hidden_at
 FROM chirp
-ORDER BY created_at ASC
+JOIN users ON users.id = chirp.user_id
+WHERE chirp.hidden_at IS NULL
+    AND (users.status <> 'shadow_banned' OR chirp.user_id = $1)
+ORDER BY chirp.created_at ASC
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+func (q *Queries) GetChirps(ctx context.Context, viewerID uuid.NullUUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirps, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -93,6 +124,7 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -108,14 +140,21 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpsForAuthor = `-- name: GetChirpsForAuthor :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT chirp.id, chirp.created_at, chirp.updated_at, chirp.body, chirp.user_id, chirp.hidden_at
 FROM chirp
-WHERE user_id = $1
-ORDER BY created_at ASC
+JOIN users ON users.id = chirp.user_id
+WHERE chirp.user_id = $1 AND chirp.hidden_at IS NULL
+    AND (users.status <> 'shadow_banned' OR chirp.user_id = $2)
+ORDER BY chirp.created_at ASC
 `
 
-func (q *Queries) GetChirpsForAuthor(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsForAuthor, userID)
+type GetChirpsForAuthorParams struct {
+	UserID   uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) GetChirpsForAuthor(ctx context.Context, arg GetChirpsForAuthorParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsForAuthor, arg.UserID, arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -129,6 +168,7 @@ func (q *Queries) GetChirpsForAuthor(ctx context.Context, userID uuid.UUID) ([]C
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -143,21 +183,76 @@ func (q *Queries) GetChirpsForAuthor(ctx context.Context, userID uuid.UUID) ([]C
 	return items, nil
 }
 
+const hideChirp = `-- name: HideChirp :exec
+UPDATE chirp
+SET hidden_at = NOW(), updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) HideChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, hideChirp, id)
+	return err
+}
+
+const getRecentChirpBodiesForUser = `-- name: GetRecentChirpBodiesForUser :many
+SELECT body
+FROM chirp
+WHERE user_id = $1 AND created_at > $2
+ORDER BY created_at DESC
+LIMIT 50
+`
+
+type GetRecentChirpBodiesForUserParams struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) GetRecentChirpBodiesForUser(ctx context.Context, arg GetRecentChirpBodiesForUserParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentChirpBodiesForUser, arg.UserID, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var body string
+		if err := rows.Scan(&body); err != nil {
+			return nil, err
+		}
+		items = append(items, body)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getSortedChirps = `-- name: GetSortedChirps :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT chirp.id, chirp.created_at, chirp.updated_at, chirp.body, chirp.user_id, chirp.hidden_at
 FROM chirp
-ORDER BY created_at %s
+JOIN users ON users.id = chirp.user_id
+WHERE chirp.hidden_at IS NULL
+    AND (users.status <> 'shadow_banned' OR chirp.user_id = $1)
+ORDER BY chirp.created_at %s
 `
 
-func (q *Queries) GetSortedChirps(ctx context.Context, sort string) ([]Chirp, error) {
+type GetSortedChirpsParams struct {
+	ViewerID uuid.NullUUID
+	Sort     string
+}
+
+func (q *Queries) GetSortedChirps(ctx context.Context, arg GetSortedChirpsParams) ([]Chirp, error) {
 	sortDirection := "ASC"
-	if sort == "desc" {
+	if arg.Sort == "desc" {
 		sortDirection = "DESC"
 	}
 
 	query := fmt.Sprintf(getSortedChirps, sortDirection)
 
-	rows, err := q.db.QueryContext(ctx, query)
+	rows, err := q.db.QueryContext(ctx, query, arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -171,6 +266,7 @@ func (q *Queries) GetSortedChirps(ctx context.Context, sort string) ([]Chirp, er
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -186,15 +282,18 @@ func (q *Queries) GetSortedChirps(ctx context.Context, sort string) ([]Chirp, er
 }
 
 const getSortedChirpsForAuthor = `-- name: GetSortedChirpsForAuthor :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT chirp.id, chirp.created_at, chirp.updated_at, chirp.body, chirp.user_id, chirp.hidden_at
 FROM chirp
-WHERE user_id = $1
-ORDER BY created_at %s
+JOIN users ON users.id = chirp.user_id
+WHERE chirp.user_id = $1 AND chirp.hidden_at IS NULL
+    AND (users.status <> 'shadow_banned' OR chirp.user_id = $2)
+ORDER BY chirp.created_at %s
 `
 
 type GetSortedChirpsForAuthorParams struct {
-	UserID uuid.UUID
-	Sort   string
+	UserID   uuid.UUID
+	ViewerID uuid.NullUUID
+	Sort     string
 }
 
 func (q *Queries) GetSortedChirpsForAuthor(ctx context.Context, arg GetSortedChirpsForAuthorParams) ([]Chirp, error) {
@@ -204,7 +303,7 @@ func (q *Queries) GetSortedChirpsForAuthor(ctx context.Context, arg GetSortedChi
 	}
 
 	query := fmt.Sprintf(getSortedChirpsForAuthor, sortDirection)
-	rows, err := q.db.QueryContext(ctx, query, arg.UserID)
+	rows, err := q.db.QueryContext(ctx, query, arg.UserID, arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -218,6 +317,7 @@ func (q *Queries) GetSortedChirpsForAuthor(ctx context.Context, arg GetSortedChi
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -231,3 +331,66 @@ func (q *Queries) GetSortedChirpsForAuthor(ctx context.Context, arg GetSortedChi
 	}
 	return items, nil
 }
+
+const listChirpsForUser = `-- name: ListChirpsForUser :many
+SELECT id, created_at, updated_at, body, user_id, hidden_at
+FROM chirp
+WHERE user_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListChirpsForUser(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const seedChirp = `-- name: SeedChirp :execrows
+INSERT INTO chirp (id, created_at, updated_at, body, user_id)
+VALUES ($1, $2, $2, $3, $4)
+ON CONFLICT DO NOTHING
+`
+
+type SeedChirpParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+}
+
+func (q *Queries) SeedChirp(ctx context.Context, arg SeedChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, seedChirp,
+		arg.ID,
+		arg.CreatedAt,
+		arg.Body,
+		arg.UserID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -6,17 +6,81 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type AccountDeletion struct {
+	UserID      uuid.UUID
+	RequestedAt time.Time
+	DeleteAfter time.Time
+}
+
+type AuditEvent struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	Action     string
+	ActorID    uuid.NullUUID
+	TargetType string
+	TargetID   string
+	Ip         string
+	UserAgent  string
+	RequestID  string
+	Details    json.RawMessage
+}
+
+type BannedWord struct {
+	Word      string
+	Action    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
 type Chirp struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Body      string
 	UserID    uuid.UUID
+	HiddenAt  sql.NullTime
+}
+
+type DataExport struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Status      string
+	CreatedAt   time.Time
+	CompletedAt sql.NullTime
+	ExpiresAt   sql.NullTime
+	Archive     []byte
+	Error       string
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type ModerationAction struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	ReportID      uuid.NullUUID
+	ModeratorID   uuid.NullUUID
+	Action        string
+	TargetUserID  uuid.UUID
+	TargetChirpID uuid.NullUUID
+	Reason        string
+	ExpiresAt     sql.NullTime
+}
+
+type RateLimitBucket struct {
+	Key       string
+	Tokens    float64
+	Allowed   bool
+	UpdatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -28,6 +92,22 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
+type Report struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ReporterID uuid.NullUUID
+	TargetType string
+	ChirpID    uuid.NullUUID
+	UserID     uuid.UUID
+	Reason     string
+	Details    string
+	Status     string
+	ResolvedBy uuid.NullUUID
+	ResolvedAt sql.NullTime
+	Resolution string
+}
+
 type User struct {
 	ID             uuid.UUID
 	CreatedAt      time.Time
@@ -35,4 +115,7 @@ type User struct {
 	Email          string
 	HashedPassword string
 	IsChirpyRed    bool
+	Role           string
+	Status         string
+	SuspendedUntil sql.NullTime
 }
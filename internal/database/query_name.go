@@ -0,0 +1,17 @@
+package database
+
+import "strings"
+
+// QueryName extracts the name from the "-- name: GetChirp :one" comment sqlc
+// puts at the top of every query, for labelling metrics and spans.
+func QueryName(query string) string {
+	rest, ok := strings.CutPrefix(query, "-- name: ")
+	if !ok {
+		return "unknown"
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	if name == "" {
+		return "unknown"
+	}
+	return name
+}
@@ -0,0 +1,26 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryName(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{name: "Sqlc Query", query: "-- name: GetChirp :one\nSELECT * FROM chirps WHERE id = $1", expected: "GetChirp"},
+		{name: "Exec Rows", query: "-- name: DeleteBannedWord :execrows\nDELETE FROM banned_words", expected: "DeleteBannedWord"},
+		{name: "Raw SQL", query: "SELECT 1", expected: "unknown"},
+		{name: "Empty Name", query: "-- name:  :one", expected: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, QueryName(tt.query))
+		})
+	}
+}
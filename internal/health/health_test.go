@@ -0,0 +1,104 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		checks   map[string]CheckFunc
+		expected Report
+	}{
+		{
+			name:     "No Checks",
+			expected: Report{Status: StatusOK, Checks: []CheckResult{}},
+		},
+		{
+			name: "All Passing",
+			checks: map[string]CheckFunc{
+				"database": func(ctx context.Context) error { return nil },
+				"cache":    func(ctx context.Context) error { return nil },
+			},
+			expected: Report{Status: StatusOK, Checks: []CheckResult{
+				{Name: "cache", Status: StatusOK},
+				{Name: "database", Status: StatusOK},
+			}},
+		},
+		{
+			name: "One Failing",
+			checks: map[string]CheckFunc{
+				"database": func(ctx context.Context) error { return errors.New("connection refused") },
+				"cache":    func(ctx context.Context) error { return nil },
+			},
+			expected: Report{Status: StatusFail, Checks: []CheckResult{
+				{Name: "cache", Status: StatusOK},
+				{Name: "database", Status: StatusFail, Error: "connection refused"},
+			}},
+		},
+		{
+			name: "Ignores Its Context",
+			checks: map[string]CheckFunc{
+				"stuck": func(ctx context.Context) error { time.Sleep(time.Second); return nil },
+			},
+			expected: Report{Status: StatusFail, Checks: []CheckResult{
+				{Name: "stuck", Status: StatusFail, Error: "context deadline exceeded"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(50 * time.Millisecond)
+			for name, check := range tt.checks {
+				c.Register(name, check)
+			}
+
+			report := c.Run(context.Background())
+			for i := range report.Checks {
+				report.Checks[i].DurationMS = 0
+			}
+
+			assert.Equal(t, tt.expected, report)
+		})
+	}
+}
+
+func TestReadyHandler(t *testing.T) {
+	c := New(time.Second)
+	healthy := true
+	c.Register("database", func(ctx context.Context) error {
+		if !healthy {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	for _, tt := range []struct {
+		healthy  bool
+		expected int
+	}{
+		{healthy: true, expected: http.StatusOK},
+		{healthy: false, expected: http.StatusServiceUnavailable},
+	} {
+		healthy = tt.healthy
+		rec := httptest.NewRecorder()
+
+		c.ReadyHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/api/readyz", nil))
+
+		assert.Equal(t, tt.expected, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		var report Report
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Len(t, report.Checks, 1)
+	}
+}
@@ -0,0 +1,136 @@
+// Package logging sets up structured JSON logging whose records carry the
+// attributes of the request they were logged for.
+//
+// Middleware opens a scope on the request context with NewScope; anything
+// learned while serving the request (its ID, the route, the authenticated
+// user) is added with Add, and every record logged with that context, e.g.
+// through slog.ErrorContext, includes it.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the value of sensitive attributes.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values are never written. Keys are
+// compared case-insensitively with "-" treated as "_".
+var sensitiveKeys = map[string]bool{
+	"password":        true,
+	"hashed_password": true,
+	"token":           true,
+	"access_token":    true,
+	"refresh_token":   true,
+	"authorization":   true,
+	"api_key":         true,
+	"apikey":          true,
+	"secret":          true,
+	"cookie":          true,
+}
+
+// IsSensitive reports whether values logged under key are redacted.
+func IsSensitive(key string) bool {
+	return sensitiveKeys[strings.ReplaceAll(strings.ToLower(key), "-", "_")]
+}
+
+// New returns a logger writing JSON records at level or above to w.
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	h := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	})
+	return slog.New(&scopeHandler{Handler: h})
+}
+
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if IsSensitive(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+// ParseLevel reads a level name such as "debug" or "warn", defaulting to
+// info.
+func ParseLevel(s string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+type scopeKey struct{}
+
+// scope holds the attributes added for one request. It is shared by every
+// context derived from the one NewScope returned, so attributes added deep
+// in a handler are seen by the middleware that opened it.
+type scope struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// NewScope returns ctx with a fresh, empty scope.
+func NewScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scopeKey{}, &scope{})
+}
+
+// Add records attributes in ctx's scope, replacing earlier ones with the same
+// key. It does nothing if ctx has no scope.
+func Add(ctx context.Context, attrs ...slog.Attr) {
+	s, ok := ctx.Value(scopeKey{}).(*scope)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range attrs {
+		replaced := false
+		for i := range s.attrs {
+			if s.attrs[i].Key == a.Key {
+				s.attrs[i] = a
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.attrs = append(s.attrs, a)
+		}
+	}
+}
+
+// Attrs returns the attributes in ctx's scope.
+func Attrs(ctx context.Context) []slog.Attr {
+	s, ok := ctx.Value(scopeKey{}).(*scope)
+	if !ok {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]slog.Attr(nil), s.attrs...)
+}
+
+// scopeHandler adds the scope's attributes to each record.
+type scopeHandler struct {
+	slog.Handler
+}
+
+func (h *scopeHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs := Attrs(ctx); len(attrs) > 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *scopeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &scopeHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *scopeHandler) WithGroup(name string) slog.Handler {
+	return &scopeHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	return line
+}
+
+func TestRedaction(t *testing.T) {
+	tests := []struct {
+		name     string
+		attrs    []any
+		key      string
+		expected any
+	}{
+		{name: "Password", attrs: []any{"password", "hunter2"}, key: "password", expected: Redacted},
+		{name: "Refresh Token", attrs: []any{"refresh_token", "abc"}, key: "refresh_token", expected: Redacted},
+		{name: "Header Spelling", attrs: []any{"Authorization", "Bearer abc"}, key: "Authorization", expected: Redacted},
+		{name: "Dashes", attrs: []any{"api-key", "abc"}, key: "api-key", expected: Redacted},
+		{name: "Ordinary Field", attrs: []any{"email", "a@example.com"}, key: "email", expected: "a@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			New(&buf, slog.LevelInfo).Info("msg", tt.attrs...)
+
+			assert.Equal(t, tt.expected, decodeLine(t, &buf)[tt.key])
+		})
+	}
+}
+
+func TestRedactionInGroup(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, slog.LevelInfo).Info("msg", slog.Group("user", "email", "a@example.com", "password", "hunter2"))
+
+	assert.Equal(t, map[string]any{"email": "a@example.com", "password": Redacted}, decodeLine(t, &buf)["user"])
+}
+
+func TestScope(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+	ctx := NewScope(context.Background())
+
+	Add(ctx, slog.String("request_id", "abc"), slog.String("user_id", "u1"))
+	Add(context.WithValue(ctx, struct{}{}, 1), slog.String("user_id", "u2"))
+	logger.InfoContext(ctx, "msg")
+
+	line := decodeLine(t, &buf)
+	assert.Equal(t, "abc", line["request_id"])
+	assert.Equal(t, "u2", line["user_id"])
+}
+
+func TestAddWithoutScope(t *testing.T) {
+	ctx := context.Background()
+
+	Add(ctx, slog.String("request_id", "abc"))
+
+	assert.Empty(t, Attrs(ctx))
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	h := AccessLog(New(&buf, slog.LevelInfo), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Add(r.Context(), slog.String("route", "/api/chirps"), slog.String("user_id", "u1"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/api/chirps", nil))
+
+	line := decodeLine(t, &buf)
+	assert.Equal(t, "request", line["msg"])
+	assert.Equal(t, "INFO", line["level"])
+	assert.Equal(t, "POST", line["method"])
+	assert.Equal(t, "/api/chirps", line["path"])
+	assert.Equal(t, "/api/chirps", line["route"])
+	assert.Equal(t, "u1", line["user_id"])
+	assert.Equal(t, float64(http.StatusCreated), line["status"])
+	assert.Equal(t, float64(5), line["bytes"])
+	assert.Contains(t, line, "duration_ms")
+}
+
+func TestAccessLogServerError(t *testing.T) {
+	var buf bytes.Buffer
+	h := AccessLog(New(&buf, slog.LevelInfo), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, "ERROR", decodeLine(t, &buf)["level"])
+}
+
+func TestParseLevel(t *testing.T) {
+	assert.Equal(t, slog.LevelDebug, ParseLevel("debug"))
+	assert.Equal(t, slog.LevelWarn, ParseLevel("WARN"))
+	assert.Equal(t, slog.LevelInfo, ParseLevel(""))
+	assert.Equal(t, slog.LevelInfo, ParseLevel("loud"))
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+)
+
+// InstrumentDB times every query run through db by its sqlc query name.
+func (m *Metrics) InstrumentDB(db database.DBTX) database.DBTX {
+	return &instrumentedDB{db: db, m: m}
+}
+
+type instrumentedDB struct {
+	db database.DBTX
+	m  *Metrics
+}
+
+func (i *instrumentedDB) observe(query string, start time.Time) {
+	i.m.queries.WithLabelValues(database.QueryName(query)).Observe(time.Since(start).Seconds())
+}
+
+func (i *instrumentedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer i.observe(query, time.Now())
+	return i.db.ExecContext(ctx, query, args...)
+}
+
+func (i *instrumentedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return i.db.PrepareContext(ctx, query)
+}
+
+func (i *instrumentedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer i.observe(query, time.Now())
+	return i.db.QueryContext(ctx, query, args...)
+}
+
+func (i *instrumentedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer i.observe(query, time.Now())
+	return i.db.QueryRowContext(ctx, query, args...)
+}
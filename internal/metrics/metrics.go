@@ -0,0 +1,230 @@
+// Package metrics holds the Prometheus collectors the API reports.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const namespace = "chirpy"
+
+// Login outcomes.
+const (
+	LoginSuccess    = "success"
+	LoginFailure    = "failure"
+	LoginRestricted = "restricted"
+)
+
+// Request error kinds, counted apart from the status code so that a client
+// hanging up isn't mistaken for a server fault.
+const (
+	ErrorFailed    = "failed"
+	ErrorCancelled = "cancelled"
+	ErrorTimedOut  = "timed_out"
+)
+
+// Metrics is the registry and the collectors the handlers update.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests      *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	requestErrors *prometheus.CounterVec
+	queries       *prometheus.HistogramVec
+	logins        *prometheus.CounterVec
+	chirps        prometheus.Counter
+	webhooks      *prometheus.CounterVec
+}
+
+// New returns Metrics registered in a fresh registry, along with the Go
+// runtime and process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route pattern, method and status.",
+		}, []string{"route", "method", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route pattern, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		requestErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_request_errors_total",
+			Help:      "Requests that ended in an unexpected error, by kind: failed, cancelled or timed_out.",
+		}, []string{"kind"}),
+		queries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Database query latency by sqlc query name.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"query"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts by outcome: success, failure or restricted.",
+		}, []string{"outcome"}),
+		chirps: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "chirps_created_total",
+			Help:      "Chirps created.",
+		}),
+		webhooks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_events_total",
+			Help:      "Polka webhook deliveries by event and outcome.",
+		}, []string{"event", "outcome"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.duration,
+		m.requestErrors,
+		m.queries,
+		m.logins,
+		m.chirps,
+		m.webhooks,
+	)
+	return m
+}
+
+// Handler serves the registry in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterDBStats exports the connection pool statistics of db.
+func (m *Metrics) RegisterDBStats(db *sql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// RegisterCounterFunc exports a counter kept elsewhere.
+func (m *Metrics) RegisterCounterFunc(name, help string, f func() float64) {
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, f))
+}
+
+// RequestError counts a request that ended in an unexpected error.
+func (m *Metrics) RequestError(kind string) {
+	m.requestErrors.WithLabelValues(kind).Inc()
+}
+
+// Login counts a login attempt.
+func (m *Metrics) Login(outcome string) {
+	m.logins.WithLabelValues(outcome).Inc()
+}
+
+// ChirpCreated counts a new chirp.
+func (m *Metrics) ChirpCreated() {
+	m.chirps.Inc()
+}
+
+// Webhook counts a webhook delivery. event must come from a fixed set, not
+// straight from the payload, to keep the number of series bounded.
+func (m *Metrics) Webhook(event, outcome string) {
+	m.webhooks.WithLabelValues(event, outcome).Inc()
+}
+
+// Total sums the series of the named metric, e.g. "chirpy_logins_total",
+// whose labels match labelPairs ("outcome", "success", ...). Histograms
+// contribute their sample count. It returns 0 for an unknown metric.
+func (m *Metrics) Total(name string, labelPairs ...string) float64 {
+	families, err := m.registry.Gather()
+	if err != nil {
+		return 0
+	}
+	var total float64
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			if !hasLabels(metric, labelPairs) {
+				continue
+			}
+			switch {
+			case metric.Counter != nil:
+				total += metric.Counter.GetValue()
+			case metric.Gauge != nil:
+				total += metric.Gauge.GetValue()
+			case metric.Histogram != nil:
+				total += float64(metric.Histogram.GetSampleCount())
+			}
+		}
+	}
+	return total
+}
+
+func hasLabels(metric *dto.Metric, labelPairs []string) bool {
+	for i := 0; i+1 < len(labelPairs); i += 2 {
+		found := false
+		for _, l := range metric.GetLabel() {
+			if l.GetName() == labelPairs[i] && l.GetValue() == labelPairs[i+1] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// InstrumentHandler counts and times the requests next serves under route.
+func (m *Metrics) InstrumentHandler(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			labels := prometheus.Labels{"route": route, "method": r.Method, "status": strconv.Itoa(status)}
+			m.requests.With(labels).Inc()
+			m.duration.With(labels).Observe(time.Since(start).Seconds())
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// statusRecorder remembers the status written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
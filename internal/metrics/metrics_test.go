@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDB struct{}
+
+func (fakeDB) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, nil
+}
+
+func (fakeDB) PrepareContext(context.Context, string) (*sql.Stmt, error) { return nil, nil }
+
+func (fakeDB) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, nil
+}
+
+func (fakeDB) QueryRowContext(context.Context, string, ...interface{}) *sql.Row { return nil }
+
+func TestInstrumentDB(t *testing.T) {
+	m := New()
+	db := m.InstrumentDB(fakeDB{})
+
+	db.ExecContext(context.Background(), "-- name: DeleteChirp :exec\nDELETE FROM chirps")
+	db.QueryRowContext(context.Background(), "-- name: GetChirp :one\nSELECT 1")
+	db.QueryRowContext(context.Background(), "-- name: GetChirp :one\nSELECT 1")
+
+	assert.Equal(t, 1.0, m.Total("chirpy_db_query_duration_seconds", "query", "DeleteChirp"))
+	assert.Equal(t, 2.0, m.Total("chirpy_db_query_duration_seconds", "query", "GetChirp"))
+}
+
+func TestInstrumentHandler(t *testing.T) {
+	m := New()
+	h := m.InstrumentHandler("/api/chirps/{chirpID}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "missing") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+
+	for _, path := range []string{"/api/chirps/1", "/api/chirps/2", "/api/chirps/missing"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	assert.Equal(t, 2.0, m.Total("chirpy_http_requests_total", "route", "/api/chirps/{chirpID}", "status", "200"))
+	assert.Equal(t, 1.0, m.Total("chirpy_http_requests_total", "route", "/api/chirps/{chirpID}", "status", "404"))
+	assert.Equal(t, 3.0, m.Total("chirpy_http_request_duration_seconds", "method", "GET"))
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	m.Login(LoginSuccess)
+	m.ChirpCreated()
+	m.Webhook("user.upgraded", "upgraded")
+	rec := httptest.NewRecorder()
+
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rec.Body.String()
+	assert.Contains(t, body, `chirpy_logins_total{outcome="success"} 1`)
+	assert.Contains(t, body, `chirpy_chirps_created_total 1`)
+	assert.Contains(t, body, `chirpy_webhook_events_total{event="user.upgraded",outcome="upgraded"} 1`)
+	assert.Contains(t, body, `go_goroutines`)
+}
@@ -0,0 +1,163 @@
+// Package migrate applies the schema migrations embedded from sql/schema
+// with goose, and checks that a database's schema is one this build knows.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+
+	"github.com/Vikuuu/Chirpy/sql/schema"
+)
+
+// Errors returned by Check.
+var (
+	// ErrPending means migrations this build needs haven't been applied.
+	ErrPending = errors.New("schema has pending migrations")
+	// ErrUnknownVersion means the schema was migrated by a newer build.
+	ErrUnknownVersion = errors.New("schema version is unknown to this build")
+)
+
+// Migrator runs the embedded migrations against a database. Commands that
+// change the schema hold a Postgres advisory lock while they run, so that
+// instances migrating on startup take turns instead of racing.
+type Migrator struct {
+	provider *goose.Provider
+	// AllowNewer makes Check accept a schema a newer build has migrated
+	// further than this one knows.
+	AllowNewer bool
+}
+
+// New returns a Migrator for db. It doesn't touch the database.
+func New(db *sql.DB) (*Migrator, error) {
+	return newMigrator(db, schema.FS)
+}
+
+func newMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	locker, err := lock.NewPostgresSessionLocker(
+		// Check for the lock every second for up to five minutes.
+		lock.WithLockTimeout(1, 300),
+	)
+	if err != nil {
+		return nil, err
+	}
+	p, err := goose.NewProvider(goose.DialectPostgres, db, fsys,
+		goose.WithSessionLocker(locker),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("loading migrations: %w", err)
+	}
+	return &Migrator{provider: p}, nil
+}
+
+// Latest is the version of the newest embedded migration.
+func (m *Migrator) Latest() int64 {
+	sources := m.provider.ListSources()
+	if len(sources) == 0 {
+		return 0
+	}
+	return sources[len(sources)-1].Version
+}
+
+// Version returns the version the database's schema is at, 0 if it has
+// never been migrated.
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	current, _, err := m.provider.GetVersions(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("reading schema version: %w", err)
+	}
+	return current, nil
+}
+
+// Check reports whether the database's schema is exactly the one this build
+// was written against, wrapping ErrPending or ErrUnknownVersion if not.
+//
+// With AllowNewer, a schema a newer build has migrated further is accepted,
+// so that instances still on the previous build keep serving during a
+// rolling deploy. That is only safe if every migration leaves the schema
+// usable by the build before it.
+func (m *Migrator) Check(ctx context.Context) error {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	return checkVersion(version, m.Latest(), m.AllowNewer)
+}
+
+func checkVersion(version, latest int64, allowNewer bool) error {
+	switch {
+	case version < latest:
+		return fmt.Errorf("%w: at version %d, want %d", ErrPending, version, latest)
+	case version > latest && !allowNewer:
+		return fmt.Errorf("%w: at version %d, this build knows up to %d", ErrUnknownVersion, version, latest)
+	}
+	return nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) ([]*goose.MigrationResult, error) {
+	results, err := m.provider.Up(ctx)
+	if err != nil {
+		return results, fmt.Errorf("migrating up: %w", err)
+	}
+	return results, nil
+}
+
+// Down rolls back the newest applied migration.
+func (m *Migrator) Down(ctx context.Context) (*goose.MigrationResult, error) {
+	result, err := m.provider.Down(ctx)
+	if err != nil {
+		return result, fmt.Errorf("migrating down: %w", err)
+	}
+	return result, nil
+}
+
+// Redo rolls back the newest applied migration and applies it again.
+func (m *Migrator) Redo(ctx context.Context) ([]*goose.MigrationResult, error) {
+	down, err := m.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+	up, err := m.provider.ApplyVersion(ctx, down.Source.Version, true)
+	if err != nil {
+		return []*goose.MigrationResult{down}, fmt.Errorf("migrating up: %w", err)
+	}
+	return []*goose.MigrationResult{down, up}, nil
+}
+
+// PrintStatus writes a table of every migration and when it was applied.
+func (m *Migrator) PrintStatus(ctx context.Context, w io.Writer) error {
+	statuses, err := m.provider.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("reading migration status: %w", err)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tMIGRATION\tSTATE\tAPPLIED AT")
+	for _, s := range statuses {
+		applied := "-"
+		if s.State == goose.StateApplied {
+			applied = s.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", s.Source.Version, path.Base(s.Source.Path), s.State, applied)
+	}
+	return tw.Flush()
+}
+
+// PrintResults writes one line per migration applied or rolled back.
+func PrintResults(w io.Writer, results ...*goose.MigrationResult) {
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s %s (%s)\n", r.Direction, path.Base(r.Source.Path), r.Duration.Round(time.Millisecond))
+	}
+}
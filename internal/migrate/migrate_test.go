@@ -0,0 +1,106 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Vikuuu/Chirpy/sql/schema"
+)
+
+// openDB returns a handle that is never connected; building a Migrator
+// doesn't touch the database.
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("postgres", "postgres://localhost/unused")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestLatest(t *testing.T) {
+	files, err := fs.Glob(schema.FS, "*.sql")
+	require.NoError(t, err)
+
+	m, err := New(openDB(t))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(len(files)), m.Latest(), "migrations should be numbered 1 to N without gaps")
+}
+
+func TestNewRefusesDuplicateVersions(t *testing.T) {
+	fsys := fstest.MapFS{
+		"001_users.sql":  {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+		"001_chirps.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+	}
+
+	_, err := newMigrator(openDB(t), fsys)
+
+	assert.Error(t, err)
+}
+
+func TestCheckVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		version    int64
+		allowNewer bool
+		err        error
+	}{
+		{name: "Current", version: 5},
+		{name: "Pending", version: 4, err: ErrPending},
+		{name: "Newer", version: 6, err: ErrUnknownVersion},
+		{name: "Newer Allowed", version: 6, allowNewer: true},
+		{name: "Pending With Newer Allowed", version: 4, allowNewer: true, err: ErrPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkVersion(tt.version, 5, tt.allowNewer)
+			if tt.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+// TestCheckNewerSchema migrates the database in TEST_DB_URL and checks it
+// with a build that knows one migration fewer. It is skipped without one.
+func TestCheckNewerSchema(t *testing.T) {
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		t.Skip("TEST_DB_URL not set")
+	}
+	ctx := context.Background()
+	db, err := sql.Open("postgres", url)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	m, err := New(db)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	require.NoError(t, m.Check(ctx))
+
+	files, err := fs.Glob(schema.FS, "*.sql")
+	require.NoError(t, err)
+	older := fstest.MapFS{}
+	for _, name := range files[:len(files)-1] {
+		data, err := fs.ReadFile(schema.FS, name)
+		require.NoError(t, err)
+		older[name] = &fstest.MapFile{Data: data}
+	}
+	old, err := newMigrator(db, older)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, old.Check(ctx), ErrUnknownVersion)
+	old.AllowNewer = true
+	assert.NoError(t, old.Check(ctx))
+}
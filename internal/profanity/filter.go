@@ -0,0 +1,158 @@
+// Package profanity implements the word-list filter applied to chirp bodies.
+package profanity
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Action is what the filter does when a chirp contains a listed word.
+type Action string
+
+const (
+	// ActionMask replaces the word with mask characters.
+	ActionMask Action = "mask"
+	// ActionReject refuses the whole chirp.
+	ActionReject Action = "reject"
+	// ActionFlag accepts the chirp unchanged but marks it for review.
+	ActionFlag Action = "flag"
+)
+
+// ParseAction validates s as an Action.
+func ParseAction(s string) (Action, error) {
+	switch a := Action(strings.ToLower(strings.TrimSpace(s))); a {
+	case ActionMask, ActionReject, ActionFlag:
+		return a, nil
+	default:
+		return "", fmt.Errorf("unknown action %q", s)
+	}
+}
+
+// Entry is a single word in the filter list.
+type Entry struct {
+	Word   string
+	Action Action
+}
+
+// Options controls how masked words are rendered.
+type Options struct {
+	// MaskRune replaces every character of a masked word. Defaults to '*'.
+	MaskRune rune
+	// KeepFirst leaves the first character of a masked word visible.
+	KeepFirst bool
+}
+
+// Match describes one listed word found in a text.
+type Match struct {
+	Word   string // normalized list entry that matched
+	Action Action
+	Start  int // byte offset of the match in the original text
+	End    int
+}
+
+// Result is the outcome of running the filter over a text.
+type Result struct {
+	Text     string // text with masked words replaced
+	Rejected bool
+	Flagged  bool
+	Matches  []Match
+}
+
+// Filter matches text against a word list. It is safe for concurrent use and
+// the list can be swapped at any time with Load.
+type Filter struct {
+	mu      sync.RWMutex
+	entries map[string]Action
+	opts    Options
+}
+
+// New returns a filter holding entries.
+func New(opts Options, entries ...Entry) *Filter {
+	if opts.MaskRune == 0 {
+		opts.MaskRune = '*'
+	}
+	f := &Filter{opts: opts}
+	f.Load(entries)
+	return f
+}
+
+// Load replaces the word list. Words are normalized before they are stored.
+func (f *Filter) Load(entries []Entry) {
+	m := make(map[string]Action, len(entries))
+	for _, e := range entries {
+		w := Normalize(e.Word)
+		if w == "" {
+			continue
+		}
+		m[w] = e.Action
+	}
+
+	f.mu.Lock()
+	f.entries = m
+	f.mu.Unlock()
+}
+
+// Apply runs the filter over text.
+func (f *Filter) Apply(text string) Result {
+	f.mu.RLock()
+	entries := f.entries
+	f.mu.RUnlock()
+
+	res := Result{Text: text}
+	if len(entries) == 0 {
+		return res
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, t := range tokenize(text) {
+		m, ok := f.match(entries, text, t)
+		if !ok {
+			continue
+		}
+		res.Matches = append(res.Matches, m)
+
+		switch m.Action {
+		case ActionReject:
+			res.Rejected = true
+		case ActionFlag:
+			res.Flagged = true
+		case ActionMask:
+			b.WriteString(text[last:m.Start])
+			b.WriteString(f.mask(text[m.Start:m.End]))
+			last = m.End
+		}
+	}
+
+	if last > 0 {
+		b.WriteString(text[last:])
+		res.Text = b.String()
+	}
+	return res
+}
+
+// match looks the token up first as a whole and then with its leading and
+// trailing symbols removed.
+func (f *Filter) match(entries map[string]Action, text string, t token) (Match, bool) {
+	for _, c := range []token{t, trim(text, t)} {
+		if c.start == c.end {
+			continue
+		}
+		w := Normalize(text[c.start:c.end])
+		if a, ok := entries[w]; ok {
+			return Match{Word: w, Action: a, Start: c.start, End: c.end}, true
+		}
+	}
+	return Match{}, false
+}
+
+// mask returns a replacement for word with the same number of characters.
+func (f *Filter) mask(word string) string {
+	if !f.opts.KeepFirst {
+		return strings.Repeat(string(f.opts.MaskRune), utf8.RuneCountInString(word))
+	}
+	r, size := utf8.DecodeRuneInString(word)
+	return string(r) + strings.Repeat(string(f.opts.MaskRune), utf8.RuneCountInString(word[size:]))
+}
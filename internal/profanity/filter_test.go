@@ -0,0 +1,186 @@
+package profanity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testEntries = []Entry{
+	{Word: "kerfuffle", Action: ActionMask},
+	{Word: "sharbert", Action: ActionMask},
+	{Word: "fornax", Action: ActionMask},
+	{Word: "grawlix", Action: ActionReject},
+	{Word: "snollygoster", Action: ActionFlag},
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Lowercase", input: "kerfuffle", expected: "kerfuffle"},
+		{name: "Uppercase", input: "KERFUFFLE", expected: "kerfuffle"},
+		{name: "Fullwidth", input: "ｋｅｒｆｕｆｆｌｅ", expected: "kerfuffle"},
+		{name: "Diacritics", input: "kërfüfflé", expected: "kerfuffle"},
+		{name: "Cyrillic Homoglyphs", input: "kеrfufflе", expected: "kerfuffle"},
+		{name: "Greek Homoglyphs", input: "fοrnαx", expected: "fornax"},
+		{name: "Leetspeak", input: "k3rfuffl3", expected: "kerfuffle"},
+		{name: "Leetspeak Symbols", input: "$h@rbert", expected: "sharbert"},
+		{name: "Zero Width Space", input: "forn​ax", expected: "fornax"},
+		{name: "Sharp S Folding", input: "STRAẞE", expected: "strasse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Normalize(tt.input))
+		})
+	}
+}
+
+func TestFilterApply(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		expectedText string
+		rejected     bool
+		flagged      bool
+	}{
+		{
+			name:         "Clean Text",
+			input:        "I had something interesting for breakfast",
+			expectedText: "I had something interesting for breakfast",
+		},
+		{
+			name:         "Plain Word",
+			input:        "This is a kerfuffle opinion I need to share with the world",
+			expectedText: "This is a ********* opinion I need to share with the world",
+		},
+		{
+			name:         "Mixed Case",
+			input:        "I hear Mastodon is better than Chirpy. sharbert I need to migrate",
+			expectedText: "I hear Mastodon is better than Chirpy. ******** I need to migrate",
+		},
+		{
+			name:         "Trailing Punctuation",
+			input:        "What a Kerfuffle! Such a kerfuffle, really.",
+			expectedText: "What a *********! Such a *********, really.",
+		},
+		{
+			name:         "Surrounding Quotes",
+			input:        `He said "fornax" twice`,
+			expectedText: `He said "******" twice`,
+		},
+		{
+			name:         "Apostrophe Suffix",
+			input:        "the kerfuffle's end",
+			expectedText: "the *********'s end",
+		},
+		{
+			name:         "Leetspeak",
+			input:        "total k3rfuffl3 today",
+			expectedText: "total ********* today",
+		},
+		{
+			name:         "Leading Symbol",
+			input:        "$harbert again",
+			expectedText: "******** again",
+		},
+		{
+			name:         "Homoglyphs",
+			input:        "such fοrnаx",
+			expectedText: "such ******",
+		},
+		{
+			name:         "Fullwidth",
+			input:        "ｆｏｒｎａｘ!",
+			expectedText: "******!",
+		},
+		{
+			name:         "Substring Is Not A Match",
+			input:        "kerfuffles and fornaxian skies",
+			expectedText: "kerfuffles and fornaxian skies",
+		},
+		{
+			name:         "Rejected Word",
+			input:        "what a grawlix",
+			expectedText: "what a grawlix",
+			rejected:     true,
+		},
+		{
+			name:         "Flagged Word",
+			input:        "you Snollygoster.",
+			expectedText: "you Snollygoster.",
+			flagged:      true,
+		},
+		{
+			name:         "Mask And Flag",
+			input:        "snollygoster kerfuffle",
+			expectedText: "snollygoster *********",
+			flagged:      true,
+		},
+	}
+
+	f := New(Options{}, testEntries...)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := f.Apply(tt.input)
+			assert.Equal(t, tt.expectedText, res.Text)
+			assert.Equal(t, tt.rejected, res.Rejected)
+			assert.Equal(t, tt.flagged, res.Flagged)
+		})
+	}
+}
+
+func TestFilterMaskOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         Options
+		input        string
+		expectedText string
+	}{
+		{
+			name:         "Custom Rune",
+			opts:         Options{MaskRune: '#'},
+			input:        "a kerfuffle",
+			expectedText: "a #########",
+		},
+		{
+			name:         "Keep First",
+			opts:         Options{KeepFirst: true},
+			input:        "a kerfuffle",
+			expectedText: "a k********",
+		},
+		{
+			name:         "Length Counts Characters Not Bytes",
+			opts:         Options{},
+			input:        "a kërfüfflé",
+			expectedText: "a *********",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := New(tt.opts, testEntries...)
+			assert.Equal(t, tt.expectedText, f.Apply(tt.input).Text)
+		})
+	}
+}
+
+func TestFilterLoad(t *testing.T) {
+	f := New(Options{})
+	assert.Equal(t, "a kerfuffle", f.Apply("a kerfuffle").Text)
+
+	f.Load([]Entry{{Word: "KERFUFFLE", Action: ActionMask}})
+	assert.Equal(t, "a *********", f.Apply("a kerfuffle").Text)
+}
+
+func TestParseAction(t *testing.T) {
+	a, err := ParseAction(" Reject ")
+	assert.NoError(t, err)
+	assert.Equal(t, ActionReject, a)
+
+	_, err = ParseAction("delete")
+	assert.Error(t, err)
+}
@@ -0,0 +1,111 @@
+package profanity
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// confusables maps characters that are commonly used to disguise a word to
+// the Latin letter they imitate. It covers Cyrillic and Greek homoglyphs as
+// well as the usual leetspeak substitutions.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h',
+	'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'ѕ': 's',
+	'і': 'i', 'ї': 'i', 'ј': 'j', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'һ': 'h',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v',
+	'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w',
+	// Latin look-alikes that survive NFKC
+	'ı': 'i', 'ſ': 's', 'ℓ': 'l',
+	// Leetspeak
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b',
+	'@': 'a', '$': 's', '!': 'i', '|': 'l', '+': 't',
+}
+
+var folder = cases.Fold()
+
+// Normalize reduces a word to the canonical form used for matching: NFKC
+// compatibility composition, Unicode case folding, removal of diacritics and
+// invisible format characters, and confusable/leetspeak substitution.
+func Normalize(word string) string {
+	s := norm.NFKC.String(word)
+	s = folder.String(s)
+	s = norm.NFD.String(s)
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r) {
+			continue
+		}
+		if c, ok := confusables[r]; ok {
+			r = c
+		}
+		b.WriteRune(r)
+	}
+
+	return norm.NFC.String(b.String())
+}
+
+// isWordRune reports whether r can be part of a token. Leetspeak symbols and
+// invisible format characters are included so that "sh@rbert" or a word split
+// by a zero-width space is still read as a single token.
+func isWordRune(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.M, r) || unicode.Is(unicode.Cf, r) {
+		return true
+	}
+	_, ok := confusables[r]
+	return ok
+}
+
+// isCoreRune reports whether r is a letter, digit or mark, i.e. something that
+// is never stripped from the edges of a token.
+func isCoreRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.M, r)
+}
+
+type token struct {
+	start, end int // byte offsets into the original text
+}
+
+// tokenize splits text into runs of word runes and returns their byte
+// offsets. Whitespace and punctuation that is not a leetspeak symbol act as
+// separators.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// trim narrows a token to its letters and digits, dropping leading and
+// trailing symbols such as the "!" in "kerfuffle!".
+func trim(text string, t token) token {
+	s := text[t.start:t.end]
+	left := strings.IndexFunc(s, isCoreRune)
+	if left < 0 {
+		return token{start: t.start, end: t.start}
+	}
+	right := strings.LastIndexFunc(s, isCoreRune)
+	_, size := utf8.DecodeRuneInString(s[right:])
+	return token{start: t.start + left, end: t.start + right + size}
+}
@@ -0,0 +1,36 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so that every
+// Chirpy instance sharing the database enforces the same limits. Refills use
+// the database clock.
+type PostgresStore struct {
+	db *database.Queries
+}
+
+// NewPostgresStore returns a PostgresStore using db.
+func NewPostgresStore(db *database.Queries) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Decision, error) {
+	row, err := s.db.TakeRateLimitToken(ctx, database.TakeRateLimitTokenParams{
+		Burst: float64(limit.burst()),
+		Rate:  limit.rate(),
+		Key:   key,
+	})
+	if err != nil {
+		return Decision{}, err
+	}
+	return decide(limit, row.Tokens, row.Allowed), nil
+}
+
+func (s *PostgresStore) Sweep(ctx context.Context, idle time.Duration) (int64, error) {
+	return s.db.DeleteIdleRateLimitBuckets(ctx, idle.Seconds())
+}
@@ -0,0 +1,152 @@
+// Package ratelimit implements token-bucket rate limiting with pluggable
+// bucket storage.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests per Per on average, with bursts of up to Burst
+// requests. A zero Burst means Burst == Requests.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// rate is the refill rate in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+func (l Limit) String() string {
+	s := fmt.Sprintf("%d/%s", l.Requests, l.Per)
+	if l.Burst > 0 {
+		s += fmt.Sprintf(":%d", l.Burst)
+	}
+	return s
+}
+
+// ParseLimit parses "requests/period[:burst]", e.g. "30/1m" or "5/1s:20".
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	spec, burstStr, hasBurst := strings.Cut(s, ":")
+	reqStr, perStr, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q: want requests/period", s)
+	}
+
+	req, err := strconv.Atoi(reqStr)
+	if err != nil || req <= 0 {
+		return Limit{}, fmt.Errorf("limit %q: requests must be a positive integer", s)
+	}
+	per, err := time.ParseDuration(perStr)
+	if err != nil || per <= 0 {
+		return Limit{}, fmt.Errorf("limit %q: period must be a positive duration", s)
+	}
+
+	l := Limit{Requests: req, Per: per}
+	if hasBurst {
+		l.Burst, err = strconv.Atoi(burstStr)
+		if err != nil || l.Burst <= 0 {
+			return Limit{}, fmt.Errorf("limit %q: burst must be a positive integer", s)
+		}
+	}
+	return l, nil
+}
+
+// Policy holds the limit for each kind of principal on a route. Principals
+// without an entry are not limited.
+type Policy map[string]Limit
+
+// ParsePolicy parses a comma-separated list of principal=limit pairs, e.g.
+// "anonymous=10/1m,user=30/1m".
+func ParsePolicy(s string) (Policy, error) {
+	p := Policy{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		principal, limit, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("policy entry %q: want principal=limit", part)
+		}
+		l, err := ParseLimit(limit)
+		if err != nil {
+			return nil, err
+		}
+		p[strings.TrimSpace(principal)] = l
+	}
+	return p, nil
+}
+
+// Decision is the outcome of taking a token from a bucket.
+type Decision struct {
+	Allowed    bool
+	Limit      int           // bucket capacity
+	Remaining  int           // whole tokens left after this request
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token, when not allowed
+}
+
+// decide builds a Decision from the bucket level after the request.
+func decide(l Limit, tokens float64, allowed bool) Decision {
+	rate := l.rate()
+	d := Decision{
+		Allowed:   allowed,
+		Limit:     l.burst(),
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     seconds((float64(l.burst()) - tokens) / rate),
+	}
+	if !allowed {
+		d.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return d
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
+
+// Store keeps token buckets.
+type Store interface {
+	// Take refills the bucket at key according to limit and removes one
+	// token from it if one is available.
+	Take(ctx context.Context, key string, limit Limit) (Decision, error)
+	// Sweep forgets buckets that have not been used for idle. Such buckets
+	// would be full again anyway.
+	Sweep(ctx context.Context, idle time.Duration) (int64, error)
+}
+
+// WriteHeaders sets the RateLimit-* headers from the IETF rate limit header
+// draft, plus Retry-After when the request was refused.
+func WriteHeaders(h http.Header, l Limit, d Decision) {
+	h.Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", l.Requests, ceilSeconds(l.Per)))
+	if !d.Allowed {
+		h.Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(d.RetryAfter))))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,198 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/migrate"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestStore() (*MemoryStore, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s := NewMemoryStore()
+	s.now = clock.now
+	return s, clock
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	s, clock := newTestStore()
+	limit := Limit{Requests: 3, Per: time.Minute}
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		d, err := s.Take(ctx, "k", limit)
+		assert.NoError(t, err)
+		assert.True(t, d.Allowed)
+		assert.Equal(t, i, d.Remaining)
+		assert.Equal(t, 3, d.Limit)
+	}
+
+	d, err := s.Take(ctx, "k", limit)
+	assert.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+	assert.Equal(t, 20*time.Second, d.RetryAfter)
+
+	// One token comes back every 20 seconds.
+	clock.t = clock.t.Add(20 * time.Second)
+	d, err = s.Take(ctx, "k", limit)
+	assert.NoError(t, err)
+	assert.True(t, d.Allowed)
+
+	// Other keys have their own bucket.
+	d, err = s.Take(ctx, "other", limit)
+	assert.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 2, d.Remaining)
+}
+
+func TestMemoryStoreBurst(t *testing.T) {
+	s, clock := newTestStore()
+	limit := Limit{Requests: 1, Per: time.Second, Burst: 5}
+	ctx := context.Background()
+
+	for range 5 {
+		d, _ := s.Take(ctx, "k", limit)
+		assert.True(t, d.Allowed)
+	}
+	d, _ := s.Take(ctx, "k", limit)
+	assert.False(t, d.Allowed)
+
+	// Refills never exceed the burst size.
+	clock.t = clock.t.Add(time.Hour)
+	d, _ = s.Take(ctx, "k", limit)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 4, d.Remaining)
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s, clock := newTestStore()
+	limit := Limit{Requests: 1, Per: time.Second}
+	ctx := context.Background()
+
+	s.Take(ctx, "old", limit)
+	clock.t = clock.t.Add(time.Hour)
+	s.Take(ctx, "new", limit)
+
+	n, err := s.Sweep(ctx, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Len(t, s.buckets, 1)
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    Limit
+		expectError bool
+	}{
+		{name: "Requests Per Minute", input: "30/1m", expected: Limit{Requests: 30, Per: time.Minute}},
+		{name: "With Burst", input: "5/1s:20", expected: Limit{Requests: 5, Per: time.Second, Burst: 20}},
+		{name: "Missing Period", input: "30", expectError: true},
+		{name: "Zero Requests", input: "0/1m", expectError: true},
+		{name: "Bad Period", input: "30/soon", expectError: true},
+		{name: "Bad Burst", input: "30/1m:x", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := ParseLimit(tt.input)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, l)
+		})
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy("anonymous=10/1m, user=30/1m")
+	assert.NoError(t, err)
+	assert.Equal(t, Policy{
+		"anonymous": {Requests: 10, Per: time.Minute},
+		"user":      {Requests: 30, Per: time.Minute},
+	}, p)
+
+	_, err = ParsePolicy("user")
+	assert.Error(t, err)
+}
+
+func TestWriteHeaders(t *testing.T) {
+	limit := Limit{Requests: 3, Per: time.Minute}
+	h := http.Header{}
+	WriteHeaders(h, limit, Decision{Allowed: false, Limit: 3, Remaining: 0, Reset: 60 * time.Second, RetryAfter: 19500 * time.Millisecond})
+
+	assert.Equal(t, "3", h.Get("RateLimit-Limit"))
+	assert.Equal(t, "0", h.Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", h.Get("RateLimit-Reset"))
+	assert.Equal(t, "3;w=60", h.Get("RateLimit-Policy"))
+	assert.Equal(t, "20", h.Get("Retry-After"))
+}
+
+// TestPostgresStoreConcurrentTakes races takes on one key against the
+// database in TEST_DB_URL, which it migrates. It is skipped without one.
+func TestPostgresStoreConcurrentTakes(t *testing.T) {
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		t.Skip("TEST_DB_URL not set")
+	}
+	ctx := context.Background()
+	db, err := sql.Open("postgres", url)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	migrator, err := migrate.New(db)
+	require.NoError(t, err)
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	s := NewPostgresStore(database.New(db))
+
+	limit := Limit{Requests: 10, Per: time.Hour}
+	for _, round := range []string{"New Bucket", "Existing Bucket"} {
+		t.Run(round, func(t *testing.T) {
+			key := "test:" + t.Name()
+			if round == "Existing Bucket" {
+				_, err := db.ExecContext(ctx, "INSERT INTO rate_limit_buckets (key, tokens, allowed, updated_at) VALUES ($1, 10, TRUE, NOW())", key)
+				require.NoError(t, err)
+			}
+			t.Cleanup(func() { db.Exec("DELETE FROM rate_limit_buckets WHERE key = $1", key) })
+
+			var (
+				wg      sync.WaitGroup
+				allowed atomic.Int32
+			)
+			for range 50 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					d, err := s.Take(ctx, key, limit)
+					assert.NoError(t, err)
+					if d.Allowed {
+						allowed.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+			assert.EqualValues(t, 10, allowed.Load(), "no take is lost")
+		})
+	}
+}
@@ -0,0 +1,234 @@
+// Package seed generates realistic sample data, with Chirpy Red members, a
+// follow graph and chirps spread over weeks, from a fixed random seed. The
+// same options always give the same users, ids and timestamps, so demos and
+// integration and load tests can start from data they know.
+package seed
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+const (
+	// DefaultSeed is the seed used when none is given.
+	DefaultSeed = 1
+	// DefaultPassword is the password of every sample user.
+	DefaultPassword = "password123"
+	// DefaultSpan is how far back the sample data goes.
+	DefaultSpan = 90 * 24 * time.Hour
+
+	// maxFollows is the most users a sample user follows.
+	maxFollows = 15
+	// redOdds is one in how many sample users are Chirpy Red members.
+	redOdds = 5
+)
+
+// Options say what to generate.
+type Options struct {
+	Seed   uint64
+	Users  int
+	Chirps int // in total, not per user
+	// Until is when the data ends; users join and chirp over the Span
+	// before it. It defaults to the start of the current day in UTC, so
+	// that runs on the same day agree on timestamps too.
+	Until time.Time
+	Span  time.Duration
+}
+
+// Data is generated sample data, ready to insert. The users' passwords are
+// left for Insert to set.
+type Data struct {
+	Users   []database.SeedUserParams
+	Follows []database.CreateFollowParams
+	Chirps  []database.SeedChirpParams
+}
+
+// Counts are the rows Insert added.
+type Counts struct {
+	Users     int
+	ChirpyRed int
+	Follows   int
+	Chirps    int
+}
+
+// Generate returns the sample data for o. Users are in the order they
+// joined, and follows and chirps in the order they happened.
+func Generate(o Options) Data {
+	if o.Until.IsZero() {
+		o.Until = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+	if o.Span <= 0 {
+		o.Span = DefaultSpan
+	}
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[:], o.Seed)
+	src := rand.NewChaCha8(key)
+	g := generator{r: rand.New(src), src: src, until: o.Until.UTC().Truncate(time.Microsecond)}
+	start := g.until.Add(-o.Span)
+
+	var d Data
+	taken := map[string]bool{}
+	for i := 0; i < o.Users; i++ {
+		first, last := pick(g.r, firstNames), pick(g.r, lastNames)
+		email := first + "." + last + "@example.com"
+		for n := 2; taken[email]; n++ {
+			email = fmt.Sprintf("%s.%s%d@example.com", first, last, n)
+		}
+		taken[email] = true
+		d.Users = append(d.Users, database.SeedUserParams{
+			ID: g.id(),
+			// Everyone has joined by two thirds of the way through, so
+			// that the newest users have a while to chirp too.
+			CreatedAt:   g.between(start, start.Add(o.Span*2/3)),
+			Email:       email,
+			IsChirpyRed: g.r.IntN(redOdds) == 0,
+		})
+	}
+
+	// A few users are followed by many and chirp a lot, as on any network:
+	// skewed favours the users generated first.
+	for i, follower := range d.Users {
+		want := g.r.IntN(min(len(d.Users)-1, maxFollows) + 1)
+		followed := map[int]bool{i: true}
+		for tries := 0; len(followed)-1 < want && tries < 4*want; tries++ {
+			j := skewed(g.r, len(d.Users))
+			if followed[j] {
+				continue
+			}
+			followed[j] = true
+			followee := d.Users[j]
+			d.Follows = append(d.Follows, database.CreateFollowParams{
+				FollowerID: follower.ID,
+				FolloweeID: followee.ID,
+				CreatedAt:  g.between(later(follower.CreatedAt, followee.CreatedAt), g.until),
+			})
+		}
+	}
+
+	for i := 0; i < o.Chirps && len(d.Users) > 0; i++ {
+		author := d.Users[skewed(g.r, len(d.Users))]
+		d.Chirps = append(d.Chirps, database.SeedChirpParams{
+			ID:        g.id(),
+			CreatedAt: g.between(author.CreatedAt, g.until),
+			Body:      g.chirp(),
+			UserID:    author.ID,
+		})
+	}
+
+	slices.SortStableFunc(d.Users, func(a, b database.SeedUserParams) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	slices.SortStableFunc(d.Follows, func(a, b database.CreateFollowParams) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	slices.SortStableFunc(d.Chirps, func(a, b database.SeedChirpParams) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return d
+}
+
+// Insert adds the data to st in one transaction, every user with
+// hashedPassword. Rows already there are left alone, as are the chirps and
+// follows of users whose email was taken, so inserting the same data twice
+// adds nothing the second time.
+func (d Data) Insert(ctx context.Context, st store.Store, hashedPassword string) (Counts, error) {
+	var counts Counts
+	err := st.InTx(ctx, func(tx store.Tx) error {
+		var err error
+		counts, err = d.InsertTx(ctx, tx, hashedPassword)
+		return err
+	})
+	return counts, err
+}
+
+// InsertTx is Insert as part of a transaction the caller runs.
+func (d Data) InsertTx(ctx context.Context, tx store.Tx, hashedPassword string) (Counts, error) {
+	var counts Counts
+	inserted := map[uuid.UUID]bool{}
+	for _, u := range d.Users {
+		u.HashedPassword = hashedPassword
+		n, err := tx.SeedUser(ctx, u)
+		if err != nil {
+			return Counts{}, fmt.Errorf("seeding %s: %w", u.Email, err)
+		}
+		if n == 0 {
+			continue
+		}
+		inserted[u.ID] = true
+		counts.Users++
+		if u.IsChirpyRed {
+			counts.ChirpyRed++
+		}
+	}
+	for _, f := range d.Follows {
+		if !inserted[f.FollowerID] || !inserted[f.FolloweeID] {
+			continue
+		}
+		n, err := tx.CreateFollow(ctx, f)
+		if err != nil {
+			return Counts{}, fmt.Errorf("seeding follow: %w", err)
+		}
+		counts.Follows += int(n)
+	}
+	for _, c := range d.Chirps {
+		if !inserted[c.UserID] {
+			continue
+		}
+		n, err := tx.SeedChirp(ctx, c)
+		if err != nil {
+			return Counts{}, fmt.Errorf("seeding chirp: %w", err)
+		}
+		counts.Chirps += int(n)
+	}
+	return counts, nil
+}
+
+// generator draws everything from one stream, so the order of the draws is
+// part of what a seed means.
+type generator struct {
+	r     *rand.Rand
+	src   *rand.ChaCha8
+	until time.Time
+}
+
+func (g generator) id() uuid.UUID {
+	id, err := uuid.NewRandomFromReader(g.src)
+	if err != nil {
+		// ChaCha8 never fails to read.
+		panic(err)
+	}
+	return id
+}
+
+// between returns a time in [from, to), to the microsecond Postgres keeps.
+func (g generator) between(from, to time.Time) time.Time {
+	d := to.Sub(from)
+	if d <= 0 {
+		return from
+	}
+	return from.Add(time.Duration(g.r.Int64N(int64(d)))).Truncate(time.Microsecond)
+}
+
+func (g generator) chirp() string {
+	body := fmt.Sprintf(pick(g.r, templates), pick(g.r, topics))
+	return strings.ToUpper(body[:1]) + body[1:]
+}
+
+// skewed picks one of n, favouring the first.
+func skewed(r *rand.Rand, n int) int {
+	f := r.Float64()
+	return int(f * f * float64(n))
+}
+
+func pick(r *rand.Rand, from []string) string { return from[r.IntN(len(from))] }
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
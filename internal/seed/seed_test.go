@@ -0,0 +1,114 @@
+package seed
+
+import (
+	"context"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+var until = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	o := Options{Seed: 42, Users: 30, Chirps: 200, Until: until}
+
+	assert.Equal(t, Generate(o), Generate(o))
+
+	other := o
+	other.Seed = 43
+	assert.NotEqual(t, Generate(o).Users, Generate(other).Users)
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name   string
+		users  int
+		chirps int
+	}{
+		{name: "Empty", users: 0, chirps: 10},
+		{name: "One User", users: 1, chirps: 5},
+		{name: "Many Users", users: 100, chirps: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Generate(Options{Seed: DefaultSeed, Users: tt.users, Chirps: tt.chirps, Until: until})
+
+			require.Len(t, d.Users, tt.users)
+			joined := map[uuid.UUID]time.Time{}
+			emails := map[string]bool{}
+			red := 0
+			for _, u := range d.Users {
+				assert.False(t, emails[u.Email], "%s is used twice", u.Email)
+				emails[u.Email] = true
+				assert.True(t, u.CreatedAt.After(until.Add(-DefaultSpan)) && u.CreatedAt.Before(until))
+				joined[u.ID] = u.CreatedAt
+				if u.IsChirpyRed {
+					red++
+				}
+			}
+			if tt.users >= 100 {
+				assert.Positive(t, red, "some users are Chirpy Red members")
+				assert.Less(t, red, tt.users/2)
+			}
+
+			follows := map[[2]uuid.UUID]bool{}
+			for _, f := range d.Follows {
+				assert.NotEqual(t, f.FollowerID, f.FolloweeID, "nobody follows themselves")
+				key := [2]uuid.UUID{f.FollowerID, f.FolloweeID}
+				assert.False(t, follows[key], "follows are unique")
+				follows[key] = true
+				assert.False(t, f.CreatedAt.Before(joined[f.FollowerID]))
+				assert.False(t, f.CreatedAt.Before(joined[f.FolloweeID]))
+			}
+			if tt.users >= 100 {
+				assert.NotEmpty(t, d.Follows)
+			}
+
+			if tt.users == 0 {
+				assert.Empty(t, d.Chirps, "chirps need an author")
+				return
+			}
+			require.Len(t, d.Chirps, tt.chirps)
+			for i, c := range d.Chirps {
+				assert.False(t, c.CreatedAt.Before(joined[c.UserID]), "chirps come after their author joined")
+				assert.True(t, c.CreatedAt.Before(until))
+				assert.LessOrEqual(t, utf8.RuneCountInString(c.Body), 140)
+				if i > 0 {
+					assert.False(t, c.CreatedAt.Before(d.Chirps[i-1].CreatedAt), "chirps are in the order they were made")
+				}
+			}
+		})
+	}
+}
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemory()
+	d := Generate(Options{Seed: 7, Users: 20, Chirps: 150, Until: until})
+
+	counts, err := d.Insert(ctx, st, "hash")
+	require.NoError(t, err)
+	assert.Equal(t, 20, counts.Users)
+	assert.Equal(t, len(d.Follows), counts.Follows)
+	assert.Equal(t, 150, counts.Chirps)
+
+	users, err := st.ListUsers(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 20)
+	assert.Equal(t, d.Users[0].Email, users[0].Email)
+	assert.Equal(t, "hash", users[0].HashedPassword)
+	chirps, err := st.GetChirps(ctx, uuid.NullUUID{})
+	require.NoError(t, err)
+	assert.Len(t, chirps, 150)
+
+	again, err := d.Insert(ctx, st, "hash")
+	require.NoError(t, err)
+	assert.Equal(t, Counts{}, again, "inserting the same data twice adds nothing")
+}
@@ -0,0 +1,38 @@
+package seed
+
+var firstNames = []string{
+	"ada", "alan", "barbara", "claude", "dennis", "edsger", "frances", "grace",
+	"hedy", "ivan", "jean", "ken", "linus", "margaret", "niklaus", "ola",
+	"radia", "rob", "sophie", "tim", "whitfield", "yukihiro",
+}
+
+var lastNames = []string{
+	"allen", "backus", "hamilton", "hopper", "kay", "knuth", "lamport",
+	"liskov", "lovelace", "perlman", "pike", "ritchie", "shannon", "sutherland",
+	"thompson", "turing", "wilson", "wirth",
+}
+
+// templates are chirp bodies with a topic to fill in. Each fits in a chirp
+// with any topic.
+var templates = []string{
+	"just finished reading about %s.",
+	"hot take: %s is underrated.",
+	"anyone else spending the weekend on %s?",
+	"coffee first, then %s.",
+	"finally understand %s. took long enough!",
+	"does anyone have a good intro to %s?",
+	"spent all day on %s and loved it.",
+	"%s is harder than it looks.",
+	"giving a talk on %s next week, wish me luck.",
+	"today I learned something new about %s.",
+	"can't stop thinking about %s.",
+	"shipping something with %s this week.",
+}
+
+var topics = []string{
+	"databases", "garbage collection", "type systems", "compilers", "sourdough",
+	"trail running", "chess openings", "mechanical keyboards", "birdwatching",
+	"distributed systems", "houseplants", "jazz piano", "rust", "go generics",
+	"woodworking", "film photography", "board games", "espresso", "sql indexes",
+	"the night sky", "crossword puzzles", "vim", "cycling", "pottery",
+}
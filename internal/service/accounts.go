@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/auth"
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// purgeBatch is how many due deletions PurgeDeletedAccounts takes on at a
+// time.
+const purgeBatch = 100
+
+// DeleteAccount deletes a user's account once they have confirmed their
+// password. With a grace period, the deletion is only scheduled: the user's
+// sessions are ended, and logging in again before it is due cancels it. It
+// returns when the account will be deleted, or the zero time if it already
+// has been.
+//
+// As in Login, the password is checked outside the transaction, which then
+// makes sure it hasn't changed since.
+func (s *Service) DeleteAccount(ctx context.Context, userID uuid.UUID, password string) (time.Time, error) {
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, fail(NotFound, "user not found")
+		}
+		return time.Time{}, fmt.Errorf("getting user: %w", err)
+	}
+	if err := auth.CheckPasswordHash(ctx, password, user.HashedPassword); err != nil {
+		return time.Time{}, invalidFields(FieldError{Field: "password", Code: "incorrect_password", Message: "password is incorrect"})
+	}
+
+	var deleteAfter time.Time
+	err = s.atomically(ctx, func(tx store.Tx) error {
+		current, err := tx.GetUserByID(ctx, userID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fail(NotFound, "user not found")
+			}
+			return fmt.Errorf("getting user: %w", err)
+		}
+		if current.HashedPassword != user.HashedPassword {
+			return invalidFields(FieldError{Field: "password", Code: "incorrect_password", Message: "password is incorrect"})
+		}
+
+		if s.deletionGrace == 0 {
+			return deleteAccount(ctx, tx, userID, userID)
+		}
+		d, err := tx.ScheduleAccountDeletion(ctx, database.ScheduleAccountDeletionParams{
+			UserID:      userID,
+			DeleteAfter: time.Now().UTC().Add(s.deletionGrace),
+		})
+		if err != nil {
+			return fmt.Errorf("scheduling deletion: %w", err)
+		}
+		if _, err := tx.RevokeAllRefreshTokensForUser(ctx, userID); err != nil {
+			return fmt.Errorf("revoking refresh tokens: %w", err)
+		}
+		deleteAfter = d.DeleteAfter
+		return audit(ctx, tx, auditEvent{
+			Action:     AuditUserDeleteRequested,
+			Actor:      userID,
+			TargetType: AuditTargetUser,
+			TargetID:   userID.String(),
+			Details:    map[string]time.Time{"delete_after": d.DeleteAfter},
+		})
+	})
+	return deleteAfter, err
+}
+
+// cancelAccountDeletion cancels the user's scheduled deletion, if there is
+// one.
+func cancelAccountDeletion(ctx context.Context, tx store.Tx, userID uuid.UUID) error {
+	n, err := tx.CancelAccountDeletion(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("cancelling deletion: %w", err)
+	}
+	if n == 0 {
+		return nil
+	}
+	return audit(ctx, tx, auditEvent{
+		Action:     AuditUserDeletionCancelled,
+		Actor:      userID,
+		TargetType: AuditTargetUser,
+		TargetID:   userID.String(),
+	})
+}
+
+// PurgeDeletedAccounts deletes the accounts whose grace period ended by now,
+// each in a transaction of its own, and counts them. A deletion cancelled in
+// the meantime is left alone.
+func (s *Service) PurgeDeletedAccounts(ctx context.Context, now time.Time) (int, error) {
+	var purged int
+	for {
+		due, err := s.store.ListDueAccountDeletions(ctx, database.ListDueAccountDeletionsParams{
+			DeleteAfter: now.UTC(),
+			Limit:       purgeBatch,
+		})
+		if err != nil {
+			return purged, fmt.Errorf("listing due deletions: %w", err)
+		}
+		for _, d := range due {
+			var deleted bool
+			err := s.atomically(ctx, func(tx store.Tx) error {
+				deleted = false
+				current, err := tx.GetAccountDeletion(ctx, d.UserID)
+				if err != nil {
+					if err == sql.ErrNoRows {
+						return nil
+					}
+					return fmt.Errorf("getting deletion: %w", err)
+				}
+				if current.DeleteAfter.After(now) {
+					return nil
+				}
+				deleted = true
+				return deleteAccount(ctx, tx, d.UserID, uuid.Nil)
+			})
+			if err != nil {
+				return purged, err
+			}
+			if deleted {
+				purged++
+			}
+		}
+		if len(due) < purgeBatch {
+			return purged, nil
+		}
+	}
+}
+
+// deleteAccount deletes a user with their chirps and sessions, which the
+// database would cascade to anyway, so that the audit log can count them.
+// actor is who deleted the account, or uuid.Nil if the grace period ran out.
+func deleteAccount(ctx context.Context, tx store.Tx, userID, actor uuid.UUID) error {
+	chirps, err := tx.DeleteChirpsForUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("deleting chirps: %w", err)
+	}
+	tokens, err := tx.DeleteRefreshTokensForUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("deleting refresh tokens: %w", err)
+	}
+	if _, err := tx.DeleteUser(ctx, userID); err != nil {
+		return fmt.Errorf("deleting user: %w", err)
+	}
+	return audit(ctx, tx, auditEvent{
+		Action:     AuditUserDelete,
+		Actor:      actor,
+		TargetType: AuditTargetUser,
+		TargetID:   userID.String(),
+		Details:    map[string]int64{"chirps": chirps, "refresh_tokens": tokens},
+	})
+}
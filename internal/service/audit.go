@@ -0,0 +1,294 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// Audit actions.
+const (
+	AuditLogin                 = "auth.login"
+	AuditLoginFailed           = "auth.login_failed"
+	AuditRefresh               = "auth.refresh"
+	AuditRevoke                = "auth.revoke"
+	AuditUserUpdate            = "user.update"
+	AuditUserUpgrade           = "user.upgrade"
+	AuditUserDeleteRequested   = "user.delete_requested"
+	AuditUserDeletionCancelled = "user.deletion_cancelled"
+	AuditUserDelete            = "user.delete"
+	AuditUserExport            = "user.export"
+	AuditChirpDelete           = "chirp.delete"
+	AuditAdminReset            = "admin.reset"
+	AuditModerate              = "admin.moderate"
+	AuditDismissReport         = "admin.dismiss_report"
+	AuditPutBannedWord         = "admin.put_banned_word"
+	AuditDeleteBannedWord      = "admin.delete_banned_word"
+	AuditCreateUser            = "admin.create_user"
+	AuditSetRole               = "admin.set_role"
+	AuditResetPassword         = "admin.reset_password"
+	AuditRevokeSessions        = "admin.revoke_sessions"
+	AuditPurgeChirps           = "admin.purge_chirps"
+)
+
+// Kinds of audit target, besides the reset scopes.
+const (
+	AuditTargetUser         = "user"
+	AuditTargetChirp        = "chirp"
+	AuditTargetRefreshToken = "refresh_token"
+	AuditTargetReport       = "report"
+	AuditTargetBannedWord   = "banned_word"
+	AuditTargetDataExport   = "data_export"
+)
+
+// Why a login failed, as recorded in the audit log.
+const (
+	loginUnknownEmail  = "unknown_email"
+	loginWrongPassword = "wrong_password"
+	loginRestricted    = "restricted"
+)
+
+// How many audit events a page lists unless asked for fewer, and at most.
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 1000
+)
+
+// Origin is where a request came from, recorded with the audit events it
+// causes.
+type Origin struct {
+	IP        string
+	UserAgent string
+	RequestID string
+}
+
+type originKey struct{}
+
+// WithOrigin returns a context carrying o, for the audit events recorded
+// under it.
+func WithOrigin(ctx context.Context, o Origin) context.Context {
+	return context.WithValue(ctx, originKey{}, o)
+}
+
+func originFrom(ctx context.Context) Origin {
+	o, _ := ctx.Value(originKey{}).(Origin)
+	return o
+}
+
+// auditEvent is something to record in the audit log.
+type auditEvent struct {
+	Action     string
+	Actor      uuid.UUID
+	TargetType string
+	TargetID   string
+	// Details is encoded as JSON. It must never hold a secret.
+	Details any
+}
+
+// change is one field of an audited update. Secrets are only ever recorded
+// as redacted.
+type change struct {
+	Old      any  `json:"old,omitempty"`
+	New      any  `json:"new,omitempty"`
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// tokenFingerprint identifies a refresh token in the audit log without
+// recording the token itself.
+func tokenFingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+// emailFingerprint identifies an email address in the audit log without
+// recording the address, which must not outlive the account it belongs to:
+// the log can't be edited when the account is deleted. It is keyed, so that
+// the address can't be found by hashing guesses.
+func (s *Service) emailFingerprint(email string) string {
+	mac := hmac.New(sha256.New, []byte(s.secret))
+	fmt.Fprintf(mac, "email\x00%s", email)
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// audit records e in tx, so that the event is only logged if what it
+// describes is committed.
+func audit(ctx context.Context, tx store.Tx, e auditEvent) error {
+	details := json.RawMessage("{}")
+	if e.Details != nil {
+		var err error
+		if details, err = json.Marshal(e.Details); err != nil {
+			return fmt.Errorf("encoding audit details: %w", err)
+		}
+	}
+	o := originFrom(ctx)
+	_, err := tx.CreateAuditEvent(ctx, database.CreateAuditEventParams{
+		Action:     e.Action,
+		ActorID:    uuid.NullUUID{UUID: e.Actor, Valid: e.Actor != uuid.Nil},
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		Ip:         o.IP,
+		UserAgent:  o.UserAgent,
+		RequestID:  o.RequestID,
+		Details:    details,
+	})
+	if err != nil {
+		return fmt.Errorf("recording %s: %w", e.Action, err)
+	}
+	return nil
+}
+
+// AuditQuery selects audit events. Zero fields select everything.
+type AuditQuery struct {
+	Action     string
+	ActorID    uuid.NullUUID
+	TargetType string
+	TargetID   string
+	// Since and Until bound when the events happened, Since inclusive.
+	Since time.Time
+	Until time.Time
+	// Cursor continues a listing from the AuditPage.Next of its last page.
+	Cursor string
+	// Limit caps the events in a page: DefaultAuditLimit if it is 0, and
+	// at most MaxAuditLimit.
+	Limit int
+}
+
+// AuditPage is one page of audit events, newest first.
+type AuditPage struct {
+	Events []database.AuditEvent
+	// Next is the cursor of the following page, or empty on the last one.
+	Next string
+}
+
+// AuditEvents lists a page of the audit events q selects.
+func (s *Service) AuditEvents(ctx context.Context, q AuditQuery) (AuditPage, error) {
+	arg, err := q.params()
+	if err != nil {
+		return AuditPage{}, err
+	}
+	// One more than asked for tells whether there is another page.
+	arg.Limit++
+	events, err := s.store.ListAuditEvents(ctx, arg)
+	if err != nil {
+		return AuditPage{}, fmt.Errorf("listing audit events: %w", err)
+	}
+	page := AuditPage{Events: events}
+	if len(events) == int(arg.Limit) {
+		page.Events = events[:len(events)-1]
+		page.Next = auditCursor(page.Events[len(page.Events)-1])
+	}
+	return page, nil
+}
+
+// ExportAuditEvents calls fn with every page of the events q selects, newest
+// first. q's limit is ignored. An export can take much longer than any one
+// query should, so each page is fetched under a timeout of its own,
+// pageTimeout, if it is positive.
+func (s *Service) ExportAuditEvents(ctx context.Context, q AuditQuery, pageTimeout time.Duration, fn func([]database.AuditEvent) error) error {
+	q.Limit = MaxAuditLimit
+	for {
+		page, err := s.auditPage(ctx, q, pageTimeout)
+		if err != nil {
+			return err
+		}
+		if err := fn(page.Events); err != nil {
+			return err
+		}
+		if page.Next == "" {
+			return nil
+		}
+		q.Cursor = page.Next
+	}
+}
+
+func (s *Service) auditPage(ctx context.Context, q AuditQuery, timeout time.Duration) (AuditPage, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return s.AuditEvents(ctx, q)
+}
+
+// params checks q and turns it into query parameters.
+func (q AuditQuery) params() (database.ListAuditEventsParams, error) {
+	var fields []FieldError
+	if q.Limit < 0 || q.Limit > MaxAuditLimit {
+		fields = append(fields, FieldError{
+			Field:   "limit",
+			Code:    "out_of_range",
+			Message: fmt.Sprintf("limit must be a number from 1 to %d", MaxAuditLimit),
+		})
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Since.Before(q.Until) {
+		fields = append(fields, FieldError{Field: "until", Code: "out_of_range", Message: "until must be after since"})
+	}
+	arg := database.ListAuditEventsParams{
+		Action:     nullString(q.Action),
+		ActorID:    q.ActorID,
+		TargetType: nullString(q.TargetType),
+		TargetID:   nullString(q.TargetID),
+		Since:      nullTime(q.Since),
+		Until:      nullTime(q.Until),
+		Limit:      int32(cmp.Or(q.Limit, DefaultAuditLimit)),
+	}
+	if q.Cursor != "" {
+		at, id, ok := parseAuditCursor(q.Cursor)
+		if !ok {
+			fields = append(fields, FieldError{Field: "cursor", Code: "invalid_cursor", Message: "cursor is not one this API returned"})
+		}
+		arg.BeforeCreatedAt = nullTime(at)
+		arg.BeforeID = uuid.NullUUID{UUID: id, Valid: ok}
+	}
+	if len(fields) > 0 {
+		return database.ListAuditEventsParams{}, invalidFields(fields...)
+	}
+	return arg, nil
+}
+
+// auditCursor encodes where a listing continues after e.
+func auditCursor(e database.AuditEvent) string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d.%s", e.CreatedAt.UnixNano(), e.ID))
+}
+
+func parseAuditCursor(cursor string) (time.Time, uuid.UUID, bool) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, false
+	}
+	nanos, id, ok := strings.Cut(string(b), ".")
+	if !ok {
+		return time.Time{}, uuid.Nil, false
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.Nil, false
+	}
+	u, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.Nil, false
+	}
+	return time.Unix(0, n).UTC(), u, true
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
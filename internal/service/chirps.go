@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/profanity"
+	"github.com/Vikuuu/Chirpy/internal/spam"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// MaxChirpLength is the most characters a chirp may have.
+const MaxChirpLength = 140
+
+// PostChirp publishes body for the user after running it past the profanity
+// filter and the spam check. A chirp that is let through but looks doubtful
+// is queued for review in the same transaction, so it is never published
+// without its report.
+func (s *Service) PostChirp(ctx context.Context, userID uuid.UUID, body string) (database.Chirp, error) {
+	if strings.TrimSpace(body) == "" {
+		return database.Chirp{}, invalidFields(FieldError{Field: "body", Code: "required", Message: "body is required"})
+	}
+	if utf8.RuneCountInString(body) > MaxChirpLength {
+		return database.Chirp{}, invalidFields(FieldError{Field: "body", Code: "too_long", Message: fmt.Sprintf("body must be at most %d characters", MaxChirpLength)})
+	}
+
+	filtered := s.filter.Apply(body)
+	if filtered.Rejected {
+		return database.Chirp{}, fail(Prohibited, "chirp contains prohibited language")
+	}
+
+	var chirp database.Chirp
+	err := s.atomically(ctx, func(tx store.Tx) error {
+		recent, err := tx.GetRecentChirpBodiesForUser(ctx, database.GetRecentChirpBodiesForUserParams{
+			UserID:    userID,
+			CreatedAt: time.Now().Add(-s.spam.Window),
+		})
+		if err != nil {
+			return fmt.Errorf("loading recent chirps: %w", err)
+		}
+		verdict := spam.Check(s.spam, filtered.Text, recent)
+		if verdict.Verdict == spam.Reject {
+			return fail(Spam, "chirp looks like spam")
+		}
+
+		chirp, err = tx.CreateChirpForUser(ctx, database.CreateChirpForUserParams{
+			Body:   filtered.Text,
+			UserID: userID,
+		})
+		if err != nil {
+			return fmt.Errorf("creating chirp: %w", err)
+		}
+
+		if filtered.Flagged {
+			var words []string
+			for _, m := range filtered.Matches {
+				if m.Action == profanity.ActionFlag {
+					words = append(words, m.Word)
+				}
+			}
+			if err := fileSystemReport(ctx, tx, chirp, "profanity", "matched words: "+strings.Join(words, ", ")); err != nil {
+				return err
+			}
+		}
+		if verdict.Verdict == spam.Review {
+			if err := fileSystemReport(ctx, tx, chirp, "spam", strings.Join(verdict.Reasons, "; ")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return chirp, err
+}
+
+// ChirpQuery selects the chirps to list.
+type ChirpQuery struct {
+	// AuthorID limits the list to one author's chirps.
+	AuthorID uuid.NullUUID
+	// ViewerID is who is looking, if they are signed in; shadow-banned
+	// users still see their own chirps.
+	ViewerID uuid.NullUUID
+	// Sort is "asc" or "desc" by creation time, or empty for the default.
+	Sort string
+}
+
+// Chirps lists the chirps q selects.
+func (s *Service) Chirps(ctx context.Context, q ChirpQuery) ([]database.Chirp, error) {
+	var chirps []database.Chirp
+	var err error
+	switch {
+	case !q.AuthorID.Valid && q.Sort == "":
+		chirps, err = s.store.GetChirps(ctx, q.ViewerID)
+	case q.Sort == "":
+		chirps, err = s.store.GetChirpsForAuthor(ctx, database.GetChirpsForAuthorParams{
+			UserID:   q.AuthorID.UUID,
+			ViewerID: q.ViewerID,
+		})
+	case q.AuthorID.Valid:
+		chirps, err = s.store.GetSortedChirpsForAuthor(ctx, database.GetSortedChirpsForAuthorParams{
+			UserID:   q.AuthorID.UUID,
+			ViewerID: q.ViewerID,
+			Sort:     q.Sort,
+		})
+	default:
+		chirps, err = s.store.GetSortedChirps(ctx, database.GetSortedChirpsParams{
+			ViewerID: q.ViewerID,
+			Sort:     q.Sort,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("retrieving chirps: %w", err)
+	}
+	return chirps, nil
+}
+
+// Chirp returns one chirp. Hidden chirps are reported as not found.
+func (s *Service) Chirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	chirp, err := s.store.GetChirp(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return database.Chirp{}, fail(NotFound, "Not Found")
+		}
+		return database.Chirp{}, fmt.Errorf("fetching chirp: %w", err)
+	}
+	if chirp.HiddenAt.Valid {
+		return database.Chirp{}, fail(NotFound, "Not Found")
+	}
+	return chirp, nil
+}
+
+// DeleteChirp deletes a chirp for its author.
+func (s *Service) DeleteChirp(ctx context.Context, userID, chirpID uuid.UUID) error {
+	return s.atomically(ctx, func(tx store.Tx) error {
+		chirp, err := tx.GetChirp(ctx, chirpID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fail(NotFound, "Not Found")
+			}
+			return fmt.Errorf("getting chirp: %w", err)
+		}
+		if chirp.UserID != userID {
+			return fail(Forbidden, "You don't have the permission to delete this chirp")
+		}
+
+		if err := tx.DeleteChirp(ctx, database.DeleteChirpParams{UserID: userID, ID: chirpID}); err != nil {
+			return fmt.Errorf("deleting chirp: %w", err)
+		}
+		return audit(ctx, tx, auditEvent{
+			Action:     AuditChirpDelete,
+			Actor:      userID,
+			TargetType: AuditTargetChirp,
+			TargetID:   chirpID.String(),
+		})
+	})
+}
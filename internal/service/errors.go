@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind is the rule an Error reports as broken.
+type Kind int
+
+const (
+	// Invalid means the input breaks a rule about its contents.
+	Invalid Kind = iota + 1
+	// Unauthenticated means credentials are missing, wrong or expired.
+	Unauthenticated
+	// Forbidden means the caller may not do this.
+	Forbidden
+	// Restricted means the caller's account is suspended or banned.
+	Restricted
+	// NotFound means the thing acted on doesn't exist, or the caller may
+	// not know that it does.
+	NotFound
+	// Conflict means the change clashes with the current state.
+	Conflict
+	// Prohibited means a chirp contains prohibited language.
+	Prohibited
+	// Spam means a chirp looks like spam.
+	Spam
+)
+
+// Error is a request the rules refuse. Message is written for the client.
+type Error struct {
+	Kind    Kind
+	Message string
+	// Fields lists the invalid inputs of an Invalid error, when they are
+	// known.
+	Fields []FieldError
+	// Err is the underlying cause, if any; it is only logged.
+	Err error
+}
+
+// FieldError describes one invalid input.
+type FieldError struct {
+	Field   string
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// KindOf returns the kind of the Error in err's chain, or 0 if there isn't
+// one.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return 0
+}
+
+func fail(kind Kind, msg string) error {
+	return &Error{Kind: kind, Message: msg}
+}
+
+// invalidFields reports one or more invalid inputs.
+func invalidFields(fields ...FieldError) error {
+	msg := "request has invalid fields"
+	if len(fields) == 1 {
+		msg = fields[0].Message
+	}
+	return &Error{Kind: Invalid, Message: msg, Fields: fields}
+}
@@ -0,0 +1,328 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// Statuses of a data export.
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+)
+
+// How long a finished export is kept, and how many pending exports
+// ProcessDataExports takes on at a time.
+const (
+	dataExportTTL   = 7 * 24 * time.Hour
+	dataExportBatch = 10
+)
+
+// RequestDataExport queues an export of everything kept about a user, or
+// returns the one already queued.
+func (s *Service) RequestDataExport(ctx context.Context, userID uuid.UUID) (database.DataExport, error) {
+	var export database.DataExport
+	err := s.atomically(ctx, func(tx store.Tx) error {
+		var err error
+		export, err = tx.GetPendingDataExportForUser(ctx, userID)
+		if err == nil {
+			return nil
+		}
+		if err != sql.ErrNoRows {
+			return fmt.Errorf("getting pending export: %w", err)
+		}
+		if export, err = tx.CreateDataExport(ctx, userID); err != nil {
+			return fmt.Errorf("creating export: %w", err)
+		}
+		return audit(ctx, tx, auditEvent{
+			Action:     AuditUserExport,
+			Actor:      userID,
+			TargetType: AuditTargetDataExport,
+			TargetID:   export.ID.String(),
+		})
+	})
+	if err != nil {
+		return database.DataExport{}, err
+	}
+	select {
+	case s.exportsQueued <- struct{}{}:
+	default:
+	}
+	return export, nil
+}
+
+// ExportsQueued receives when a data export has been queued since
+// ProcessDataExports last could have seen it, so that a worker needn't wait
+// for its next tick.
+func (s *Service) ExportsQueued() <-chan struct{} {
+	return s.exportsQueued
+}
+
+// DataExport returns one of a user's exports. Other users' exports, and
+// expired ones, are not found.
+func (s *Service) DataExport(ctx context.Context, userID, id uuid.UUID) (database.DataExport, error) {
+	export, err := s.store.GetDataExport(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return database.DataExport{}, fail(NotFound, "export not found")
+		}
+		return database.DataExport{}, fmt.Errorf("getting export: %w", err)
+	}
+	if export.UserID != userID || (export.ExpiresAt.Valid && !export.ExpiresAt.Time.After(time.Now())) {
+		return database.DataExport{}, fail(NotFound, "export not found")
+	}
+	return export, nil
+}
+
+// ProcessDataExports builds the pending exports and counts them.
+func (s *Service) ProcessDataExports(ctx context.Context) (int, error) {
+	var built int
+	for {
+		pending, err := s.store.ListPendingDataExports(ctx, dataExportBatch)
+		if err != nil {
+			return built, fmt.Errorf("listing pending exports: %w", err)
+		}
+		for _, e := range pending {
+			if err := s.buildDataExport(ctx, e); err != nil {
+				return built, err
+			}
+			built++
+		}
+		if len(pending) < dataExportBatch {
+			return built, nil
+		}
+	}
+}
+
+// buildDataExport builds an export from one snapshot of the user's data,
+// storing the archive in the same transaction so that an export interrupted
+// halfway is still pending. One that can't be built is marked failed, for
+// the user to ask again.
+func (s *Service) buildDataExport(ctx context.Context, e database.DataExport) error {
+	now := time.Now().UTC()
+	err := s.atomically(ctx, func(tx store.Tx) error {
+		archive, err := writeDataExport(ctx, tx, e.UserID, now)
+		if err != nil {
+			return err
+		}
+		_, err = tx.FinishDataExport(ctx, database.FinishDataExportParams{
+			ID:        e.ID,
+			Status:    ExportReady,
+			ExpiresAt: nullTime(now.Add(dataExportTTL)),
+			Archive:   archive,
+		})
+		if err != nil {
+			return fmt.Errorf("storing export: %w", err)
+		}
+		return nil
+	})
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	_, finishErr := s.store.FinishDataExport(ctx, database.FinishDataExportParams{
+		ID:        e.ID,
+		Status:    ExportFailed,
+		ExpiresAt: nullTime(now.Add(dataExportTTL)),
+		Error:     err.Error(),
+	})
+	if finishErr != nil {
+		return fmt.Errorf("marking export failed: %w (building it: %w)", finishErr, err)
+	}
+	return nil
+}
+
+// PurgeExpiredDataExports deletes the exports that expired by now and counts
+// them.
+func (s *Service) PurgeExpiredDataExports(ctx context.Context, now time.Time) (int64, error) {
+	n, err := s.store.DeleteExpiredDataExports(ctx, nullTime(now))
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired exports: %w", err)
+	}
+	return n, nil
+}
+
+// The files of an export archive. The profile leaves out the password hash,
+// and anything moderators know that the user isn't told, such as a shadow
+// ban. Sessions are identified by the fingerprint the audit log uses, never
+// by the token.
+type (
+	exportedProfile struct {
+		ID          uuid.UUID `json:"id"`
+		CreatedAt   time.Time `json:"created_at"`
+		UpdatedAt   time.Time `json:"updated_at"`
+		Email       string    `json:"email"`
+		IsChirpyRed bool      `json:"is_chirpy_red"`
+	}
+	exportedChirp struct {
+		ID        uuid.UUID  `json:"id"`
+		CreatedAt time.Time  `json:"created_at"`
+		UpdatedAt time.Time  `json:"updated_at"`
+		Body      string     `json:"body"`
+		HiddenAt  *time.Time `json:"hidden_at,omitempty"`
+	}
+	exportedSession struct {
+		ID        string     `json:"id"`
+		CreatedAt time.Time  `json:"created_at"`
+		ExpiresAt time.Time  `json:"expires_at"`
+		RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	}
+	exportedAuditEvent struct {
+		ID         uuid.UUID       `json:"id"`
+		CreatedAt  time.Time       `json:"created_at"`
+		Action     string          `json:"action"`
+		TargetType string          `json:"target_type"`
+		TargetID   string          `json:"target_id"`
+		IP         string          `json:"ip"`
+		UserAgent  string          `json:"user_agent"`
+		Details    json.RawMessage `json:"details"`
+	}
+)
+
+// writeDataExport zips up what is kept about a user: profile.json,
+// chirps.json, sessions.json and audit_events.json.
+func writeDataExport(ctx context.Context, tx store.Tx, userID uuid.UUID, now time.Time) ([]byte, error) {
+	user, err := tx.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("getting user: %w", err)
+	}
+	chirps, err := tx.ListChirpsForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing chirps: %w", err)
+	}
+	tokens, err := tx.ListRefreshTokensForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing refresh tokens: %w", err)
+	}
+	events, err := userAuditEvents(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", exportedProfile{
+			ID:          user.ID,
+			CreatedAt:   user.CreatedAt,
+			UpdatedAt:   user.UpdatedAt,
+			Email:       user.Email,
+			IsChirpyRed: user.IsChirpyRed,
+		}},
+		{"chirps.json", mapSlice(chirps, func(c database.Chirp) exportedChirp {
+			return exportedChirp{ID: c.ID, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt, Body: c.Body, HiddenAt: timePtr(c.HiddenAt)}
+		})},
+		{"sessions.json", mapSlice(tokens, func(t database.RefreshToken) exportedSession {
+			return exportedSession{ID: tokenFingerprint(t.Token), CreatedAt: t.CreatedAt, ExpiresAt: t.ExpiresAt, RevokedAt: timePtr(t.RevokedAt)}
+		})},
+		{"audit_events.json", mapSlice(events, func(e database.AuditEvent) exportedAuditEvent {
+			return exportedAuditEvent{
+				ID:         e.ID,
+				CreatedAt:  e.CreatedAt,
+				Action:     e.Action,
+				TargetType: e.TargetType,
+				TargetID:   e.TargetID,
+				IP:         e.Ip,
+				UserAgent:  e.UserAgent,
+				Details:    e.Details,
+			}
+		})},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return nil, fmt.Errorf("adding %s: %w", f.name, err)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return nil, fmt.Errorf("writing %s: %w", f.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("closing archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// userAuditEvents returns the audit events a user caused, and those about
+// them that no one caused, such as failed logins and Chirpy Red upgrades,
+// newest first. What moderators did to them is left out.
+func userAuditEvents(ctx context.Context, tx store.Tx, userID uuid.UUID) ([]database.AuditEvent, error) {
+	byUser, err := allAuditEvents(ctx, tx, database.ListAuditEventsParams{
+		ActorID: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	aboutUser, err := allAuditEvents(ctx, tx, database.ListAuditEventsParams{
+		TargetType: nullString(AuditTargetUser),
+		TargetID:   nullString(userID.String()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range aboutUser {
+		if !e.ActorID.Valid {
+			byUser = append(byUser, e)
+		}
+	}
+	slices.SortFunc(byUser, func(a, b database.AuditEvent) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(b.ID[:], a.ID[:])
+	})
+	return byUser, nil
+}
+
+// allAuditEvents lists every event arg selects, a page at a time.
+func allAuditEvents(ctx context.Context, tx store.Tx, arg database.ListAuditEventsParams) ([]database.AuditEvent, error) {
+	arg.Limit = MaxAuditLimit
+	var out []database.AuditEvent
+	for {
+		events, err := tx.ListAuditEvents(ctx, arg)
+		if err != nil {
+			return nil, fmt.Errorf("listing audit events: %w", err)
+		}
+		out = append(out, events...)
+		if len(events) < int(arg.Limit) {
+			return out, nil
+		}
+		last := events[len(events)-1]
+		arg.BeforeCreatedAt = nullTime(last.CreatedAt)
+		arg.BeforeID = uuid.NullUUID{UUID: last.ID, Valid: true}
+	}
+}
+
+// mapSlice maps every element of s, returning an empty slice rather than nil
+// so that it is encoded as [].
+func mapSlice[T, U any](s []T, f func(T) U) []U {
+	out := make([]U, 0, len(s))
+	for _, v := range s {
+		out = append(out, f(v))
+	}
+	return out
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
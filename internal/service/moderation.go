@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+const (
+	ActionHideChirp   = "hide_chirp"
+	ActionDeleteChirp = "delete_chirp"
+	ActionWarn        = "warn"
+	ActionSuspend     = "suspend"
+	ActionBan         = "ban"
+	ActionShadowBan   = "shadow_ban"
+	ActionReinstate   = "reinstate"
+)
+
+var moderationActions = []string{
+	ActionHideChirp, ActionDeleteChirp, ActionWarn, ActionSuspend, ActionBan, ActionShadowBan, ActionReinstate,
+}
+
+// Moderation is an action a moderator takes.
+type Moderation struct {
+	Action string
+	Reason string
+	// Duration is how long a suspension lasts, such as "72h".
+	Duration string
+}
+
+// ReportsByStatus lists the reports in a status, open if it is empty.
+func (s *Service) ReportsByStatus(ctx context.Context, status string) ([]database.Report, error) {
+	if status == "" {
+		status = ReportStatusOpen
+	}
+	if status != ReportStatusOpen && status != ReportStatusActioned && status != ReportStatusDismissed {
+		return nil, invalidFields(FieldError{Field: "status", Code: "invalid_choice", Message: "status must be open, actioned or dismissed"})
+	}
+
+	reports, err := s.store.ListReportsByStatus(ctx, status)
+	if err != nil {
+		return nil, fmt.Errorf("listing reports: %w", err)
+	}
+	return reports, nil
+}
+
+// ModerateReport takes m against whoever a report is about and closes the
+// report, both or neither.
+func (s *Service) ModerateReport(ctx context.Context, moderatorID, reportID uuid.UUID, m Moderation) (database.Report, database.ModerationAction, error) {
+	var report database.Report
+	var action database.ModerationAction
+	err := s.atomically(ctx, func(tx store.Tx) error {
+		var err error
+		report, err = openReport(ctx, tx, reportID)
+		if err != nil {
+			return err
+		}
+
+		expiresAt, err := checkModeration(m, report.ChirpID)
+		if err != nil {
+			return err
+		}
+
+		action, err = applyModeration(ctx, tx, moderatorID, uuid.NullUUID{UUID: report.ID, Valid: true}, report.UserID, report.ChirpID, m, expiresAt)
+		if err != nil {
+			return fmt.Errorf("applying %s: %w", m.Action, err)
+		}
+
+		report, err = resolveReport(ctx, tx, report.ID, ReportStatusActioned, moderatorID, m.Reason)
+		return err
+	})
+	return report, action, err
+}
+
+// ModerateUser restricts or reinstates an account directly, without a
+// report. Moderators can't act on their own account.
+func (s *Service) ModerateUser(ctx context.Context, moderatorID, userID uuid.UUID, m Moderation) (database.ModerationAction, error) {
+	if userID == moderatorID {
+		return database.ModerationAction{}, fail(Invalid, "you cannot moderate your own account")
+	}
+	expiresAt, err := checkModeration(m, uuid.NullUUID{})
+	if err != nil {
+		return database.ModerationAction{}, err
+	}
+
+	var action database.ModerationAction
+	err = s.atomically(ctx, func(tx store.Tx) error {
+		var err error
+		action, err = applyModeration(ctx, tx, moderatorID, uuid.NullUUID{}, userID, uuid.NullUUID{}, m, expiresAt)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fail(NotFound, "user not found")
+			}
+			return fmt.Errorf("applying %s: %w", m.Action, err)
+		}
+		return nil
+	})
+	return action, err
+}
+
+// DismissReport closes a report without acting on it.
+func (s *Service) DismissReport(ctx context.Context, moderatorID, reportID uuid.UUID, reason string) (database.Report, error) {
+	var report database.Report
+	err := s.atomically(ctx, func(tx store.Tx) error {
+		if _, err := openReport(ctx, tx, reportID); err != nil {
+			return err
+		}
+		var err error
+		report, err = resolveReport(ctx, tx, reportID, ReportStatusDismissed, moderatorID, reason)
+		if err != nil {
+			return err
+		}
+		return audit(ctx, tx, auditEvent{
+			Action:     AuditDismissReport,
+			Actor:      moderatorID,
+			TargetType: AuditTargetReport,
+			TargetID:   reportID.String(),
+			Details:    map[string]string{"reason": reason},
+		})
+	})
+	return report, err
+}
+
+// ModerationActionsAgainst lists the actions moderators took against a user
+// and their chirps, as the user may see them: a shadow ban only works if its
+// target doesn't know about it.
+func (s *Service) ModerationActionsAgainst(ctx context.Context, userID uuid.UUID) ([]database.ModerationAction, error) {
+	actions, err := s.store.ListModerationActionsForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing moderation actions: %w", err)
+	}
+	return slices.DeleteFunc(actions, func(a database.ModerationAction) bool {
+		return a.Action == ActionShadowBan
+	}), nil
+}
+
+// openReport loads a report and checks that it is still open.
+func openReport(ctx context.Context, tx store.Tx, id uuid.UUID) (database.Report, error) {
+	report, err := tx.GetReport(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return database.Report{}, fail(NotFound, "report not found")
+		}
+		return database.Report{}, fmt.Errorf("getting report: %w", err)
+	}
+	if report.Status != ReportStatusOpen {
+		return database.Report{}, fail(Conflict, "report is already resolved")
+	}
+	return report, nil
+}
+
+func resolveReport(ctx context.Context, tx store.Tx, id uuid.UUID, status string, moderatorID uuid.UUID, resolution string) (database.Report, error) {
+	report, err := tx.ResolveReport(ctx, database.ResolveReportParams{
+		Status:     status,
+		ResolvedBy: uuid.NullUUID{UUID: moderatorID, Valid: true},
+		Resolution: resolution,
+		ID:         id,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return database.Report{}, fail(Conflict, "report is already resolved")
+		}
+		return database.Report{}, fmt.Errorf("resolving report: %w", err)
+	}
+	return report, nil
+}
+
+// checkModeration validates m for a target that may or may not include a
+// chirp and returns the expiry of a suspension.
+func checkModeration(m Moderation, chirpID uuid.NullUUID) (sql.NullTime, error) {
+	var fields []FieldError
+	if !slices.Contains(moderationActions, m.Action) {
+		fields = append(fields, FieldError{Field: "action", Code: "invalid_choice", Message: "unknown moderation action"})
+	}
+
+	var expiresAt sql.NullTime
+	switch m.Action {
+	case ActionHideChirp, ActionDeleteChirp:
+		if !chirpID.Valid {
+			return sql.NullTime{}, fail(Invalid, "there is no chirp to act on")
+		}
+	case ActionSuspend:
+		d, err := time.ParseDuration(m.Duration)
+		if err != nil || d <= 0 {
+			fields = append(fields, FieldError{Field: "duration", Code: "invalid_duration", Message: "suspend needs a positive duration such as \"72h\""})
+			break
+		}
+		expiresAt = sql.NullTime{Time: time.Now().UTC().Add(d), Valid: true}
+	}
+	if len(fields) > 0 {
+		return sql.NullTime{}, invalidFields(fields...)
+	}
+	return expiresAt, nil
+}
+
+// applyModeration carries out a checked action against userID (and chirpID
+// for chirp actions) and records it, in the moderation log and the audit log.
+// moderatorID is uuid.Nil for an operator's action.
+func applyModeration(ctx context.Context, tx store.Tx, moderatorID uuid.UUID, reportID uuid.NullUUID, userID uuid.UUID, chirpID uuid.NullUUID, m Moderation, expiresAt sql.NullTime) (database.ModerationAction, error) {
+	var err error
+	switch m.Action {
+	case ActionHideChirp:
+		err = tx.HideChirp(ctx, chirpID.UUID)
+	case ActionDeleteChirp:
+		err = tx.DeleteChirp(ctx, database.DeleteChirpParams{
+			UserID: userID,
+			ID:     chirpID.UUID,
+		})
+	case ActionSuspend:
+		err = setUserStatus(ctx, tx, userID, StatusSuspended, expiresAt)
+	case ActionBan:
+		err = setUserStatus(ctx, tx, userID, StatusBanned, sql.NullTime{})
+	case ActionShadowBan:
+		err = setUserStatus(ctx, tx, userID, StatusShadowBanned, sql.NullTime{})
+	case ActionReinstate:
+		err = setUserStatus(ctx, tx, userID, StatusActive, sql.NullTime{})
+	}
+	if err != nil {
+		return database.ModerationAction{}, err
+	}
+
+	action, err := tx.CreateModerationAction(ctx, database.CreateModerationActionParams{
+		ReportID:      reportID,
+		ModeratorID:   uuid.NullUUID{UUID: moderatorID, Valid: moderatorID != uuid.Nil},
+		Action:        m.Action,
+		TargetUserID:  userID,
+		TargetChirpID: chirpID,
+		Reason:        m.Reason,
+		ExpiresAt:     expiresAt,
+	})
+	if err != nil {
+		return database.ModerationAction{}, err
+	}
+
+	details := map[string]any{"action": m.Action, "reason": m.Reason, "moderation_action_id": action.ID}
+	if reportID.Valid {
+		details["report_id"] = reportID.UUID
+	}
+	if chirpID.Valid {
+		details["chirp_id"] = chirpID.UUID
+	}
+	if expiresAt.Valid {
+		details["expires_at"] = expiresAt.Time
+	}
+	err = audit(ctx, tx, auditEvent{
+		Action:     AuditModerate,
+		Actor:      moderatorID,
+		TargetType: AuditTargetUser,
+		TargetID:   userID.String(),
+		Details:    details,
+	})
+	return action, err
+}
+
+func setUserStatus(ctx context.Context, tx store.Tx, userID uuid.UUID, status string, until sql.NullTime) error {
+	n, err := tx.SetUserStatus(ctx, database.SetUserStatusParams{
+		Status:         status,
+		SuspendedUntil: until,
+		ID:             userID,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
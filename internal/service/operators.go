@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/auth"
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// The operator methods carry out the chirpy CLI's account commands. No
+// account acts in them, so they are audited without an actor; the CLI
+// records which command it was as the origin's user agent.
+
+// CreateAccount creates an account with a role, as Register does for the
+// default one.
+func (s *Service) CreateAccount(ctx context.Context, email, password, role string) (database.CreateUserRow, error) {
+	if err := checkPassword(password); err != nil {
+		return database.CreateUserRow{}, err
+	}
+	hash, err := auth.HashPassword(ctx, password)
+	if err != nil {
+		return database.CreateUserRow{}, fmt.Errorf("hashing password: %w", err)
+	}
+
+	var user database.CreateUserRow
+	err = s.atomically(ctx, func(tx store.Tx) error {
+		var err error
+		user, err = tx.CreateUser(ctx, database.CreateUserParams{Email: email, HashedPassword: hash})
+		if err != nil {
+			if errors.Is(err, store.ErrConflict) {
+				return fail(Conflict, "email is already registered")
+			}
+			return fmt.Errorf("creating user: %w", err)
+		}
+		if role != RoleUser {
+			if _, err := tx.SetUserRole(ctx, database.SetUserRoleParams{Role: role, ID: user.ID}); err != nil {
+				return fmt.Errorf("setting role: %w", err)
+			}
+		}
+		return audit(ctx, tx, auditEvent{
+			Action:     AuditCreateUser,
+			TargetType: AuditTargetUser,
+			TargetID:   user.ID.String(),
+			Details:    map[string]string{"role": role},
+		})
+	})
+	return user, err
+}
+
+// SetRole gives a user a role and returns the one they had.
+func (s *Service) SetRole(ctx context.Context, userID uuid.UUID, role string) (string, error) {
+	var old string
+	err := s.atomically(ctx, func(tx store.Tx) error {
+		user, err := getUser(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		old = user.Role
+		if _, err := tx.SetUserRole(ctx, database.SetUserRoleParams{Role: role, ID: userID}); err != nil {
+			return fmt.Errorf("setting role: %w", err)
+		}
+		return audit(ctx, tx, auditEvent{
+			Action:     AuditSetRole,
+			TargetType: AuditTargetUser,
+			TargetID:   userID.String(),
+			Details:    map[string]change{"role": {Old: old, New: role}},
+		})
+	})
+	return old, err
+}
+
+// Suspend suspends a user for a while, recorded in the moderation log as a
+// suspension with no moderator.
+func (s *Service) Suspend(ctx context.Context, userID uuid.UUID, period time.Duration, reason string) (database.ModerationAction, error) {
+	m := Moderation{Action: ActionSuspend, Reason: reason, Duration: period.String()}
+	expiresAt, err := checkModeration(m, uuid.NullUUID{})
+	if err != nil {
+		return database.ModerationAction{}, err
+	}
+
+	var action database.ModerationAction
+	err = s.atomically(ctx, func(tx store.Tx) error {
+		var err error
+		action, err = applyModeration(ctx, tx, uuid.Nil, uuid.NullUUID{}, userID, uuid.NullUUID{}, m, expiresAt)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fail(NotFound, "user not found")
+			}
+			return fmt.Errorf("applying %s: %w", m.Action, err)
+		}
+		return nil
+	})
+	return action, err
+}
+
+// ResetPassword gives a user a new password and revokes their refresh
+// tokens, since whoever knew the old password may still hold a session. It
+// counts the tokens revoked.
+func (s *Service) ResetPassword(ctx context.Context, userID uuid.UUID, password string) (int64, error) {
+	if err := checkPassword(password); err != nil {
+		return 0, err
+	}
+	hash, err := auth.HashPassword(ctx, password)
+	if err != nil {
+		return 0, fmt.Errorf("hashing password: %w", err)
+	}
+
+	var revoked int64
+	err = s.atomically(ctx, func(tx store.Tx) error {
+		n, err := tx.SetUserPassword(ctx, database.SetUserPasswordParams{HashedPassword: hash, ID: userID})
+		if err != nil {
+			return fmt.Errorf("setting password: %w", err)
+		}
+		if n == 0 {
+			return fail(NotFound, "user not found")
+		}
+		if revoked, err = tx.RevokeAllRefreshTokensForUser(ctx, userID); err != nil {
+			return fmt.Errorf("revoking refresh tokens: %w", err)
+		}
+		return audit(ctx, tx, auditEvent{
+			Action:     AuditResetPassword,
+			TargetType: AuditTargetUser,
+			TargetID:   userID.String(),
+			Details: map[string]any{
+				"password":       change{Redacted: true},
+				"refresh_tokens": revoked,
+			},
+		})
+	})
+	return revoked, err
+}
+
+// RevokeSessions revokes every refresh token of a user and counts them.
+func (s *Service) RevokeSessions(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var revoked int64
+	err := s.atomically(ctx, func(tx store.Tx) error {
+		if _, err := getUser(ctx, tx, userID); err != nil {
+			return err
+		}
+		var err error
+		if revoked, err = tx.RevokeAllRefreshTokensForUser(ctx, userID); err != nil {
+			return fmt.Errorf("revoking refresh tokens: %w", err)
+		}
+		return audit(ctx, tx, auditEvent{
+			Action:     AuditRevokeSessions,
+			TargetType: AuditTargetUser,
+			TargetID:   userID.String(),
+			Details:    map[string]int64{"refresh_tokens": revoked},
+		})
+	})
+	return revoked, err
+}
+
+// PurgeChirps deletes every chirp of a user and counts them.
+func (s *Service) PurgeChirps(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var deleted int64
+	err := s.atomically(ctx, func(tx store.Tx) error {
+		if _, err := getUser(ctx, tx, userID); err != nil {
+			return err
+		}
+		var err error
+		if deleted, err = tx.DeleteChirpsForUser(ctx, userID); err != nil {
+			return fmt.Errorf("deleting chirps: %w", err)
+		}
+		return audit(ctx, tx, auditEvent{
+			Action:     AuditPurgeChirps,
+			TargetType: AuditTargetUser,
+			TargetID:   userID.String(),
+			Details:    map[string]int64{"chirps": deleted},
+		})
+	})
+	return deleted, err
+}
+
+func getUser(ctx context.Context, tx store.Tx, id uuid.UUID) (database.User, error) {
+	user, err := tx.GetUserByID(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return database.User{}, fail(NotFound, "user not found")
+		}
+		return database.User{}, fmt.Errorf("getting user: %w", err)
+	}
+	return user, nil
+}
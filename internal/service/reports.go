@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+const (
+	ReportTargetChirp = "chirp"
+	ReportTargetUser  = "user"
+
+	ReportStatusOpen      = "open"
+	ReportStatusActioned  = "actioned"
+	ReportStatusDismissed = "dismissed"
+)
+
+// NewReport is a report a user files.
+type NewReport struct {
+	// TargetType is ReportTargetChirp, with ChirpID set, or
+	// ReportTargetUser, with UserID set.
+	TargetType string
+	ChirpID    uuid.UUID
+	UserID     uuid.UUID
+	Reason     string
+	Details    string
+}
+
+// Report files a report by reporterID against a chirp or a user. Nobody may
+// report themselves.
+func (s *Service) Report(ctx context.Context, reporterID uuid.UUID, r NewReport) (database.Report, error) {
+	params := database.CreateReportParams{
+		ReporterID: uuid.NullUUID{UUID: reporterID, Valid: true},
+		TargetType: r.TargetType,
+		Reason:     r.Reason,
+		Details:    r.Details,
+	}
+
+	var report database.Report
+	err := s.atomically(ctx, func(tx store.Tx) error {
+		switch r.TargetType {
+		case ReportTargetChirp:
+			chirp, err := tx.GetChirp(ctx, r.ChirpID)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					return fail(NotFound, "chirp not found")
+				}
+				return fmt.Errorf("getting chirp: %w", err)
+			}
+			params.ChirpID = uuid.NullUUID{UUID: chirp.ID, Valid: true}
+			params.UserID = chirp.UserID
+		case ReportTargetUser:
+			user, err := tx.GetUserByID(ctx, r.UserID)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					return fail(NotFound, "user not found")
+				}
+				return fmt.Errorf("getting user: %w", err)
+			}
+			params.UserID = user.ID
+		default:
+			return invalidFields(FieldError{Field: "target_type", Code: "invalid_choice", Message: "target_type must be chirp or user"})
+		}
+
+		if params.UserID == reporterID {
+			return fail(Invalid, "you cannot report yourself")
+		}
+
+		var err error
+		report, err = tx.CreateReport(ctx, params)
+		if err != nil {
+			return fmt.Errorf("creating report: %w", err)
+		}
+		return nil
+	})
+	return report, err
+}
+
+// ReportsBy lists the reports a user filed, with their outcome.
+func (s *Service) ReportsBy(ctx context.Context, reporterID uuid.UUID) ([]database.Report, error) {
+	reports, err := s.store.ListReportsForReporter(ctx, uuid.NullUUID{UUID: reporterID, Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("listing reports: %w", err)
+	}
+	return reports, nil
+}
+
+// fileSystemReport queues a chirp for review without a human reporter, as
+// done for chirps caught by the profanity filter.
+func fileSystemReport(ctx context.Context, tx store.Tx, chirp database.Chirp, reason, details string) error {
+	_, err := tx.CreateReport(ctx, database.CreateReportParams{
+		TargetType: ReportTargetChirp,
+		ChirpID:    uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		UserID:     chirp.UserID,
+		Reason:     reason,
+		Details:    details,
+	})
+	if err != nil {
+		return fmt.Errorf("flagging chirp: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// Reset scopes.
+const (
+	// ResetAll deletes every chirp and refresh token, and every user but
+	// the admin resetting, with everything they own.
+	ResetAll = "all"
+	// ResetChirps deletes every chirp.
+	ResetChirps = "chirps"
+	// ResetTokens deletes every refresh token, signing everyone out.
+	ResetTokens = "tokens"
+	// ResetUsers deletes the users whose email matches a pattern.
+	ResetUsers = "users"
+	// ResetMetrics deletes nothing; the server resets its counters.
+	ResetMetrics = "metrics"
+)
+
+// resetConfirmationTTL is how long an admin has to confirm a reset.
+const resetConfirmationTTL = 5 * time.Minute
+
+// ResetScope is what a reset deletes. Pattern is only given with ResetUsers,
+// as a path.Match pattern for the emails of the users to delete, such as
+// "*@example.com". The admin resetting is never deleted, so that they can
+// reset again.
+type ResetScope struct {
+	Scope   string
+	Pattern string
+}
+
+// ResetCounts are the rows a reset deleted. Those deleted by cascade, such
+// as the reports against a deleted user, aren't counted.
+type ResetCounts struct {
+	Users         int64
+	Chirps        int64
+	RefreshTokens int64
+}
+
+func (r ResetScope) validate() error {
+	switch r.Scope {
+	case ResetUsers:
+		if r.Pattern == "" {
+			return invalidFields(FieldError{Field: "pattern", Code: "required", Message: "pattern is required to reset users"})
+		}
+		if _, err := path.Match(r.Pattern, ""); err != nil {
+			return invalidFields(FieldError{Field: "pattern", Code: "invalid_pattern", Message: "pattern is not a valid pattern"})
+		}
+	case ResetAll, ResetChirps, ResetTokens, ResetMetrics:
+		if r.Pattern != "" {
+			return invalidFields(FieldError{Field: "pattern", Code: "unexpected", Message: "pattern is only used to reset users"})
+		}
+	default:
+		return invalidFields(FieldError{Field: "scope", Code: "oneof", Message: "scope must be one of all, chirps, tokens, users or metrics"})
+	}
+	return nil
+}
+
+// ResetConfirmation checks scope and returns a token with which adminID can
+// confirm resetting it, and when the token expires. The token is signed
+// rather than stored, so it can be used more than once until then.
+func (s *Service) ResetConfirmation(adminID uuid.UUID, scope ResetScope) (string, time.Time, error) {
+	if err := scope.validate(); err != nil {
+		return "", time.Time{}, err
+	}
+	expires := time.Now().Add(resetConfirmationTTL).Truncate(time.Second)
+	return s.resetToken(adminID, scope, expires), expires, nil
+}
+
+// resetToken signs adminID's reset of scope until expires.
+func (s *Service) resetToken(adminID uuid.UUID, scope ResetScope, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(s.secret))
+	// Scope and pattern are NUL-separated so that no pair runs into another.
+	fmt.Fprintf(mac, "reset\x00%s\x00%s\x00%s\x00%s", adminID, scope.Scope, scope.Pattern, exp)
+	return exp + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// checkResetToken reports whether token confirms adminID's reset of scope.
+func (s *Service) checkResetToken(adminID uuid.UUID, scope ResetScope, token string) bool {
+	exp, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().After(time.Unix(unix, 0)) {
+		return false
+	}
+	return hmac.Equal([]byte(token), []byte(s.resetToken(adminID, scope, time.Unix(unix, 0))))
+}
+
+// Reset deletes what scope covers and records who did it in the audit log,
+// given a token from ResetConfirmation for the same admin and scope. If
+// refill isn't nil it is run in the same transaction once the data is gone,
+// so that if it fails nothing is deleted. Like any transaction it may be run
+// more than once.
+func (s *Service) Reset(ctx context.Context, adminID uuid.UUID, scope ResetScope, confirmation string, refill func(tx store.Tx) error) (ResetCounts, error) {
+	if err := scope.validate(); err != nil {
+		return ResetCounts{}, err
+	}
+	if !s.checkResetToken(adminID, scope, confirmation) {
+		return ResetCounts{}, invalidFields(FieldError{
+			Field:   "confirm",
+			Code:    "invalid_confirmation",
+			Message: "confirmation token is invalid or has expired",
+		})
+	}
+
+	var counts ResetCounts
+	err := s.atomically(ctx, func(tx store.Tx) error {
+		var err error
+		if counts, err = reset(ctx, tx, adminID, scope); err != nil {
+			return err
+		}
+		if refill != nil {
+			if err := refill(tx); err != nil {
+				return err
+			}
+		}
+		return audit(ctx, tx, auditEvent{
+			Action:     AuditAdminReset,
+			Actor:      adminID,
+			TargetType: scope.Scope,
+			TargetID:   scope.Pattern,
+			Details: map[string]int64{
+				"users":          counts.Users,
+				"chirps":         counts.Chirps,
+				"refresh_tokens": counts.RefreshTokens,
+			},
+		})
+	})
+	return counts, err
+}
+
+func reset(ctx context.Context, tx store.Tx, adminID uuid.UUID, scope ResetScope) (ResetCounts, error) {
+	var counts ResetCounts
+	var err error
+	switch scope.Scope {
+	case ResetAll:
+		// Chirps and tokens are deleted first only to count them.
+		if counts.Chirps, err = tx.DeleteAllChirps(ctx); err != nil {
+			return counts, fmt.Errorf("deleting chirps: %w", err)
+		}
+		if counts.RefreshTokens, err = tx.DeleteAllRefreshTokens(ctx); err != nil {
+			return counts, fmt.Errorf("deleting refresh tokens: %w", err)
+		}
+		if err := deleteUsers(ctx, tx, adminID, func(string) bool { return true }, &counts); err != nil {
+			return counts, err
+		}
+	case ResetChirps:
+		if counts.Chirps, err = tx.DeleteAllChirps(ctx); err != nil {
+			return counts, fmt.Errorf("deleting chirps: %w", err)
+		}
+	case ResetTokens:
+		if counts.RefreshTokens, err = tx.DeleteAllRefreshTokens(ctx); err != nil {
+			return counts, fmt.Errorf("deleting refresh tokens: %w", err)
+		}
+	case ResetUsers:
+		match := func(email string) bool {
+			ok, _ := path.Match(scope.Pattern, email)
+			return ok
+		}
+		if err := deleteUsers(ctx, tx, adminID, match, &counts); err != nil {
+			return counts, err
+		}
+	}
+	return counts, nil
+}
+
+// deleteUsers deletes the users whose email matches, but for adminID, adding
+// them and the chirps and refresh tokens they had to counts.
+func deleteUsers(ctx context.Context, tx store.Tx, adminID uuid.UUID, match func(email string) bool, counts *ResetCounts) error {
+	users, err := tx.ListUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("listing users: %w", err)
+	}
+	for _, u := range users {
+		if u.ID == adminID || !match(u.Email) {
+			continue
+		}
+		chirps, err := tx.DeleteChirpsForUser(ctx, u.ID)
+		if err != nil {
+			return fmt.Errorf("deleting chirps of %s: %w", u.Email, err)
+		}
+		tokens, err := tx.DeleteRefreshTokensForUser(ctx, u.ID)
+		if err != nil {
+			return fmt.Errorf("deleting refresh tokens of %s: %w", u.Email, err)
+		}
+		n, err := tx.DeleteUser(ctx, u.ID)
+		if err != nil {
+			return fmt.Errorf("deleting %s: %w", u.Email, err)
+		}
+		counts.Chirps += chirps
+		counts.RefreshTokens += tokens
+		counts.Users += n
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// Package service holds Chirpy's business rules: who may do what to which
+// account, chirp or report, and which changes have to happen together. Each
+// operation that reads before it writes runs as one unit of work, so the HTTP
+// handlers are left decoding requests and encoding responses.
+package service
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Vikuuu/Chirpy/internal/profanity"
+	"github.com/Vikuuu/Chirpy/internal/spam"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// Service carries out requests against a store.
+type Service struct {
+	store         store.Store
+	filter        *profanity.Filter
+	spam          spam.Config
+	secret        string
+	deletionGrace time.Duration
+	// exportsQueued holds a value while a queued data export waits to be
+	// picked up.
+	exportsQueued chan struct{}
+}
+
+// Config is what a Service needs besides its store.
+type Config struct {
+	// Filter checks chirps. It is shared, so that reloading the banned
+	// words takes effect at once.
+	Filter *profanity.Filter
+	Spam   spam.Config
+	// Secret signs access tokens.
+	Secret string
+	// DeletionGrace is how long a deleted account waits before it is gone
+	// for good. Zero deletes accounts at once.
+	DeletionGrace time.Duration
+}
+
+// New returns a Service over st.
+func New(st store.Store, c Config) *Service {
+	return &Service{
+		store:         st,
+		filter:        c.Filter,
+		spam:          c.Spam,
+		secret:        c.Secret,
+		deletionGrace: c.DeletionGrace,
+		exportsQueued: make(chan struct{}, 1),
+	}
+}
+
+// maxAttempts is how many times a unit of work is tried before a
+// serialization failure is given up on.
+const maxAttempts = 3
+
+// atomically runs fn as one unit of work. If it loses a race with a
+// concurrent transaction it is run again from the start, so fn must not have
+// effects outside tx.
+func (s *Service) atomically(ctx context.Context, fn func(tx store.Tx) error) error {
+	for attempt := 1; ; attempt++ {
+		err := s.store.InTx(ctx, fn)
+		if !errors.Is(err, store.ErrSerialization) || attempt == maxAttempts {
+			return err
+		}
+		// Jitter keeps the transactions that collided from colliding again.
+		delay := time.Duration(attempt) * 10 * time.Millisecond
+		delay += rand.N(delay)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
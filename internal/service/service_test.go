@@ -0,0 +1,469 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/profanity"
+	"github.com/Vikuuu/Chirpy/internal/spam"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// racyStore fails the first failures transactions with ErrSerialization.
+type racyStore struct {
+	*store.Memory
+	failures int
+	attempts int
+}
+
+func (s *racyStore) InTx(ctx context.Context, fn func(tx store.Tx) error) error {
+	s.attempts++
+	if s.attempts <= s.failures {
+		return store.ErrSerialization
+	}
+	return s.Memory.InTx(ctx, fn)
+}
+
+// faultyStore is a store whose transactions can't file reports or record
+// moderation actions.
+type faultyStore struct {
+	*store.Memory
+}
+
+func (s faultyStore) InTx(ctx context.Context, fn func(tx store.Tx) error) error {
+	return s.Memory.InTx(ctx, func(tx store.Tx) error {
+		return fn(faultyTx{tx})
+	})
+}
+
+type faultyTx struct {
+	store.Tx
+}
+
+func (faultyTx) CreateReport(ctx context.Context, arg database.CreateReportParams) (database.Report, error) {
+	return database.Report{}, errors.New("reports are down")
+}
+
+func (faultyTx) CreateModerationAction(ctx context.Context, arg database.CreateModerationActionParams) (database.ModerationAction, error) {
+	return database.ModerationAction{}, errors.New("moderation log is down")
+}
+
+// auditLog is a store that keeps the audit events recorded through its
+// transactions, committed or not.
+type auditLog struct {
+	*store.Memory
+	events []database.AuditEvent
+}
+
+func (s *auditLog) InTx(ctx context.Context, fn func(tx store.Tx) error) error {
+	return s.Memory.InTx(ctx, func(tx store.Tx) error {
+		return fn(auditTx{Tx: tx, log: s})
+	})
+}
+
+type auditTx struct {
+	store.Tx
+	log *auditLog
+}
+
+func (tx auditTx) CreateAuditEvent(ctx context.Context, arg database.CreateAuditEventParams) (database.AuditEvent, error) {
+	e, err := tx.Tx.CreateAuditEvent(ctx, arg)
+	if err == nil {
+		tx.log.events = append(tx.log.events, e)
+	}
+	return e, err
+}
+
+func newService(st store.Store) *Service {
+	filter := profanity.New(profanity.Options{})
+	filter.Load([]profanity.Entry{{Word: "meth", Action: profanity.ActionFlag}})
+	return New(st, Config{Filter: filter, Spam: spam.DefaultConfig(), Secret: "secret"})
+}
+
+func createUser(t *testing.T, s *Service, email string) uuid.UUID {
+	t.Helper()
+	u, err := s.Register(context.Background(), email, "password123")
+	require.NoError(t, err)
+	return u.ID
+}
+
+func TestAtomically(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name     string
+		failures int
+		err      error
+		expected error
+		attempts int
+	}{
+		{name: "First Try", attempts: 1},
+		{name: "Retried", failures: 2, attempts: 3},
+		{name: "Given Up", failures: maxAttempts, expected: store.ErrSerialization, attempts: maxAttempts},
+		{name: "Other Errors Not Retried", err: failed, expected: failed, attempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &racyStore{Memory: store.NewMemory(), failures: tt.failures}
+			s := newService(st)
+			err := s.atomically(context.Background(), func(tx store.Tx) error { return tt.err })
+			if tt.expected != nil {
+				assert.ErrorIs(t, err, tt.expected)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.attempts, st.attempts)
+		})
+	}
+}
+
+func TestPostChirp(t *testing.T) {
+	ctx := context.Background()
+	s := newService(store.NewMemory())
+	userID := createUser(t, s, "walt@example.com")
+
+	tests := []struct {
+		name string
+		body string
+		kind Kind
+	}{
+		{name: "Blank", body: "   ", kind: Invalid},
+		{name: "Too Long", body: strings.Repeat("é", MaxChirpLength+1), kind: Invalid},
+		{name: "Spam", body: "say my name", kind: Spam},
+	}
+
+	_, err := s.PostChirp(ctx, userID, "say my name")
+	require.NoError(t, err)
+	_, err = s.PostChirp(ctx, userID, "say my name")
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.PostChirp(ctx, userID, tt.body)
+			assert.Equal(t, tt.kind, KindOf(err))
+		})
+	}
+
+	c, err := s.PostChirp(ctx, userID, strings.Repeat("é", MaxChirpLength))
+	require.NoError(t, err, "length is counted in characters, not bytes")
+	assert.Equal(t, userID, c.UserID)
+}
+
+func TestPostChirpIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemory()
+	s := newService(faultyStore{st})
+	userID := createUser(t, s, "walt@example.com")
+
+	_, err := s.PostChirp(ctx, userID, "blue meth")
+	require.Error(t, err)
+
+	chirps, err := st.GetChirps(ctx, uuid.NullUUID{})
+	require.NoError(t, err)
+	assert.Empty(t, chirps, "a flagged chirp isn't published if it can't be queued for review")
+
+	_, err = s.PostChirp(ctx, userID, "science, yeah")
+	require.NoError(t, err)
+}
+
+func TestDeleteChirp(t *testing.T) {
+	ctx := context.Background()
+	s := newService(store.NewMemory())
+	walt := createUser(t, s, "walt@example.com")
+	jesse := createUser(t, s, "jesse@example.com")
+	c, err := s.PostChirp(ctx, walt, "tread lightly")
+	require.NoError(t, err)
+
+	assert.Equal(t, Forbidden, KindOf(s.DeleteChirp(ctx, jesse, c.ID)))
+	_, err = s.Chirp(ctx, c.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteChirp(ctx, walt, c.ID))
+	assert.Equal(t, NotFound, KindOf(s.DeleteChirp(ctx, walt, c.ID)))
+}
+
+func TestModerateReportIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemory()
+	s := newService(st)
+	walt := createUser(t, s, "walt@example.com")
+	jesse := createUser(t, s, "jesse@example.com")
+	moderator := createUser(t, s, "mod@example.com")
+	c, err := s.PostChirp(ctx, walt, "los pollos hermanos")
+	require.NoError(t, err)
+	report, err := s.Report(ctx, jesse, NewReport{TargetType: ReportTargetChirp, ChirpID: c.ID, Reason: "other"})
+	require.NoError(t, err)
+
+	_, _, err = newService(faultyStore{st}).ModerateReport(ctx, moderator, report.ID, Moderation{Action: ActionBan, Reason: "gone"})
+	require.Error(t, err)
+
+	user, err := st.GetUserByID(ctx, walt)
+	require.NoError(t, err)
+	assert.Equal(t, StatusActive, user.Status, "the ban is undone when it can't be recorded")
+	got, err := st.GetReport(ctx, report.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ReportStatusOpen, got.Status)
+
+	_, _, err = s.ModerateReport(ctx, moderator, report.ID, Moderation{Action: ActionBan, Reason: "gone"})
+	require.NoError(t, err)
+	_, _, err = s.ModerateReport(ctx, moderator, report.ID, Moderation{Action: ActionWarn, Reason: "again"})
+	assert.Equal(t, Conflict, KindOf(err))
+}
+
+func TestResetConfirmation(t *testing.T) {
+	ctx := context.Background()
+	s := newService(store.NewMemory())
+	admin, other := uuid.New(), uuid.New()
+	scope := ResetScope{Scope: ResetUsers, Pattern: "*@example.com"}
+	token, expires, err := s.ResetConfirmation(admin, scope)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(resetConfirmationTTL), expires, time.Second)
+
+	tests := []struct {
+		name  string
+		admin uuid.UUID
+		scope ResetScope
+		token string
+	}{
+		{name: "Other Admin", admin: other, scope: scope, token: token},
+		{name: "Other Pattern", admin: admin, scope: ResetScope{Scope: ResetUsers, Pattern: "*"}, token: token},
+		{name: "Other Scope", admin: admin, scope: ResetScope{Scope: ResetAll}, token: token},
+		{name: "Expired", admin: admin, scope: scope, token: s.resetToken(admin, scope, time.Now().Add(-time.Second))},
+		{name: "Tampered Expiry", admin: admin, scope: scope, token: "9999999999" + token[strings.Index(token, "."):]},
+		{name: "Garbage", admin: admin, scope: scope, token: "garbage"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Reset(ctx, tt.admin, tt.scope, tt.token, nil)
+			assert.Equal(t, Invalid, KindOf(err))
+		})
+	}
+
+	_, err = s.Reset(ctx, admin, scope, token, nil)
+	require.NoError(t, err)
+}
+
+func TestResetIsAudited(t *testing.T) {
+	st := &auditLog{Memory: store.NewMemory()}
+	s := newService(st)
+	admin := createUser(t, s, "admin@example.org")
+	walt := createUser(t, s, "walt@example.com")
+	_, err := s.PostChirp(context.Background(), walt, "say my name")
+	require.NoError(t, err)
+	ctx := WithOrigin(context.Background(), Origin{IP: "192.0.2.1", UserAgent: "curl/8.0", RequestID: "req-1"})
+	scope := ResetScope{Scope: ResetUsers, Pattern: "*@example.com"}
+	token, _, err := s.ResetConfirmation(admin, scope)
+	require.NoError(t, err)
+
+	counts, err := s.Reset(ctx, admin, scope, token, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ResetCounts{Users: 1, Chirps: 1}, counts)
+	_, err = st.GetUserByID(ctx, admin)
+	require.NoError(t, err, "users not matching the pattern stay")
+
+	require.Len(t, st.events, 1)
+	e := st.events[0]
+	assert.Equal(t, AuditAdminReset, e.Action)
+	assert.Equal(t, uuid.NullUUID{UUID: admin, Valid: true}, e.ActorID)
+	assert.Equal(t, ResetUsers, e.TargetType)
+	assert.Equal(t, "*@example.com", e.TargetID)
+	assert.Equal(t, "192.0.2.1", e.Ip)
+	assert.Equal(t, "curl/8.0", e.UserAgent)
+	assert.Equal(t, "req-1", e.RequestID)
+	assert.JSONEq(t, `{"users": 1, "chirps": 1, "refresh_tokens": 0}`, string(e.Details))
+}
+
+func TestResetRollsBackWhenRefillFails(t *testing.T) {
+	ctx := context.Background()
+	s := newService(store.NewMemory())
+	admin := createUser(t, s, "admin@example.org")
+	walt := createUser(t, s, "walt@example.com")
+	scope := ResetScope{Scope: ResetAll}
+	token, _, err := s.ResetConfirmation(admin, scope)
+	require.NoError(t, err)
+
+	failed := errors.New("failed")
+	_, err = s.Reset(ctx, admin, scope, token, func(tx store.Tx) error {
+		_, err := tx.GetUserByID(ctx, walt)
+		require.ErrorIs(t, err, sql.ErrNoRows, "refill runs after the reset")
+		return failed
+	})
+	require.ErrorIs(t, err, failed)
+	_, err = s.store.GetUserByID(ctx, walt)
+	assert.NoError(t, err, "nothing is deleted")
+}
+
+func TestLoginIsAudited(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemory()
+	s := newService(st)
+	walt := createUser(t, s, "walt@example.com")
+	jesse := createUser(t, s, "jesse@example.com")
+	_, err := st.SetUserStatus(ctx, database.SetUserStatusParams{Status: StatusBanned, ID: jesse})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		action   string
+		target   string
+		details  string
+	}{
+		{name: "Success", email: "walt@example.com", password: "password123", action: AuditLogin},
+		{name: "Wrong Password", email: "walt@example.com", password: "password456", action: AuditLoginFailed, target: walt.String(),
+			details: `{"email_fingerprint": "` + s.emailFingerprint("walt@example.com") + `", "reason": "wrong_password"}`},
+		{name: "Unknown Email", email: "skyler@example.com", password: "password123", action: AuditLoginFailed,
+			details: `{"email_fingerprint": "` + s.emailFingerprint("skyler@example.com") + `", "reason": "unknown_email"}`},
+		{name: "Restricted", email: "jesse@example.com", password: "password123", action: AuditLoginFailed, target: jesse.String(),
+			details: `{"email_fingerprint": "` + s.emailFingerprint("jesse@example.com") + `", "reason": "restricted"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, loginErr := s.Login(ctx, tt.email, tt.password)
+			events, err := st.ListAuditEvents(ctx, database.ListAuditEventsParams{Limit: 1})
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			e := events[0]
+			assert.Equal(t, tt.action, e.Action)
+			if tt.action == AuditLogin {
+				require.NoError(t, loginErr)
+				assert.Equal(t, uuid.NullUUID{UUID: walt, Valid: true}, e.ActorID)
+				assert.Equal(t, tokenFingerprint(session.RefreshToken), e.TargetID)
+				assert.NotContains(t, e.TargetID, session.RefreshToken)
+				return
+			}
+			require.Error(t, loginErr)
+			assert.False(t, e.ActorID.Valid)
+			assert.Equal(t, tt.target, e.TargetID)
+			assert.JSONEq(t, tt.details, string(e.Details))
+			assert.NotContains(t, string(e.Details), tt.email)
+		})
+	}
+	other := New(store.NewMemory(), Config{Secret: "other"})
+	assert.NotEqual(t, s.emailFingerprint("walt@example.com"), other.emailFingerprint("walt@example.com"), "fingerprints are keyed")
+}
+
+func TestUpdateUserIsAudited(t *testing.T) {
+	ctx := context.Background()
+	st := &auditLog{Memory: store.NewMemory()}
+	s := newService(st)
+	walt := createUser(t, s, "walt@example.com")
+
+	_, err := s.UpdateUser(ctx, walt, "walt@example.com", "password456")
+	require.NoError(t, err)
+	require.Len(t, st.events, 1)
+	assert.Equal(t, AuditUserUpdate, st.events[0].Action)
+	assert.JSONEq(t, `{"password": {"redacted": true}}`, string(st.events[0].Details), "an unchanged email isn't a change")
+	assert.NotContains(t, string(st.events[0].Details), "password456")
+
+	_, err = s.UpdateUser(ctx, walt, "heisenberg@example.com", "password456")
+	require.NoError(t, err)
+	require.Len(t, st.events, 2)
+	assert.JSONEq(t, `{
+		"email": {"old": "`+s.emailFingerprint("walt@example.com")+`", "new": "`+s.emailFingerprint("heisenberg@example.com")+`"},
+		"password": {"redacted": true}
+	}`, string(st.events[1].Details))
+	assert.NotContains(t, string(st.events[1].Details), "@example.com", "the log outlives the account, so it keeps no addresses")
+
+	_, err = s.UpdateUser(ctx, uuid.New(), "gone@example.com", "password456")
+	assert.Equal(t, NotFound, KindOf(err))
+	assert.Len(t, st.events, 2)
+}
+
+// brokenChirpsStore is a store whose transactions can't list a user's
+// chirps.
+type brokenChirpsStore struct {
+	*store.Memory
+}
+
+func (s brokenChirpsStore) InTx(ctx context.Context, fn func(tx store.Tx) error) error {
+	return s.Memory.InTx(ctx, func(tx store.Tx) error {
+		return fn(brokenChirpsTx{tx})
+	})
+}
+
+type brokenChirpsTx struct {
+	store.Tx
+}
+
+func (brokenChirpsTx) ListChirpsForUser(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	return nil, errors.New("chirps are down")
+}
+
+func TestDataExportFailure(t *testing.T) {
+	ctx := context.Background()
+	st := brokenChirpsStore{store.NewMemory()}
+	s := newService(st)
+	walt := createUser(t, s, "walt@example.com")
+
+	queued, err := s.RequestDataExport(ctx, walt)
+	require.NoError(t, err)
+	select {
+	case <-s.ExportsQueued():
+	default:
+		t.Error("queueing an export doesn't wake the worker")
+	}
+
+	n, err := s.ProcessDataExports(ctx)
+	require.NoError(t, err, "a failed export is recorded, not retried")
+	assert.Equal(t, 1, n)
+	export, err := s.DataExport(ctx, walt, queued.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ExportFailed, export.Status)
+	assert.Contains(t, export.Error, "chirps are down")
+	assert.Nil(t, export.Archive)
+
+	again, err := s.RequestDataExport(ctx, walt)
+	require.NoError(t, err)
+	assert.NotEqual(t, queued.ID, again.ID, "a failed export can be asked for again")
+}
+
+func TestDeleteAccountWithGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemory()
+	s := New(st, Config{Filter: profanity.New(profanity.Options{}), Spam: spam.DefaultConfig(), Secret: "secret", DeletionGrace: time.Hour})
+	walt := createUser(t, s, "walt@example.com")
+	_, err := s.Login(ctx, "walt@example.com", "password123")
+	require.NoError(t, err)
+
+	_, err = s.DeleteAccount(ctx, walt, "password456")
+	assert.Equal(t, Invalid, KindOf(err))
+	_, err = st.GetAccountDeletion(ctx, walt)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	deleteAfter, err := s.DeleteAccount(ctx, walt, "password123")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), deleteAfter, time.Minute)
+	tokens, err := st.ListRefreshTokensForUser(ctx, walt)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.True(t, tokens[0].RevokedAt.Valid, "sessions end when the deletion is asked for")
+
+	_, err = s.DeleteAccount(ctx, uuid.New(), "password123")
+	assert.Equal(t, NotFound, KindOf(err))
+}
+
+func TestPasswordsOver72BytesAreInvalid(t *testing.T) {
+	ctx := context.Background()
+	s := newService(store.NewMemory())
+	long := strings.Repeat("é", 40)
+
+	_, err := s.Register(ctx, "walt@example.com", long)
+	assert.Equal(t, Invalid, KindOf(err), "rather than a bcrypt error")
+
+	walt := createUser(t, s, "walt@example.com")
+	_, err = s.UpdateUser(ctx, walt, "walt@example.com", long)
+	assert.Equal(t, Invalid, KindOf(err))
+}
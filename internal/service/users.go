@@ -0,0 +1,358 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/auth"
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+const (
+	StatusActive       = "active"
+	StatusSuspended    = "suspended"
+	StatusBanned       = "banned"
+	StatusShadowBanned = "shadow_banned"
+)
+
+// accessTokenTTL is how long an access token is valid for.
+const accessTokenTTL = time.Hour
+
+// AccountRestriction returns why user may not use their account right now,
+// or an empty string if they may. Shadow-banned users are deliberately not
+// told.
+func AccountRestriction(user database.User, now time.Time) string {
+	switch user.Status {
+	case StatusBanned:
+		return "account is banned"
+	case StatusSuspended:
+		if !user.SuspendedUntil.Valid {
+			return "account is suspended"
+		}
+		if user.SuspendedUntil.Time.After(now) {
+			return fmt.Sprintf("account is suspended until %s", user.SuspendedUntil.Time.UTC().Format(time.RFC3339))
+		}
+	}
+	return ""
+}
+
+// ActiveUser loads the user an access token was issued to and checks that
+// they may still use their account; a token outlives a suspension or ban,
+// and the deletion of the account. An account scheduled for deletion is
+// only restored by logging in, as the user is told.
+func (s *Service) ActiveUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := s.store.GetUserByID(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return database.User{}, &Error{Kind: Unauthenticated, Message: "Unauthorized", Err: err}
+		}
+		return database.User{}, fmt.Errorf("getting user: %w", err)
+	}
+	if msg := AccountRestriction(user, time.Now()); msg != "" {
+		return database.User{}, fail(Restricted, msg)
+	}
+	if _, err := s.store.GetAccountDeletion(ctx, id); err != sql.ErrNoRows {
+		if err != nil {
+			return database.User{}, fmt.Errorf("getting deletion: %w", err)
+		}
+		return database.User{}, fail(Unauthenticated, "account is scheduled for deletion; log in to restore it")
+	}
+	return user, nil
+}
+
+// CheckRole checks that user holds one of roles.
+func CheckRole(user database.User, roles ...string) error {
+	if !slices.Contains(roles, user.Role) {
+		return fail(Forbidden, "Forbidden")
+	}
+	return nil
+}
+
+// checkPassword refuses a password longer than bcrypt can hash.
+func checkPassword(password string) error {
+	if len(password) > auth.MaxPasswordBytes {
+		return invalidFields(FieldError{
+			Field:   "password",
+			Code:    "too_long",
+			Message: fmt.Sprintf("password must be at most %d bytes", auth.MaxPasswordBytes),
+		})
+	}
+	return nil
+}
+
+// Register creates an account.
+func (s *Service) Register(ctx context.Context, email, password string) (database.CreateUserRow, error) {
+	if err := checkPassword(password); err != nil {
+		return database.CreateUserRow{}, err
+	}
+	hash, err := auth.HashPassword(ctx, password)
+	if err != nil {
+		return database.CreateUserRow{}, fmt.Errorf("hashing password: %w", err)
+	}
+
+	user, err := s.store.CreateUser(ctx, database.CreateUserParams{
+		Email:          email,
+		HashedPassword: hash,
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return database.CreateUserRow{}, fail(Conflict, "email is already registered")
+		}
+		return database.CreateUserRow{}, fmt.Errorf("creating user: %w", err)
+	}
+	return user, nil
+}
+
+// Session is what a login hands out.
+type Session struct {
+	User         database.User
+	AccessToken  string
+	RefreshToken string
+}
+
+// Login checks a user's password and opens a session for them. Failed
+// logins are audited as well as successful ones.
+//
+// The password is checked outside the transaction, since bcrypt is slow on
+// purpose. The transaction then makes sure the account hasn't been
+// restricted or given a new password since, and stores the refresh token.
+func (s *Service) Login(ctx context.Context, email, password string) (Session, error) {
+	const unauthMsg = "incorrect email or password"
+
+	user, err := s.store.GetUser(ctx, email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Session{}, s.loginFailed(ctx, email, uuid.Nil, loginUnknownEmail, fail(Unauthenticated, unauthMsg))
+		}
+		return Session{}, fmt.Errorf("getting user: %w", err)
+	}
+	if err := auth.CheckPasswordHash(ctx, password, user.HashedPassword); err != nil {
+		return Session{}, s.loginFailed(ctx, email, user.ID, loginWrongPassword, fail(Unauthenticated, unauthMsg))
+	}
+
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return Session{}, fmt.Errorf("creating refresh token: %w", err)
+	}
+	var reason string
+	err = s.atomically(ctx, func(tx store.Tx) error {
+		current, err := tx.GetUserByID(ctx, user.ID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				reason = loginUnknownEmail
+				return fail(Unauthenticated, unauthMsg)
+			}
+			return fmt.Errorf("getting user: %w", err)
+		}
+		if current.HashedPassword != user.HashedPassword {
+			reason = loginWrongPassword
+			return fail(Unauthenticated, unauthMsg)
+		}
+		if msg := AccountRestriction(current, time.Now()); msg != "" {
+			reason = loginRestricted
+			return fail(Restricted, msg)
+		}
+		user = current
+
+		if err := tx.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+			Token:  refreshToken,
+			UserID: user.ID,
+		}); err != nil {
+			return fmt.Errorf("storing refresh token: %w", err)
+		}
+		// Logging in is how a deleted account is restored.
+		if err := cancelAccountDeletion(ctx, tx, user.ID); err != nil {
+			return err
+		}
+		return audit(ctx, tx, auditEvent{
+			Action:     AuditLogin,
+			Actor:      user.ID,
+			TargetType: AuditTargetRefreshToken,
+			TargetID:   tokenFingerprint(refreshToken),
+		})
+	})
+	if err != nil {
+		if reason != "" {
+			return Session{}, s.loginFailed(ctx, email, user.ID, reason, err)
+		}
+		return Session{}, err
+	}
+
+	accessToken, err := auth.MakeJWT(user.ID, s.secret, accessTokenTTL)
+	if err != nil {
+		return Session{}, fmt.Errorf("creating access token: %w", err)
+	}
+	return Session{User: user, AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// loginFailed audits a failed login as email, for reason, and returns err,
+// the failure. userID is the account email belongs to, if there is one. The
+// email is recorded by its fingerprint, which still tells repeated attempts
+// on one address apart from attempts on many.
+func (s *Service) loginFailed(ctx context.Context, email string, userID uuid.UUID, reason string, err error) error {
+	e := auditEvent{
+		Action:     AuditLoginFailed,
+		TargetType: AuditTargetUser,
+		Details:    map[string]string{"email_fingerprint": s.emailFingerprint(email), "reason": reason},
+	}
+	if userID != uuid.Nil {
+		e.TargetID = userID.String()
+	}
+	// Nothing else is written, so the event needs no transaction.
+	if auditErr := audit(ctx, s.store, e); auditErr != nil {
+		return auditErr
+	}
+	return err
+}
+
+// Refresh issues a new access token for a refresh token that is still good.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (string, error) {
+	var userID uuid.UUID
+	err := s.atomically(ctx, func(tx store.Tx) error {
+		token, err := tx.GetUserFromRefreshToken(ctx, refreshToken)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fail(Unauthenticated, "not a valid refresh token")
+			}
+			return fmt.Errorf("getting refresh token: %w", err)
+		}
+		if token.ExpiresAt.Before(time.Now()) || token.RevokedAt.Valid {
+			return fail(Unauthenticated, "refresh token expired")
+		}
+
+		user, err := tx.GetUserByID(ctx, token.UserID)
+		if err != nil {
+			return fmt.Errorf("getting user: %w", err)
+		}
+		if msg := AccountRestriction(user, time.Now()); msg != "" {
+			return fail(Restricted, msg)
+		}
+		userID = user.ID
+		return audit(ctx, tx, auditEvent{
+			Action:     AuditRefresh,
+			Actor:      user.ID,
+			TargetType: AuditTargetRefreshToken,
+			TargetID:   tokenFingerprint(refreshToken),
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	accessToken, err := auth.MakeJWT(userID, s.secret, accessTokenTTL)
+	if err != nil {
+		return "", fmt.Errorf("creating access token: %w", err)
+	}
+	return accessToken, nil
+}
+
+// Revoke ends the session a refresh token belongs to. An unknown token is
+// not an error.
+func (s *Service) Revoke(ctx context.Context, refreshToken string) error {
+	return s.atomically(ctx, func(tx store.Tx) error {
+		token, err := tx.GetUserFromRefreshToken(ctx, refreshToken)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil
+			}
+			return fmt.Errorf("getting refresh token: %w", err)
+		}
+
+		now := time.Now()
+		err = tx.RevokeRefreshToken(ctx, database.RevokeRefreshTokenParams{
+			RevokedAt: sql.NullTime{Time: now, Valid: true},
+			UpdatedAt: now,
+			Token:     refreshToken,
+		})
+		if err != nil {
+			return fmt.Errorf("revoking token: %w", err)
+		}
+		return audit(ctx, tx, auditEvent{
+			Action:     AuditRevoke,
+			Actor:      token.UserID,
+			TargetType: AuditTargetRefreshToken,
+			TargetID:   tokenFingerprint(refreshToken),
+		})
+	})
+}
+
+// UpdateUser replaces a user's email and password, returning the new email.
+// The audit log records the fingerprints of the old and new email, since the
+// log outlives the account, and only that the password was set.
+func (s *Service) UpdateUser(ctx context.Context, id uuid.UUID, email, password string) (string, error) {
+	if err := checkPassword(password); err != nil {
+		return "", err
+	}
+	hash, err := auth.HashPassword(ctx, password)
+	if err != nil {
+		return "", fmt.Errorf("hashing password: %w", err)
+	}
+
+	var updated string
+	err = s.atomically(ctx, func(tx store.Tx) error {
+		old, err := tx.GetUserByID(ctx, id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fail(NotFound, "user not found")
+			}
+			return fmt.Errorf("getting user: %w", err)
+		}
+
+		updated, err = tx.EditUser(ctx, database.EditUserParams{
+			Email:          email,
+			HashedPassword: hash,
+			UpdatedAt:      time.Now().UTC(),
+			ID:             id,
+		})
+		if err != nil {
+			if errors.Is(err, store.ErrConflict) {
+				return fail(Conflict, "email is already registered")
+			}
+			return fmt.Errorf("updating user: %w", err)
+		}
+
+		changes := map[string]change{"password": {Redacted: true}}
+		if updated != old.Email {
+			changes["email"] = change{Old: s.emailFingerprint(old.Email), New: s.emailFingerprint(updated)}
+		}
+		return audit(ctx, tx, auditEvent{
+			Action:     AuditUserUpdate,
+			Actor:      id,
+			TargetType: AuditTargetUser,
+			TargetID:   id.String(),
+			Details:    changes,
+		})
+	})
+	return updated, err
+}
+
+// UpgradeToRed gives a user Chirpy Red, as paid for through Polka.
+func (s *Service) UpgradeToRed(ctx context.Context, userID uuid.UUID) error {
+	return s.atomically(ctx, func(tx store.Tx) error {
+		n, err := tx.UpgradeUserToRed(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("updating user: %w", err)
+		}
+		if n == 0 {
+			return fail(NotFound, "User Not Found")
+		}
+		return audit(ctx, tx, auditEvent{
+			Action:     AuditUserUpgrade,
+			TargetType: AuditTargetUser,
+			TargetID:   userID.String(),
+		})
+	})
+}
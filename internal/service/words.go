@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// PutBannedWord bans a normalized word, or changes what happens to chirps
+// using it, on behalf of an admin.
+func (s *Service) PutBannedWord(ctx context.Context, adminID uuid.UUID, word, action string) (database.BannedWord, error) {
+	var banned database.BannedWord
+	err := s.atomically(ctx, func(tx store.Tx) error {
+		var err error
+		banned, err = tx.UpsertBannedWord(ctx, database.UpsertBannedWordParams{
+			Word:   word,
+			Action: action,
+		})
+		if err != nil {
+			return fmt.Errorf("saving banned word: %w", err)
+		}
+		return audit(ctx, tx, auditEvent{
+			Action:     AuditPutBannedWord,
+			Actor:      adminID,
+			TargetType: AuditTargetBannedWord,
+			TargetID:   word,
+			Details:    map[string]string{"action": action},
+		})
+	})
+	return banned, err
+}
+
+// DeleteBannedWord lifts the ban on a normalized word on behalf of an admin.
+func (s *Service) DeleteBannedWord(ctx context.Context, adminID uuid.UUID, word string) error {
+	return s.atomically(ctx, func(tx store.Tx) error {
+		n, err := tx.DeleteBannedWord(ctx, word)
+		if err != nil {
+			return fmt.Errorf("deleting banned word: %w", err)
+		}
+		if n == 0 {
+			return fail(NotFound, "word is not in the list")
+		}
+		return audit(ctx, tx, auditEvent{
+			Action:     AuditDeleteBannedWord,
+			Actor:      adminID,
+			TargetType: AuditTargetBannedWord,
+			TargetID:   word,
+		})
+	})
+}
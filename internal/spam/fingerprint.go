@@ -0,0 +1,94 @@
+package spam
+
+import (
+	"crypto/sha256"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// shingleSize is the length in characters of the shingles fed to simhash.
+const shingleSize = 4
+
+// Fingerprint identifies a chirp body for duplicate detection.
+type Fingerprint struct {
+	// Hash is the SHA-256 of the normalized body; equal hashes are exact
+	// duplicates.
+	Hash [32]byte
+	// SimHash is a 64-bit locality-sensitive hash over character shingles;
+	// similar bodies have a small Hamming distance.
+	SimHash uint64
+}
+
+// NewFingerprint computes the fingerprint of body.
+func NewFingerprint(body string) Fingerprint {
+	n := normalize(body)
+	return Fingerprint{
+		Hash:    sha256.Sum256([]byte(n)),
+		SimHash: simhash(n),
+	}
+}
+
+// Near reports whether f and o are within distance bits of each other.
+func (f Fingerprint) Near(o Fingerprint, distance int) bool {
+	return bits.OnesCount64(f.SimHash^o.SimHash) <= distance
+}
+
+// normalize case-folds body, drops punctuation and collapses whitespace, so
+// that "Buy now!!" and "buy   now" hash the same.
+func normalize(body string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(body) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// simhash computes Charikar's simhash over the character shingles of s.
+func simhash(s string) uint64 {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	add := func(shingle []rune) {
+		h := fnv.New64a()
+		h.Write([]byte(string(shingle)))
+		sum := h.Sum64()
+		for i := range weights {
+			if sum&(1<<i) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	if len(runes) <= shingleSize {
+		add(runes)
+	} else {
+		for i := 0; i+shingleSize <= len(runes); i++ {
+			add(runes[i : i+shingleSize])
+		}
+	}
+
+	var out uint64
+	for i, w := range weights {
+		if w > 0 {
+			out |= 1 << i
+		}
+	}
+	return out
+}
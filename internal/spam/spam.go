@@ -0,0 +1,183 @@
+// Package spam scores new chirps against a user's recent chirps to catch
+// repeated posts, link bursts and mass mentions.
+package spam
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Verdict is what should happen to a chirp.
+type Verdict int
+
+const (
+	// Allow accepts the chirp.
+	Allow Verdict = iota
+	// Review accepts the chirp and sends it to the moderation queue.
+	Review
+	// Reject refuses the chirp.
+	Reject
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Review:
+		return "review"
+	case Reject:
+		return "reject"
+	default:
+		return "allow"
+	}
+}
+
+// Threshold is the count at which a signal sends a chirp to review and at
+// which it rejects it. A zero value disables that tier.
+type Threshold struct {
+	Review int
+	Reject int
+}
+
+// ParseThreshold parses "review=N,reject=M". Either part may be omitted.
+func ParseThreshold(s string) (Threshold, error) {
+	var t Threshold
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return Threshold{}, fmt.Errorf("threshold %q: want review=N,reject=M", s)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(val))
+		if err != nil || n < 0 {
+			return Threshold{}, fmt.Errorf("threshold %q: %q is not a count", s, val)
+		}
+		switch strings.TrimSpace(key) {
+		case "review":
+			t.Review = n
+		case "reject":
+			t.Reject = n
+		default:
+			return Threshold{}, fmt.Errorf("threshold %q: unknown tier %q", s, key)
+		}
+	}
+	return t, nil
+}
+
+// String formats t the way ParseThreshold reads it.
+func (t Threshold) String() string {
+	return fmt.Sprintf("review=%d,reject=%d", t.Review, t.Reject)
+}
+
+func (t Threshold) verdict(n int) Verdict {
+	switch {
+	case t.Reject > 0 && n >= t.Reject:
+		return Reject
+	case t.Review > 0 && n >= t.Review:
+		return Review
+	default:
+		return Allow
+	}
+}
+
+// Config holds the detection thresholds.
+type Config struct {
+	// Window is how far back a user's chirps are compared.
+	Window time.Duration
+	// NearDistance is the largest simhash Hamming distance at which two
+	// chirps count as near-duplicates.
+	NearDistance int
+	// Duplicates counts earlier exact or near-duplicate chirps in Window.
+	Duplicates Threshold
+	// LinkOnly counts link-only chirps in Window, including the new one.
+	LinkOnly Threshold
+	// Mentions counts distinct @mentions in the new chirp.
+	Mentions Threshold
+}
+
+// DefaultConfig returns the thresholds used when nothing is configured.
+func DefaultConfig() Config {
+	return Config{
+		Window:       10 * time.Minute,
+		NearDistance: 6,
+		Duplicates:   Threshold{Review: 1, Reject: 2},
+		LinkOnly:     Threshold{Review: 3, Reject: 5},
+		Mentions:     Threshold{Review: 5, Reject: 10},
+	}
+}
+
+// Result is the verdict for a chirp and the signals that led to it.
+type Result struct {
+	Verdict Verdict
+	Reasons []string
+}
+
+func (r *Result) add(v Verdict, reason string) {
+	if v == Allow {
+		return
+	}
+	if v > r.Verdict {
+		r.Verdict = v
+	}
+	r.Reasons = append(r.Reasons, reason)
+}
+
+// Check scores body against the bodies of the same user's chirps posted
+// within cfg.Window.
+func Check(cfg Config, body string, recent []string) Result {
+	var res Result
+
+	fp := NewFingerprint(body)
+	bodyLinkOnly := IsLinkOnly(body)
+	dups := 0
+	linkOnly := 0
+	if bodyLinkOnly {
+		linkOnly++
+	}
+	for _, prev := range recent {
+		p := NewFingerprint(prev)
+		if p.Hash == fp.Hash || p.Near(fp, cfg.NearDistance) {
+			dups++
+		}
+		if IsLinkOnly(prev) {
+			linkOnly++
+		}
+	}
+
+	res.add(cfg.Duplicates.verdict(dups), fmt.Sprintf("%d similar chirps in the last %s", dups, cfg.Window))
+	if bodyLinkOnly {
+		res.add(cfg.LinkOnly.verdict(linkOnly), fmt.Sprintf("%d link-only chirps in the last %s", linkOnly, cfg.Window))
+	}
+	mentions := CountMentions(body)
+	res.add(cfg.Mentions.verdict(mentions), fmt.Sprintf("%d mentions", mentions))
+
+	return res
+}
+
+var (
+	urlPattern     = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+	mentionPattern = regexp.MustCompile(`@(\w+)`)
+)
+
+// IsLinkOnly reports whether body has at least one link and nothing else but
+// whitespace and punctuation.
+func IsLinkOnly(body string) bool {
+	if !urlPattern.MatchString(body) {
+		return false
+	}
+	rest := urlPattern.ReplaceAllString(body, "")
+	return normalize(rest) == ""
+}
+
+// CountMentions returns the number of distinct @handles in body.
+func CountMentions(body string) int {
+	seen := map[string]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		seen[strings.ToLower(m[1])] = true
+	}
+	return len(seen)
+}
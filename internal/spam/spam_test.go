@@ -0,0 +1,159 @@
+package spam
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  string
+		exact bool
+		near  bool
+	}{
+		{
+			name:  "Identical",
+			a:     "Check out my amazing new crypto project",
+			b:     "Check out my amazing new crypto project",
+			exact: true,
+			near:  true,
+		},
+		{
+			name:  "Case And Punctuation",
+			a:     "Check out my amazing new crypto project",
+			b:     "check out   my amazing new crypto project!!!",
+			exact: true,
+			near:  true,
+		},
+		{
+			name: "Appended Counter",
+			a:    "Check out my amazing new crypto project, it will make you rich",
+			b:    "Check out my amazing new crypto project, it will make you rich 2",
+			near: true,
+		},
+		{
+			name: "One Word Changed",
+			a:    "Buy cheap watches at my shop today",
+			b:    "Buy cheap watches at my shop now",
+			near: true,
+		},
+		{
+			name: "Unrelated",
+			a:    "I had something interesting for breakfast",
+			b:    "The weather is lovely in Lisbon this week",
+		},
+	}
+
+	cfg := DefaultConfig()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := NewFingerprint(tt.a), NewFingerprint(tt.b)
+			assert.Equal(t, tt.exact, a.Hash == b.Hash)
+			assert.Equal(t, tt.near, a.Near(b, cfg.NearDistance))
+		})
+	}
+}
+
+func TestIsLinkOnly(t *testing.T) {
+	assert.True(t, IsLinkOnly("https://example.com/deal"))
+	assert.True(t, IsLinkOnly("  www.example.com !! https://x.io "))
+	assert.False(t, IsLinkOnly("read this https://example.com/post"))
+	assert.False(t, IsLinkOnly("no links here"))
+}
+
+func TestCountMentions(t *testing.T) {
+	assert.Equal(t, 0, CountMentions("hello"))
+	assert.Equal(t, 2, CountMentions("@alice @bob @Alice hi"))
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		recent   []string
+		expected Verdict
+	}{
+		{
+			name:     "Fresh Chirp",
+			body:     "I had something interesting for breakfast",
+			recent:   []string{"The weather is lovely in Lisbon this week"},
+			expected: Allow,
+		},
+		{
+			name:     "One Repeat Goes To Review",
+			body:     "Follow me for free followers",
+			recent:   []string{"follow me for FREE followers!"},
+			expected: Review,
+		},
+		{
+			name: "Repeated Repeats Are Rejected",
+			body: "Follow me for free followers",
+			recent: []string{
+				"follow me for FREE followers!",
+				"Follow me for free followers",
+			},
+			expected: Reject,
+		},
+		{
+			name:     "Single Link Is Fine",
+			body:     "https://example.com/deal",
+			expected: Allow,
+		},
+		{
+			name: "Link Burst Goes To Review",
+			body: "https://example.com/deal",
+			recent: []string{
+				"https://example.com/a",
+				"https://example.org/b",
+			},
+			expected: Review,
+		},
+		{
+			name: "Link Burst Is Rejected",
+			body: "https://example.com/deal",
+			recent: []string{
+				"https://example.com/a",
+				"https://example.org/b",
+				"https://example.net/c",
+				"https://example.io/d",
+			},
+			expected: Reject,
+		},
+		{
+			name:     "Mass Mention Goes To Review",
+			body:     "hey @a @b @c @d @e",
+			expected: Review,
+		},
+		{
+			name:     "Mass Mention Is Rejected",
+			body:     "@a @b @c @d @e @f @g @h @i @j",
+			expected: Reject,
+		},
+	}
+
+	cfg := DefaultConfig()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Check(cfg, tt.body, tt.recent)
+			assert.Equal(t, tt.expected, res.Verdict, res.Reasons)
+			if tt.expected != Allow {
+				assert.NotEmpty(t, res.Reasons)
+			}
+		})
+	}
+}
+
+func TestParseThreshold(t *testing.T) {
+	th, err := ParseThreshold("review=2, reject=4")
+	assert.NoError(t, err)
+	assert.Equal(t, Threshold{Review: 2, Reject: 4}, th)
+
+	th, err = ParseThreshold("reject=3")
+	assert.NoError(t, err)
+	assert.Equal(t, Threshold{Reject: 3}, th)
+
+	_, err = ParseThreshold("block=3")
+	assert.Error(t, err)
+}
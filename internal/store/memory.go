@@ -0,0 +1,776 @@
+package store
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+)
+
+// Memory is a store that keeps everything in process and loses it on exit.
+// It mirrors the Postgres schema's constraints and cascades closely enough
+// for tests and demos, not for data anyone wants to keep.
+type Memory struct {
+	*memoryTables
+	// inTx is set on the view of the tables a transaction queries through,
+	// whose queries run under the lock the transaction holds.
+	inTx bool
+}
+
+// memoryTables are the rows a Memory store and its transactions share. mu is
+// held for each query made outside a transaction, and for the whole of a
+// transaction, so that queries never see or interleave with one that is
+// still in progress.
+type memoryTables struct {
+	mu sync.Mutex
+	// Rows are kept in insertion order, except that seeded rows are put in
+	// created_at order among the timestamped ones.
+	users     []database.User
+	chirps    []database.Chirp
+	follows   []database.Follow
+	tokens    []database.RefreshToken
+	reports   []database.Report
+	actions   []database.ModerationAction
+	words     []database.BannedWord
+	audit     []database.AuditEvent
+	deletions []database.AccountDeletion
+	exports   []database.DataExport
+}
+
+// NewMemory returns an empty store holding only the default banned words.
+func NewMemory() *Memory {
+	m := &Memory{memoryTables: &memoryTables{}}
+	now := time.Now().UTC()
+	for _, w := range defaultBannedWords {
+		m.words = append(m.words, database.BannedWord{Word: w, Action: "mask", CreatedAt: now, UpdatedAt: now})
+	}
+	return m
+}
+
+func (m *Memory) Ping(ctx context.Context) error { return nil }
+
+func (m *Memory) Close() error { return nil }
+
+// InTx runs fn with every other query held off, and puts every table back
+// as it was if fn fails. Nesting transactions isn't supported.
+func (m *Memory) InTx(ctx context.Context, fn func(tx Tx) error) error {
+	if m.inTx {
+		panic("store: Memory.InTx called inside a transaction")
+	}
+	defer m.lock()()
+	rollback := m.snapshot()
+	if err := fn(&Memory{memoryTables: m.memoryTables, inTx: true}); err != nil {
+		rollback()
+		return err
+	}
+	return nil
+}
+
+// lock takes mu for a query, unless the query is part of a transaction,
+// which holds it already, and returns the func that releases it.
+func (m *Memory) lock() func() {
+	if m.inTx {
+		return func() {}
+	}
+	m.mu.Lock()
+	return m.mu.Unlock
+}
+
+// snapshot copies every table and returns a func that restores the copies.
+// mu must be held.
+func (m *Memory) snapshot() func() {
+	users, chirps, follows := slices.Clone(m.users), slices.Clone(m.chirps), slices.Clone(m.follows)
+	tokens, reports, actions := slices.Clone(m.tokens), slices.Clone(m.reports), slices.Clone(m.actions)
+	words, audit := slices.Clone(m.words), slices.Clone(m.audit)
+	deletions, exports := slices.Clone(m.deletions), slices.Clone(m.exports)
+	return func() {
+		m.users, m.chirps, m.follows = users, chirps, follows
+		m.tokens, m.reports, m.actions = tokens, reports, actions
+		m.words, m.audit = words, audit
+		m.deletions, m.exports = deletions, exports
+	}
+}
+
+// find returns a pointer to the first row in rows matching, or nil.
+func find[T any](rows []T, match func(T) bool) *T {
+	for i := range rows {
+		if match(rows[i]) {
+			return &rows[i]
+		}
+	}
+	return nil
+}
+
+// insertByTime inserts row after every row created no later than it, so that
+// rows inserted with a creation time in the past stay in created_at order.
+func insertByTime[T any](rows []T, row T, createdAt func(T) time.Time) []T {
+	i := len(rows)
+	for i > 0 && createdAt(rows[i-1]).After(createdAt(row)) {
+		i--
+	}
+	return slices.Insert(rows, i, row)
+}
+
+// filter returns the rows matching, in order.
+func filter[T any](rows []T, match func(T) bool) []T {
+	var out []T
+	for _, r := range rows {
+		if match(r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (m *Memory) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.CreateUserRow, error) {
+	defer m.lock()()
+	if find(m.users, func(u database.User) bool { return u.Email == arg.Email }) != nil {
+		return database.CreateUserRow{}, ErrConflict
+	}
+	now := time.Now().UTC()
+	u := database.User{
+		ID:             uuid.New(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+		Role:           "user",
+		Status:         "active",
+	}
+	m.users = append(m.users, u)
+	return database.CreateUserRow{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		Email:       u.Email,
+		IsChirpyRed: u.IsChirpyRed,
+	}, nil
+}
+
+func (m *Memory) GetUser(ctx context.Context, email string) (database.User, error) {
+	defer m.lock()()
+	if u := find(m.users, func(u database.User) bool { return u.Email == email }); u != nil {
+		return *u, nil
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (m *Memory) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	defer m.lock()()
+	if u := m.user(id); u != nil {
+		return *u, nil
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (m *Memory) user(id uuid.UUID) *database.User {
+	return find(m.users, func(u database.User) bool { return u.ID == id })
+}
+
+func (m *Memory) ListUsers(ctx context.Context) ([]database.User, error) {
+	defer m.lock()()
+	return slices.Clone(m.users), nil
+}
+
+func (m *Memory) EditUser(ctx context.Context, arg database.EditUserParams) (string, error) {
+	defer m.lock()()
+	u := m.user(arg.ID)
+	if u == nil {
+		return "", sql.ErrNoRows
+	}
+	if find(m.users, func(o database.User) bool { return o.Email == arg.Email && o.ID != arg.ID }) != nil {
+		return "", ErrConflict
+	}
+	u.Email = arg.Email
+	u.HashedPassword = arg.HashedPassword
+	u.UpdatedAt = arg.UpdatedAt
+	return u.Email, nil
+}
+
+// updateUser applies change to the user with id, counting the rows changed.
+func (m *Memory) updateUser(id uuid.UUID, change func(*database.User)) int64 {
+	defer m.lock()()
+	u := m.user(id)
+	if u == nil {
+		return 0
+	}
+	change(u)
+	return 1
+}
+
+func (m *Memory) UpgradeUserToRed(ctx context.Context, id uuid.UUID) (int64, error) {
+	return m.updateUser(id, func(u *database.User) { u.IsChirpyRed = true }), nil
+}
+
+func (m *Memory) SetUserStatus(ctx context.Context, arg database.SetUserStatusParams) (int64, error) {
+	return m.updateUser(arg.ID, func(u *database.User) {
+		u.Status = arg.Status
+		u.SuspendedUntil = arg.SuspendedUntil
+		u.UpdatedAt = time.Now().UTC()
+	}), nil
+}
+
+func (m *Memory) SetUserRole(ctx context.Context, arg database.SetUserRoleParams) (int64, error) {
+	return m.updateUser(arg.ID, func(u *database.User) {
+		u.Role = arg.Role
+		u.UpdatedAt = time.Now().UTC()
+	}), nil
+}
+
+func (m *Memory) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) (int64, error) {
+	return m.updateUser(arg.ID, func(u *database.User) {
+		u.HashedPassword = arg.HashedPassword
+		u.UpdatedAt = time.Now().UTC()
+	}), nil
+}
+
+// DeleteUser deletes the user as the cascades from users do in Postgres:
+// with their chirps, tokens and follows and the reports and actions against
+// them. Reports they filed and actions they took stay, without them.
+func (m *Memory) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	defer m.lock()()
+	if m.user(id) == nil {
+		return 0, nil
+	}
+	m.users = slices.DeleteFunc(m.users, func(u database.User) bool { return u.ID == id })
+	m.deleteChirps(func(c database.Chirp) bool { return c.UserID == id })
+	m.tokens = slices.DeleteFunc(m.tokens, func(t database.RefreshToken) bool { return t.UserID == id })
+	m.follows = slices.DeleteFunc(m.follows, func(f database.Follow) bool { return f.FollowerID == id || f.FolloweeID == id })
+	m.deletions = slices.DeleteFunc(m.deletions, func(d database.AccountDeletion) bool { return d.UserID == id })
+	m.exports = slices.DeleteFunc(m.exports, func(e database.DataExport) bool { return e.UserID == id })
+
+	var reports []uuid.UUID
+	m.reports = slices.DeleteFunc(m.reports, func(r database.Report) bool {
+		if r.UserID == id {
+			reports = append(reports, r.ID)
+			return true
+		}
+		return false
+	})
+	for i := range m.reports {
+		unlink(&m.reports[i].ReporterID, id)
+		unlink(&m.reports[i].ResolvedBy, id)
+	}
+	m.actions = slices.DeleteFunc(m.actions, func(a database.ModerationAction) bool { return a.TargetUserID == id })
+	for i := range m.actions {
+		a := &m.actions[i]
+		unlink(&a.ModeratorID, id)
+		if a.ReportID.Valid && slices.Contains(reports, a.ReportID.UUID) {
+			a.ReportID = uuid.NullUUID{}
+		}
+	}
+	return 1, nil
+}
+
+// unlink clears ref if it points at id, as ON DELETE SET NULL does.
+func unlink(ref *uuid.NullUUID, id uuid.UUID) {
+	if ref.Valid && ref.UUID == id {
+		*ref = uuid.NullUUID{}
+	}
+}
+
+// DeleteAllUsers empties everything but the banned words and the audit log,
+// which is what the cascades from users do in Postgres.
+func (m *Memory) DeleteAllUsers(ctx context.Context) (int64, error) {
+	defer m.lock()()
+	n := len(m.users)
+	m.users, m.chirps, m.follows, m.tokens, m.reports, m.actions = nil, nil, nil, nil, nil, nil
+	m.deletions, m.exports = nil, nil
+	return int64(n), nil
+}
+
+func (m *Memory) SeedUser(ctx context.Context, arg database.SeedUserParams) (int64, error) {
+	defer m.lock()()
+	if find(m.users, func(u database.User) bool { return u.ID == arg.ID || u.Email == arg.Email }) != nil {
+		return 0, nil
+	}
+	m.users = insertByTime(m.users, database.User{
+		ID:             arg.ID,
+		CreatedAt:      arg.CreatedAt,
+		UpdatedAt:      arg.CreatedAt,
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+		IsChirpyRed:    arg.IsChirpyRed,
+		Role:           "user",
+		Status:         "active",
+	}, func(u database.User) time.Time { return u.CreatedAt })
+	return 1, nil
+}
+
+func (m *Memory) CreateChirpForUser(ctx context.Context, arg database.CreateChirpForUserParams) (database.Chirp, error) {
+	defer m.lock()()
+	now := time.Now().UTC()
+	c := database.Chirp{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Body: arg.Body, UserID: arg.UserID}
+	m.chirps = append(m.chirps, c)
+	return c, nil
+}
+
+func (m *Memory) GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	defer m.lock()()
+	if c := find(m.chirps, func(c database.Chirp) bool { return c.ID == id }); c != nil {
+		return *c, nil
+	}
+	return database.Chirp{}, sql.ErrNoRows
+}
+
+// visibleChirps lists the chirps viewer may see, by author if one is given,
+// in the order asked for.
+func (m *Memory) visibleChirps(author uuid.NullUUID, viewer uuid.NullUUID, sort string) []database.Chirp {
+	defer m.lock()()
+	out := filter(m.chirps, func(c database.Chirp) bool {
+		if c.HiddenAt.Valid || (author.Valid && c.UserID != author.UUID) {
+			return false
+		}
+		if viewer.Valid && viewer.UUID == c.UserID {
+			return true
+		}
+		u := m.user(c.UserID)
+		return u != nil && u.Status != "shadow_banned"
+	})
+	if sort == "desc" {
+		slices.Reverse(out)
+	}
+	return out
+}
+
+func (m *Memory) GetChirps(ctx context.Context, viewerID uuid.NullUUID) ([]database.Chirp, error) {
+	return m.visibleChirps(uuid.NullUUID{}, viewerID, "asc"), nil
+}
+
+func (m *Memory) GetChirpsForAuthor(ctx context.Context, arg database.GetChirpsForAuthorParams) ([]database.Chirp, error) {
+	return m.visibleChirps(uuid.NullUUID{UUID: arg.UserID, Valid: true}, arg.ViewerID, "asc"), nil
+}
+
+func (m *Memory) GetSortedChirps(ctx context.Context, arg database.GetSortedChirpsParams) ([]database.Chirp, error) {
+	return m.visibleChirps(uuid.NullUUID{}, arg.ViewerID, arg.Sort), nil
+}
+
+func (m *Memory) GetSortedChirpsForAuthor(ctx context.Context, arg database.GetSortedChirpsForAuthorParams) ([]database.Chirp, error) {
+	return m.visibleChirps(uuid.NullUUID{UUID: arg.UserID, Valid: true}, arg.ViewerID, arg.Sort), nil
+}
+
+func (m *Memory) GetRecentChirpBodiesForUser(ctx context.Context, arg database.GetRecentChirpBodiesForUserParams) ([]string, error) {
+	defer m.lock()()
+	var bodies []string
+	for i := len(m.chirps) - 1; i >= 0 && len(bodies) < 50; i-- {
+		if c := m.chirps[i]; c.UserID == arg.UserID && c.CreatedAt.After(arg.CreatedAt) {
+			bodies = append(bodies, c.Body)
+		}
+	}
+	return bodies, nil
+}
+
+// deleteChirps removes the chirps matching and unlinks the reports on them,
+// counting the chirps removed. m.mu must be held.
+func (m *Memory) deleteChirps(match func(database.Chirp) bool) int64 {
+	n := len(m.chirps)
+	m.chirps = slices.DeleteFunc(m.chirps, func(c database.Chirp) bool {
+		if !match(c) {
+			return false
+		}
+		for i := range m.reports {
+			if r := &m.reports[i]; r.ChirpID.Valid && r.ChirpID.UUID == c.ID {
+				r.ChirpID = uuid.NullUUID{}
+			}
+		}
+		return true
+	})
+	return int64(n - len(m.chirps))
+}
+
+func (m *Memory) DeleteChirp(ctx context.Context, arg database.DeleteChirpParams) error {
+	defer m.lock()()
+	m.deleteChirps(func(c database.Chirp) bool { return c.ID == arg.ID && c.UserID == arg.UserID })
+	return nil
+}
+
+func (m *Memory) DeleteChirpsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	defer m.lock()()
+	return m.deleteChirps(func(c database.Chirp) bool { return c.UserID == userID }), nil
+}
+
+func (m *Memory) DeleteAllChirps(ctx context.Context) (int64, error) {
+	defer m.lock()()
+	return m.deleteChirps(func(database.Chirp) bool { return true }), nil
+}
+
+func (m *Memory) HideChirp(ctx context.Context, id uuid.UUID) error {
+	defer m.lock()()
+	if c := find(m.chirps, func(c database.Chirp) bool { return c.ID == id }); c != nil {
+		now := time.Now().UTC()
+		c.HiddenAt = sql.NullTime{Time: now, Valid: true}
+		c.UpdatedAt = now
+	}
+	return nil
+}
+
+func (m *Memory) SeedChirp(ctx context.Context, arg database.SeedChirpParams) (int64, error) {
+	defer m.lock()()
+	if find(m.chirps, func(c database.Chirp) bool { return c.ID == arg.ID }) != nil {
+		return 0, nil
+	}
+	m.chirps = insertByTime(m.chirps, database.Chirp{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.CreatedAt,
+		Body:      arg.Body,
+		UserID:    arg.UserID,
+	}, func(c database.Chirp) time.Time { return c.CreatedAt })
+	return 1, nil
+}
+
+func (m *Memory) CreateFollow(ctx context.Context, arg database.CreateFollowParams) (int64, error) {
+	defer m.lock()()
+	if find(m.follows, func(f database.Follow) bool {
+		return f.FollowerID == arg.FollowerID && f.FolloweeID == arg.FolloweeID
+	}) != nil {
+		return 0, nil
+	}
+	m.follows = insertByTime(m.follows, database.Follow{
+		FollowerID: arg.FollowerID,
+		FolloweeID: arg.FolloweeID,
+		CreatedAt:  arg.CreatedAt,
+	}, func(f database.Follow) time.Time { return f.CreatedAt })
+	return 1, nil
+}
+
+func (m *Memory) ListFollowees(ctx context.Context, followerID uuid.UUID) ([]uuid.UUID, error) {
+	defer m.lock()()
+	follows := filter(m.follows, func(f database.Follow) bool { return f.FollowerID == followerID })
+	slices.SortStableFunc(follows, func(a, b database.Follow) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), bytes.Compare(a.FolloweeID[:], b.FolloweeID[:]))
+	})
+	var out []uuid.UUID
+	for _, f := range follows {
+		out = append(out, f.FolloweeID)
+	}
+	return out, nil
+}
+
+func (m *Memory) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) error {
+	defer m.lock()()
+	if find(m.tokens, func(t database.RefreshToken) bool { return t.Token == arg.Token }) != nil {
+		return ErrConflict
+	}
+	now := time.Now().UTC()
+	m.tokens = append(m.tokens, database.RefreshToken{
+		Token:     arg.Token,
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    arg.UserID,
+		ExpiresAt: now.Add(refreshTokenTTL),
+	})
+	return nil
+}
+
+func (m *Memory) GetUserFromRefreshToken(ctx context.Context, token string) (database.GetUserFromRefreshTokenRow, error) {
+	defer m.lock()()
+	if t := find(m.tokens, func(t database.RefreshToken) bool { return t.Token == token }); t != nil {
+		return database.GetUserFromRefreshTokenRow{UserID: t.UserID, ExpiresAt: t.ExpiresAt, RevokedAt: t.RevokedAt}, nil
+	}
+	return database.GetUserFromRefreshTokenRow{}, sql.ErrNoRows
+}
+
+func (m *Memory) RevokeRefreshToken(ctx context.Context, arg database.RevokeRefreshTokenParams) error {
+	defer m.lock()()
+	if t := find(m.tokens, func(t database.RefreshToken) bool { return t.Token == arg.Token }); t != nil {
+		t.RevokedAt = arg.RevokedAt
+		t.UpdatedAt = arg.UpdatedAt
+	}
+	return nil
+}
+
+func (m *Memory) RevokeAllRefreshTokensForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	defer m.lock()()
+	var n int64
+	now := time.Now().UTC()
+	for i := range m.tokens {
+		if t := &m.tokens[i]; t.UserID == userID && !t.RevokedAt.Valid {
+			t.RevokedAt = sql.NullTime{Time: now, Valid: true}
+			t.UpdatedAt = now
+			n++
+		}
+	}
+	return n, nil
+}
+
+// deleteTokens removes the refresh tokens matching, counting them.
+func (m *Memory) deleteTokens(match func(database.RefreshToken) bool) int64 {
+	defer m.lock()()
+	n := len(m.tokens)
+	m.tokens = slices.DeleteFunc(m.tokens, match)
+	return int64(n - len(m.tokens))
+}
+
+func (m *Memory) DeleteRefreshTokensForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return m.deleteTokens(func(t database.RefreshToken) bool { return t.UserID == userID }), nil
+}
+
+func (m *Memory) DeleteAllRefreshTokens(ctx context.Context) (int64, error) {
+	return m.deleteTokens(func(database.RefreshToken) bool { return true }), nil
+}
+
+func (m *Memory) CreateReport(ctx context.Context, arg database.CreateReportParams) (database.Report, error) {
+	defer m.lock()()
+	now := time.Now().UTC()
+	r := database.Report{
+		ID:         uuid.New(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		ReporterID: arg.ReporterID,
+		TargetType: arg.TargetType,
+		ChirpID:    arg.ChirpID,
+		UserID:     arg.UserID,
+		Reason:     arg.Reason,
+		Details:    arg.Details,
+		Status:     "open",
+	}
+	m.reports = append(m.reports, r)
+	return r, nil
+}
+
+func (m *Memory) GetReport(ctx context.Context, id uuid.UUID) (database.Report, error) {
+	defer m.lock()()
+	if r := find(m.reports, func(r database.Report) bool { return r.ID == id }); r != nil {
+		return *r, nil
+	}
+	return database.Report{}, sql.ErrNoRows
+}
+
+func (m *Memory) ListReportsByStatus(ctx context.Context, status string) ([]database.Report, error) {
+	defer m.lock()()
+	return filter(m.reports, func(r database.Report) bool { return r.Status == status }), nil
+}
+
+func (m *Memory) ListReportsForReporter(ctx context.Context, reporterID uuid.NullUUID) ([]database.Report, error) {
+	defer m.lock()()
+	out := filter(m.reports, func(r database.Report) bool {
+		return reporterID.Valid && r.ReporterID.Valid && r.ReporterID.UUID == reporterID.UUID
+	})
+	slices.Reverse(out)
+	return out, nil
+}
+
+func (m *Memory) ResolveReport(ctx context.Context, arg database.ResolveReportParams) (database.Report, error) {
+	defer m.lock()()
+	r := find(m.reports, func(r database.Report) bool { return r.ID == arg.ID && r.Status == "open" })
+	if r == nil {
+		return database.Report{}, sql.ErrNoRows
+	}
+	now := time.Now().UTC()
+	r.Status = arg.Status
+	r.ResolvedBy = arg.ResolvedBy
+	r.Resolution = arg.Resolution
+	r.ResolvedAt = sql.NullTime{Time: now, Valid: true}
+	r.UpdatedAt = now
+	return *r, nil
+}
+
+func (m *Memory) CreateModerationAction(ctx context.Context, arg database.CreateModerationActionParams) (database.ModerationAction, error) {
+	defer m.lock()()
+	a := database.ModerationAction{
+		ID:            uuid.New(),
+		CreatedAt:     time.Now().UTC(),
+		ReportID:      arg.ReportID,
+		ModeratorID:   arg.ModeratorID,
+		Action:        arg.Action,
+		TargetUserID:  arg.TargetUserID,
+		TargetChirpID: arg.TargetChirpID,
+		Reason:        arg.Reason,
+		ExpiresAt:     arg.ExpiresAt,
+	}
+	m.actions = append(m.actions, a)
+	return a, nil
+}
+
+func (m *Memory) ListModerationActionsForUser(ctx context.Context, targetUserID uuid.UUID) ([]database.ModerationAction, error) {
+	defer m.lock()()
+	out := filter(m.actions, func(a database.ModerationAction) bool { return a.TargetUserID == targetUserID })
+	slices.Reverse(out)
+	return out, nil
+}
+
+func (m *Memory) ListBannedWords(ctx context.Context) ([]database.BannedWord, error) {
+	defer m.lock()()
+	out := slices.Clone(m.words)
+	slices.SortFunc(out, func(a, b database.BannedWord) int { return strings.Compare(a.Word, b.Word) })
+	return out, nil
+}
+
+func (m *Memory) UpsertBannedWord(ctx context.Context, arg database.UpsertBannedWordParams) (database.BannedWord, error) {
+	defer m.lock()()
+	now := time.Now().UTC()
+	if w := find(m.words, func(w database.BannedWord) bool { return w.Word == arg.Word }); w != nil {
+		w.Action = arg.Action
+		w.UpdatedAt = now
+		return *w, nil
+	}
+	w := database.BannedWord{Word: arg.Word, Action: arg.Action, CreatedAt: now, UpdatedAt: now}
+	m.words = append(m.words, w)
+	return w, nil
+}
+
+func (m *Memory) DeleteBannedWord(ctx context.Context, word string) (int64, error) {
+	defer m.lock()()
+	n := len(m.words)
+	m.words = slices.DeleteFunc(m.words, func(w database.BannedWord) bool { return w.Word == word })
+	return int64(n - len(m.words)), nil
+}
+
+func (m *Memory) CreateAuditEvent(ctx context.Context, arg database.CreateAuditEventParams) (database.AuditEvent, error) {
+	defer m.lock()()
+	e := database.AuditEvent{
+		ID:         uuid.New(),
+		CreatedAt:  time.Now().UTC(),
+		Action:     arg.Action,
+		ActorID:    arg.ActorID,
+		TargetType: arg.TargetType,
+		TargetID:   arg.TargetID,
+		Ip:         arg.Ip,
+		UserAgent:  arg.UserAgent,
+		RequestID:  arg.RequestID,
+		Details:    arg.Details,
+	}
+	if e.Details == nil {
+		e.Details = json.RawMessage("{}")
+	}
+	m.audit = append(m.audit, e)
+	return e, nil
+}
+
+func (m *Memory) ListAuditEvents(ctx context.Context, arg database.ListAuditEventsParams) ([]database.AuditEvent, error) {
+	defer m.lock()()
+	// newer orders a before b if it sorts later by (created_at, id).
+	newer := func(a, b database.AuditEvent) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), bytes.Compare(b.ID[:], a.ID[:]))
+	}
+	cursor := database.AuditEvent{CreatedAt: arg.BeforeCreatedAt.Time, ID: arg.BeforeID.UUID}
+	out := filter(m.audit, func(e database.AuditEvent) bool {
+		return (!arg.Action.Valid || e.Action == arg.Action.String) &&
+			(!arg.ActorID.Valid || e.ActorID == arg.ActorID) &&
+			(!arg.TargetType.Valid || e.TargetType == arg.TargetType.String) &&
+			(!arg.TargetID.Valid || e.TargetID == arg.TargetID.String) &&
+			(!arg.Since.Valid || !e.CreatedAt.Before(arg.Since.Time)) &&
+			(!arg.Until.Valid || e.CreatedAt.Before(arg.Until.Time)) &&
+			(!arg.BeforeCreatedAt.Valid || newer(cursor, e) < 0)
+	})
+	slices.SortFunc(out, newer)
+	if len(out) > int(arg.Limit) {
+		out = out[:arg.Limit]
+	}
+	return out, nil
+}
+
+func (m *Memory) ListChirpsForUser(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	defer m.lock()()
+	return filter(m.chirps, func(c database.Chirp) bool { return c.UserID == userID }), nil
+}
+
+func (m *Memory) ListRefreshTokensForUser(ctx context.Context, userID uuid.UUID) ([]database.RefreshToken, error) {
+	defer m.lock()()
+	out := filter(m.tokens, func(t database.RefreshToken) bool { return t.UserID == userID })
+	slices.Reverse(out)
+	return out, nil
+}
+
+func (m *Memory) ScheduleAccountDeletion(ctx context.Context, arg database.ScheduleAccountDeletionParams) (database.AccountDeletion, error) {
+	defer m.lock()()
+	d := database.AccountDeletion{UserID: arg.UserID, RequestedAt: time.Now().UTC(), DeleteAfter: arg.DeleteAfter}
+	if existing := find(m.deletions, func(d database.AccountDeletion) bool { return d.UserID == arg.UserID }); existing != nil {
+		*existing = d
+		return d, nil
+	}
+	m.deletions = append(m.deletions, d)
+	return d, nil
+}
+
+func (m *Memory) GetAccountDeletion(ctx context.Context, userID uuid.UUID) (database.AccountDeletion, error) {
+	defer m.lock()()
+	if d := find(m.deletions, func(d database.AccountDeletion) bool { return d.UserID == userID }); d != nil {
+		return *d, nil
+	}
+	return database.AccountDeletion{}, sql.ErrNoRows
+}
+
+func (m *Memory) CancelAccountDeletion(ctx context.Context, userID uuid.UUID) (int64, error) {
+	defer m.lock()()
+	n := len(m.deletions)
+	m.deletions = slices.DeleteFunc(m.deletions, func(d database.AccountDeletion) bool { return d.UserID == userID })
+	return int64(n - len(m.deletions)), nil
+}
+
+func (m *Memory) ListDueAccountDeletions(ctx context.Context, arg database.ListDueAccountDeletionsParams) ([]database.AccountDeletion, error) {
+	defer m.lock()()
+	out := filter(m.deletions, func(d database.AccountDeletion) bool { return !d.DeleteAfter.After(arg.DeleteAfter) })
+	slices.SortStableFunc(out, func(a, b database.AccountDeletion) int { return a.DeleteAfter.Compare(b.DeleteAfter) })
+	if len(out) > int(arg.Limit) {
+		out = out[:arg.Limit]
+	}
+	return out, nil
+}
+
+func (m *Memory) CreateDataExport(ctx context.Context, userID uuid.UUID) (database.DataExport, error) {
+	defer m.lock()()
+	e := database.DataExport{ID: uuid.New(), UserID: userID, Status: "pending", CreatedAt: time.Now().UTC()}
+	m.exports = append(m.exports, e)
+	return e, nil
+}
+
+func (m *Memory) GetDataExport(ctx context.Context, id uuid.UUID) (database.DataExport, error) {
+	defer m.lock()()
+	if e := find(m.exports, func(e database.DataExport) bool { return e.ID == id }); e != nil {
+		return *e, nil
+	}
+	return database.DataExport{}, sql.ErrNoRows
+}
+
+func (m *Memory) GetPendingDataExportForUser(ctx context.Context, userID uuid.UUID) (database.DataExport, error) {
+	defer m.lock()()
+	for _, e := range slices.Backward(m.exports) {
+		if e.UserID == userID && e.Status == "pending" {
+			return e, nil
+		}
+	}
+	return database.DataExport{}, sql.ErrNoRows
+}
+
+func (m *Memory) ListPendingDataExports(ctx context.Context, limit int32) ([]database.DataExport, error) {
+	defer m.lock()()
+	out := filter(m.exports, func(e database.DataExport) bool { return e.Status == "pending" })
+	if len(out) > int(limit) {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (m *Memory) FinishDataExport(ctx context.Context, arg database.FinishDataExportParams) (int64, error) {
+	defer m.lock()()
+	e := find(m.exports, func(e database.DataExport) bool { return e.ID == arg.ID && e.Status == "pending" })
+	if e == nil {
+		return 0, nil
+	}
+	e.Status = arg.Status
+	e.CompletedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	e.ExpiresAt = arg.ExpiresAt
+	e.Archive = arg.Archive
+	e.Error = arg.Error
+	return 1, nil
+}
+
+func (m *Memory) DeleteExpiredDataExports(ctx context.Context, expiresAt sql.NullTime) (int64, error) {
+	defer m.lock()()
+	n := len(m.exports)
+	m.exports = slices.DeleteFunc(m.exports, func(e database.DataExport) bool {
+		return expiresAt.Valid && e.ExpiresAt.Valid && !e.ExpiresAt.Time.After(expiresAt.Time)
+	})
+	return int64(n - len(m.exports)), nil
+}
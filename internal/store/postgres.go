@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+)
+
+// Postgres is the production store: the sqlc queries, with Postgres's
+// unique violations reported as ErrConflict.
+type Postgres struct {
+	*database.Queries
+	db         *sql.DB
+	instrument func(database.DBTX) database.DBTX
+}
+
+// NewPostgres returns a store querying db. Queries, including those in
+// transactions, go through instrument if it isn't nil. Closing the store
+// closes db.
+func NewPostgres(db *sql.DB, instrument func(database.DBTX) database.DBTX) *Postgres {
+	if instrument == nil {
+		instrument = func(db database.DBTX) database.DBTX { return db }
+	}
+	return &Postgres{Queries: database.New(instrument(db)), db: db, instrument: instrument}
+}
+
+func (p *Postgres) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.CreateUserRow, error) {
+	user, err := p.Queries.CreateUser(ctx, arg)
+	return user, pqConflict(err)
+}
+
+func (p *Postgres) EditUser(ctx context.Context, arg database.EditUserParams) (string, error) {
+	email, err := p.Queries.EditUser(ctx, arg)
+	return email, pqConflict(err)
+}
+
+// CreateAuditEvent records an event, with no details as an empty object.
+func (p *Postgres) CreateAuditEvent(ctx context.Context, arg database.CreateAuditEventParams) (database.AuditEvent, error) {
+	if len(arg.Details) == 0 {
+		arg.Details = json.RawMessage("{}")
+	}
+	return p.Queries.CreateAuditEvent(ctx, arg)
+}
+
+func (p *Postgres) Ping(ctx context.Context) error { return p.db.PingContext(ctx) }
+
+func (p *Postgres) Close() error { return p.db.Close() }
+
+// DB returns the database the store queries.
+func (p *Postgres) DB() *sql.DB { return p.db }
+
+// InTx runs fn at serializable isolation, so that whatever fn read still
+// holds when it commits; if it doesn't, Postgres aborts the transaction and
+// InTx returns ErrSerialization. The queries are built over the instrumented
+// transaction rather than with Queries.WithTx, which would bypass
+// instrument.
+func (p *Postgres) InTx(ctx context.Context, fn func(tx Tx) error) error {
+	tx, err := p.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	if err := fn(&Postgres{Queries: database.New(p.instrument(tx)), instrument: p.instrument}); err != nil {
+		tx.Rollback()
+		return pqSerialization(err)
+	}
+	return pqSerialization(tx.Commit())
+}
+
+// pqConflict turns a unique violation into ErrConflict.
+func pqConflict(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrConflict
+	}
+	return err
+}
+
+// pqSerialization reports a serialization failure or deadlock as
+// ErrSerialization.
+func pqSerialization(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && (pqErr.Code == "40001" || pqErr.Code == "40P01") {
+		return fmt.Errorf("%w: %w", ErrSerialization, err)
+	}
+	return err
+}
@@ -0,0 +1,799 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+)
+
+//go:embed sqlite_schema.sql
+var sqliteSchema string
+
+// sqliteUpgrades bring the schema of a database made by an older build up to
+// date: sqliteUpgrades[i] takes it from version i+1 to i+2.
+var sqliteUpgrades = []string{`
+	CREATE TABLE follows (
+		follower_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		followee_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		created_at INTEGER NOT NULL,
+		PRIMARY KEY (follower_id, followee_id),
+		CHECK (follower_id <> followee_id)
+	);
+	CREATE INDEX follows_followee_id_idx ON follows (followee_id);`, `
+	CREATE TABLE audit_events (
+		id TEXT PRIMARY KEY,
+		created_at INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		actor_id TEXT,
+		target_type TEXT NOT NULL DEFAULT '',
+		target_id TEXT NOT NULL DEFAULT '',
+		ip TEXT NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		request_id TEXT NOT NULL DEFAULT '',
+		details TEXT NOT NULL DEFAULT '{}'
+	);
+	CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);`, `
+	CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+	BEGIN SELECT RAISE(ABORT, 'audit_events is append-only'); END;
+	CREATE TRIGGER audit_events_no_delete BEFORE DELETE ON audit_events
+	BEGIN SELECT RAISE(ABORT, 'audit_events is append-only'); END;
+	CREATE INDEX audit_events_actor_id_idx ON audit_events (actor_id, created_at);
+	CREATE INDEX audit_events_target_idx ON audit_events (target_type, target_id, created_at);`, `
+	CREATE TABLE account_deletions (
+		user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		requested_at INTEGER NOT NULL,
+		delete_after INTEGER NOT NULL
+	);
+	CREATE INDEX account_deletions_delete_after_idx ON account_deletions (delete_after);
+	CREATE TABLE data_exports (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+		created_at INTEGER NOT NULL,
+		completed_at INTEGER,
+		expires_at INTEGER,
+		archive BLOB,
+		error TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX data_exports_user_id_idx ON data_exports (user_id, created_at);
+	CREATE INDEX data_exports_status_idx ON data_exports (status, created_at);`,
+}
+
+// sqliteSchemaVersion is recorded in the database's user_version once the
+// schema has been created or upgraded. sqlite_schema.sql is version 1.
+var sqliteSchemaVersion = 1 + len(sqliteUpgrades)
+
+// SQLite is a store in a single SQLite file, for running the server without
+// Postgres.
+type SQLite struct {
+	db *sql.DB
+	// conn runs the queries: db, or the transaction in InTx.
+	conn database.DBTX
+}
+
+// OpenSQLite opens the database file at path, creating it and its schema if
+// it doesn't exist yet.
+func OpenSQLite(ctx context.Context, path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	// One connection serializes writers, which SQLite would otherwise make
+	// wait on the file lock, and lets ":memory:" mean one database.
+	db.SetMaxOpenConns(1)
+
+	s := &SQLite{db: db, conn: db}
+	if err := s.init(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// init creates the schema in a new database and upgrades it in one made by
+// an older build. A database made by a newer build is refused rather than
+// guessed at.
+func (s *SQLite) init(ctx context.Context) error {
+	var version int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	switch {
+	case version == sqliteSchemaVersion:
+		return nil
+	case version > sqliteSchemaVersion:
+		return fmt.Errorf("schema is at version %d, this build knows %d", version, sqliteSchemaVersion)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if version == 0 {
+		if _, err := tx.ExecContext(ctx, sqliteSchema); err != nil {
+			return err
+		}
+		now := time.Now().UnixMicro()
+		for _, w := range defaultBannedWords {
+			if _, err := tx.ExecContext(ctx,
+				"INSERT INTO banned_words (word, action, created_at, updated_at) VALUES (?, 'mask', ?, ?)",
+				w, now, now,
+			); err != nil {
+				return err
+			}
+		}
+		version = 1
+	}
+	for _, upgrade := range sqliteUpgrades[version-1:] {
+		if _, err := tx.ExecContext(ctx, upgrade); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", sqliteSchemaVersion)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLite) Ping(ctx context.Context) error { return s.db.PingContext(ctx) }
+
+func (s *SQLite) Close() error { return s.db.Close() }
+
+// InTx runs fn in a transaction. The store has a single connection, so the
+// transaction has the database to itself; only another process writing the
+// same file can make it fail with ErrSerialization.
+func (s *SQLite) InTx(ctx context.Context, fn func(tx Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return sqliteBusy(err)
+	}
+	if err := fn(&SQLite{db: s.db, conn: tx}); err != nil {
+		tx.Rollback()
+		return sqliteBusy(err)
+	}
+	return sqliteBusy(tx.Commit())
+}
+
+// sqliteConflict turns a unique or primary key violation into ErrConflict.
+func sqliteConflict(err error) error {
+	var e *sqlite.Error
+	if errors.As(err, &e) && (e.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || e.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY) {
+		return ErrConflict
+	}
+	return err
+}
+
+// sqliteBusy reports a database locked by another connection as
+// ErrSerialization.
+func sqliteBusy(err error) error {
+	var e *sqlite.Error
+	if errors.As(err, &e) && e.Code()&0xff == sqlite3.SQLITE_BUSY {
+		return fmt.Errorf("%w: %w", ErrSerialization, err)
+	}
+	return err
+}
+
+// micros scans a timestamp column into t.
+type micros struct{ t *time.Time }
+
+func (m micros) Scan(src any) error {
+	v, ok := src.(int64)
+	if !ok {
+		return fmt.Errorf("timestamp is %T, not an integer", src)
+	}
+	*m.t = time.UnixMicro(v).UTC()
+	return nil
+}
+
+// nullMicros scans a nullable timestamp column into t.
+type nullMicros struct{ t *sql.NullTime }
+
+func (m nullMicros) Scan(src any) error {
+	if src == nil {
+		*m.t = sql.NullTime{}
+		return nil
+	}
+	m.t.Valid = true
+	return micros{&m.t.Time}.Scan(src)
+}
+
+// nullTime is t as a nullable timestamp argument.
+func nullTime(t sql.NullTime) any {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time.UnixMicro()
+}
+
+// row is a *sql.Row or *sql.Rows.
+type row interface {
+	Scan(dest ...any) error
+}
+
+// queryAll runs query and scans every row it returns.
+func queryAll[T any](ctx context.Context, db database.DBTX, scan func(row) (T, error), query string, args ...any) ([]T, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []T
+	for rows.Next() {
+		i, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return items, rows.Err()
+}
+
+// execRows runs query and counts the rows it changed.
+func (s *SQLite) execRows(ctx context.Context, query string, args ...any) (int64, error) {
+	result, err := s.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const userColumns = "id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, status, suspended_until"
+
+func scanUser(r row) (database.User, error) {
+	var i database.User
+	err := r.Scan(
+		&i.ID,
+		micros{&i.CreatedAt},
+		micros{&i.UpdatedAt},
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.Status,
+		nullMicros{&i.SuspendedUntil},
+	)
+	return i, err
+}
+
+func (s *SQLite) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.CreateUserRow, error) {
+	now := time.Now().UnixMicro()
+	row := s.conn.QueryRowContext(ctx, `
+		INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id, created_at, updated_at, email, is_chirpy_red`,
+		uuid.New(), now, now, arg.Email, arg.HashedPassword,
+	)
+	var i database.CreateUserRow
+	err := row.Scan(&i.ID, micros{&i.CreatedAt}, micros{&i.UpdatedAt}, &i.Email, &i.IsChirpyRed)
+	return i, sqliteConflict(err)
+}
+
+func (s *SQLite) GetUser(ctx context.Context, email string) (database.User, error) {
+	return scanUser(s.conn.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE email = ?", email))
+}
+
+func (s *SQLite) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	return scanUser(s.conn.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = ?", id))
+}
+
+func (s *SQLite) ListUsers(ctx context.Context) ([]database.User, error) {
+	return queryAll(ctx, s.conn, scanUser, "SELECT "+userColumns+" FROM users ORDER BY created_at ASC, rowid ASC")
+}
+
+func (s *SQLite) EditUser(ctx context.Context, arg database.EditUserParams) (string, error) {
+	var email string
+	err := s.conn.QueryRowContext(ctx,
+		"UPDATE users SET email = ?, hashed_password = ?, updated_at = ? WHERE id = ? RETURNING email",
+		arg.Email, arg.HashedPassword, arg.UpdatedAt.UnixMicro(), arg.ID,
+	).Scan(&email)
+	return email, sqliteConflict(err)
+}
+
+func (s *SQLite) UpgradeUserToRed(ctx context.Context, id uuid.UUID) (int64, error) {
+	return s.execRows(ctx, "UPDATE users SET is_chirpy_red = TRUE WHERE id = ?", id)
+}
+
+func (s *SQLite) SetUserStatus(ctx context.Context, arg database.SetUserStatusParams) (int64, error) {
+	return s.execRows(ctx,
+		"UPDATE users SET status = ?, suspended_until = ?, updated_at = ? WHERE id = ?",
+		arg.Status, nullTime(arg.SuspendedUntil), time.Now().UnixMicro(), arg.ID,
+	)
+}
+
+func (s *SQLite) SetUserRole(ctx context.Context, arg database.SetUserRoleParams) (int64, error) {
+	return s.execRows(ctx, "UPDATE users SET role = ?, updated_at = ? WHERE id = ?", arg.Role, time.Now().UnixMicro(), arg.ID)
+}
+
+func (s *SQLite) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) (int64, error) {
+	return s.execRows(ctx,
+		"UPDATE users SET hashed_password = ?, updated_at = ? WHERE id = ?",
+		arg.HashedPassword, time.Now().UnixMicro(), arg.ID,
+	)
+}
+
+func (s *SQLite) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	return s.execRows(ctx, "DELETE FROM users WHERE id = ?", id)
+}
+
+func (s *SQLite) DeleteAllUsers(ctx context.Context) (int64, error) {
+	return s.execRows(ctx, "DELETE FROM users")
+}
+
+func (s *SQLite) SeedUser(ctx context.Context, arg database.SeedUserParams) (int64, error) {
+	return s.execRows(ctx, `
+		INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red)
+		VALUES (?1, ?2, ?2, ?3, ?4, ?5)
+		ON CONFLICT DO NOTHING`,
+		arg.ID, arg.CreatedAt.UnixMicro(), arg.Email, arg.HashedPassword, arg.IsChirpyRed,
+	)
+}
+
+const chirpColumns = "chirp.id, chirp.created_at, chirp.updated_at, chirp.body, chirp.user_id, chirp.hidden_at"
+
+func scanChirp(r row) (database.Chirp, error) {
+	var i database.Chirp
+	err := r.Scan(
+		&i.ID,
+		micros{&i.CreatedAt},
+		micros{&i.UpdatedAt},
+		&i.Body,
+		&i.UserID,
+		nullMicros{&i.HiddenAt},
+	)
+	return i, err
+}
+
+func (s *SQLite) CreateChirpForUser(ctx context.Context, arg database.CreateChirpForUserParams) (database.Chirp, error) {
+	now := time.Now().UnixMicro()
+	return scanChirp(s.conn.QueryRowContext(ctx, `
+		INSERT INTO chirp (id, created_at, updated_at, body, user_id)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id, created_at, updated_at, body, user_id, hidden_at`,
+		uuid.New(), now, now, arg.Body, arg.UserID,
+	))
+}
+
+func (s *SQLite) GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	return scanChirp(s.conn.QueryRowContext(ctx, "SELECT "+chirpColumns+" FROM chirp WHERE id = ?", id))
+}
+
+// visibleChirps lists the chirps viewer may see, by author if one is given,
+// in the order asked for.
+func (s *SQLite) visibleChirps(ctx context.Context, author, viewer uuid.NullUUID, sort string) ([]database.Chirp, error) {
+	sortDirection := "ASC"
+	if sort == "desc" {
+		sortDirection = "DESC"
+	}
+	return queryAll(ctx, s.conn, scanChirp, fmt.Sprintf(`
+		SELECT %s
+		FROM chirp
+		JOIN users ON users.id = chirp.user_id
+		WHERE chirp.hidden_at IS NULL
+			AND (?1 IS NULL OR chirp.user_id = ?1)
+			AND (users.status <> 'shadow_banned' OR chirp.user_id = ?2)
+		ORDER BY chirp.created_at %[2]s, chirp.rowid %[2]s`, chirpColumns, sortDirection),
+		author, viewer,
+	)
+}
+
+func (s *SQLite) GetChirps(ctx context.Context, viewerID uuid.NullUUID) ([]database.Chirp, error) {
+	return s.visibleChirps(ctx, uuid.NullUUID{}, viewerID, "asc")
+}
+
+func (s *SQLite) GetChirpsForAuthor(ctx context.Context, arg database.GetChirpsForAuthorParams) ([]database.Chirp, error) {
+	return s.visibleChirps(ctx, uuid.NullUUID{UUID: arg.UserID, Valid: true}, arg.ViewerID, "asc")
+}
+
+func (s *SQLite) GetSortedChirps(ctx context.Context, arg database.GetSortedChirpsParams) ([]database.Chirp, error) {
+	return s.visibleChirps(ctx, uuid.NullUUID{}, arg.ViewerID, arg.Sort)
+}
+
+func (s *SQLite) GetSortedChirpsForAuthor(ctx context.Context, arg database.GetSortedChirpsForAuthorParams) ([]database.Chirp, error) {
+	return s.visibleChirps(ctx, uuid.NullUUID{UUID: arg.UserID, Valid: true}, arg.ViewerID, arg.Sort)
+}
+
+func (s *SQLite) GetRecentChirpBodiesForUser(ctx context.Context, arg database.GetRecentChirpBodiesForUserParams) ([]string, error) {
+	return queryAll(ctx, s.conn, func(r row) (string, error) {
+		var body string
+		err := r.Scan(&body)
+		return body, err
+	}, `
+		SELECT body
+		FROM chirp
+		WHERE user_id = ? AND created_at > ?
+		ORDER BY created_at DESC, rowid DESC
+		LIMIT 50`,
+		arg.UserID, arg.CreatedAt.UnixMicro(),
+	)
+}
+
+func (s *SQLite) DeleteChirp(ctx context.Context, arg database.DeleteChirpParams) error {
+	_, err := s.conn.ExecContext(ctx, "DELETE FROM chirp WHERE user_id = ? AND id = ?", arg.UserID, arg.ID)
+	return err
+}
+
+func (s *SQLite) DeleteChirpsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.execRows(ctx, "DELETE FROM chirp WHERE user_id = ?", userID)
+}
+
+func (s *SQLite) DeleteAllChirps(ctx context.Context) (int64, error) {
+	return s.execRows(ctx, "DELETE FROM chirp")
+}
+
+func (s *SQLite) HideChirp(ctx context.Context, id uuid.UUID) error {
+	now := time.Now().UnixMicro()
+	_, err := s.conn.ExecContext(ctx, "UPDATE chirp SET hidden_at = ?, updated_at = ? WHERE id = ?", now, now, id)
+	return err
+}
+
+func (s *SQLite) ListChirpsForUser(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	return queryAll(ctx, s.conn, scanChirp,
+		"SELECT "+chirpColumns+" FROM chirp WHERE user_id = ? ORDER BY created_at ASC, rowid ASC", userID)
+}
+
+func (s *SQLite) SeedChirp(ctx context.Context, arg database.SeedChirpParams) (int64, error) {
+	return s.execRows(ctx, `
+		INSERT INTO chirp (id, created_at, updated_at, body, user_id)
+		VALUES (?1, ?2, ?2, ?3, ?4)
+		ON CONFLICT DO NOTHING`,
+		arg.ID, arg.CreatedAt.UnixMicro(), arg.Body, arg.UserID,
+	)
+}
+
+func (s *SQLite) CreateFollow(ctx context.Context, arg database.CreateFollowParams) (int64, error) {
+	return s.execRows(ctx,
+		"INSERT INTO follows (follower_id, followee_id, created_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING",
+		arg.FollowerID, arg.FolloweeID, arg.CreatedAt.UnixMicro(),
+	)
+}
+
+func (s *SQLite) ListFollowees(ctx context.Context, followerID uuid.UUID) ([]uuid.UUID, error) {
+	return queryAll(ctx, s.conn, func(r row) (uuid.UUID, error) {
+		var id uuid.UUID
+		err := r.Scan(&id)
+		return id, err
+	}, "SELECT followee_id FROM follows WHERE follower_id = ? ORDER BY created_at ASC, followee_id ASC", followerID)
+}
+
+func (s *SQLite) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) error {
+	now := time.Now()
+	_, err := s.conn.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		arg.Token, now.UnixMicro(), now.UnixMicro(), arg.UserID, now.Add(refreshTokenTTL).UnixMicro(),
+	)
+	return sqliteConflict(err)
+}
+
+func (s *SQLite) GetUserFromRefreshToken(ctx context.Context, token string) (database.GetUserFromRefreshTokenRow, error) {
+	var i database.GetUserFromRefreshTokenRow
+	err := s.conn.QueryRowContext(ctx,
+		"SELECT user_id, expires_at, revoked_at FROM refresh_tokens WHERE token = ?", token,
+	).Scan(&i.UserID, micros{&i.ExpiresAt}, nullMicros{&i.RevokedAt})
+	return i, err
+}
+
+func (s *SQLite) RevokeRefreshToken(ctx context.Context, arg database.RevokeRefreshTokenParams) error {
+	_, err := s.conn.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = ?, updated_at = ? WHERE token = ?",
+		nullTime(arg.RevokedAt), arg.UpdatedAt.UnixMicro(), arg.Token,
+	)
+	return err
+}
+
+func (s *SQLite) RevokeAllRefreshTokensForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	now := time.Now().UnixMicro()
+	return s.execRows(ctx,
+		"UPDATE refresh_tokens SET revoked_at = ?, updated_at = ? WHERE user_id = ? AND revoked_at IS NULL",
+		now, now, userID,
+	)
+}
+
+func (s *SQLite) DeleteRefreshTokensForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.execRows(ctx, "DELETE FROM refresh_tokens WHERE user_id = ?", userID)
+}
+
+func (s *SQLite) DeleteAllRefreshTokens(ctx context.Context) (int64, error) {
+	return s.execRows(ctx, "DELETE FROM refresh_tokens")
+}
+
+func scanRefreshToken(r row) (database.RefreshToken, error) {
+	var i database.RefreshToken
+	err := r.Scan(
+		&i.Token,
+		micros{&i.CreatedAt},
+		micros{&i.UpdatedAt},
+		&i.UserID,
+		micros{&i.ExpiresAt},
+		nullMicros{&i.RevokedAt},
+	)
+	return i, err
+}
+
+func (s *SQLite) ListRefreshTokensForUser(ctx context.Context, userID uuid.UUID) ([]database.RefreshToken, error) {
+	return queryAll(ctx, s.conn, scanRefreshToken, `
+		SELECT token, created_at, updated_at, user_id, expires_at, revoked_at
+		FROM refresh_tokens WHERE user_id = ? ORDER BY created_at DESC, rowid DESC`,
+		userID,
+	)
+}
+
+const reportColumns = "id, created_at, updated_at, reporter_id, target_type, chirp_id, user_id, reason, details, status, resolved_by, resolved_at, resolution"
+
+func scanReport(r row) (database.Report, error) {
+	var i database.Report
+	err := r.Scan(
+		&i.ID,
+		micros{&i.CreatedAt},
+		micros{&i.UpdatedAt},
+		&i.ReporterID,
+		&i.TargetType,
+		&i.ChirpID,
+		&i.UserID,
+		&i.Reason,
+		&i.Details,
+		&i.Status,
+		&i.ResolvedBy,
+		nullMicros{&i.ResolvedAt},
+		&i.Resolution,
+	)
+	return i, err
+}
+
+func (s *SQLite) CreateReport(ctx context.Context, arg database.CreateReportParams) (database.Report, error) {
+	now := time.Now().UnixMicro()
+	return scanReport(s.conn.QueryRowContext(ctx, `
+		INSERT INTO reports (id, created_at, updated_at, reporter_id, target_type, chirp_id, user_id, reason, details)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING `+reportColumns,
+		uuid.New(), now, now, arg.ReporterID, arg.TargetType, arg.ChirpID, arg.UserID, arg.Reason, arg.Details,
+	))
+}
+
+func (s *SQLite) GetReport(ctx context.Context, id uuid.UUID) (database.Report, error) {
+	return scanReport(s.conn.QueryRowContext(ctx, "SELECT "+reportColumns+" FROM reports WHERE id = ?", id))
+}
+
+func (s *SQLite) ListReportsByStatus(ctx context.Context, status string) ([]database.Report, error) {
+	return queryAll(ctx, s.conn, scanReport,
+		"SELECT "+reportColumns+" FROM reports WHERE status = ? ORDER BY created_at ASC, rowid ASC", status)
+}
+
+func (s *SQLite) ListReportsForReporter(ctx context.Context, reporterID uuid.NullUUID) ([]database.Report, error) {
+	return queryAll(ctx, s.conn, scanReport,
+		"SELECT "+reportColumns+" FROM reports WHERE reporter_id = ? ORDER BY created_at DESC, rowid DESC", reporterID)
+}
+
+func (s *SQLite) ResolveReport(ctx context.Context, arg database.ResolveReportParams) (database.Report, error) {
+	now := time.Now().UnixMicro()
+	return scanReport(s.conn.QueryRowContext(ctx, `
+		UPDATE reports
+		SET status = ?, resolved_by = ?, resolution = ?, resolved_at = ?, updated_at = ?
+		WHERE id = ? AND status = 'open'
+		RETURNING `+reportColumns,
+		arg.Status, arg.ResolvedBy, arg.Resolution, now, now, arg.ID,
+	))
+}
+
+const moderationActionColumns = "id, created_at, report_id, moderator_id, action, target_user_id, target_chirp_id, reason, expires_at"
+
+func scanModerationAction(r row) (database.ModerationAction, error) {
+	var i database.ModerationAction
+	err := r.Scan(
+		&i.ID,
+		micros{&i.CreatedAt},
+		&i.ReportID,
+		&i.ModeratorID,
+		&i.Action,
+		&i.TargetUserID,
+		&i.TargetChirpID,
+		&i.Reason,
+		nullMicros{&i.ExpiresAt},
+	)
+	return i, err
+}
+
+func (s *SQLite) CreateModerationAction(ctx context.Context, arg database.CreateModerationActionParams) (database.ModerationAction, error) {
+	return scanModerationAction(s.conn.QueryRowContext(ctx, `
+		INSERT INTO moderation_actions (id, created_at, report_id, moderator_id, action, target_user_id, target_chirp_id, reason, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING `+moderationActionColumns,
+		uuid.New(), time.Now().UnixMicro(), arg.ReportID, arg.ModeratorID, arg.Action,
+		arg.TargetUserID, arg.TargetChirpID, arg.Reason, nullTime(arg.ExpiresAt),
+	))
+}
+
+func (s *SQLite) ListModerationActionsForUser(ctx context.Context, targetUserID uuid.UUID) ([]database.ModerationAction, error) {
+	return queryAll(ctx, s.conn, scanModerationAction,
+		"SELECT "+moderationActionColumns+" FROM moderation_actions WHERE target_user_id = ? ORDER BY created_at DESC, rowid DESC",
+		targetUserID,
+	)
+}
+
+func scanBannedWord(r row) (database.BannedWord, error) {
+	var i database.BannedWord
+	err := r.Scan(&i.Word, &i.Action, micros{&i.CreatedAt}, micros{&i.UpdatedAt})
+	return i, err
+}
+
+func (s *SQLite) ListBannedWords(ctx context.Context) ([]database.BannedWord, error) {
+	return queryAll(ctx, s.conn, scanBannedWord,
+		"SELECT word, action, created_at, updated_at FROM banned_words ORDER BY word ASC")
+}
+
+func (s *SQLite) UpsertBannedWord(ctx context.Context, arg database.UpsertBannedWordParams) (database.BannedWord, error) {
+	now := time.Now().UnixMicro()
+	return scanBannedWord(s.conn.QueryRowContext(ctx, `
+		INSERT INTO banned_words (word, action, created_at, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (word) DO UPDATE
+		SET action = excluded.action, updated_at = excluded.updated_at
+		RETURNING word, action, created_at, updated_at`,
+		arg.Word, arg.Action, now, now,
+	))
+}
+
+func (s *SQLite) DeleteBannedWord(ctx context.Context, word string) (int64, error) {
+	return s.execRows(ctx, "DELETE FROM banned_words WHERE word = ?", word)
+}
+
+const auditEventColumns = "id, created_at, action, actor_id, target_type, target_id, ip, user_agent, request_id, details"
+
+func scanAuditEvent(r row) (database.AuditEvent, error) {
+	var i database.AuditEvent
+	var details string
+	err := r.Scan(
+		&i.ID,
+		micros{&i.CreatedAt},
+		&i.Action,
+		&i.ActorID,
+		&i.TargetType,
+		&i.TargetID,
+		&i.Ip,
+		&i.UserAgent,
+		&i.RequestID,
+		&details,
+	)
+	i.Details = json.RawMessage(details)
+	return i, err
+}
+
+func (s *SQLite) CreateAuditEvent(ctx context.Context, arg database.CreateAuditEventParams) (database.AuditEvent, error) {
+	details := string(arg.Details)
+	if details == "" {
+		details = "{}"
+	}
+	return scanAuditEvent(s.conn.QueryRowContext(ctx, `
+		INSERT INTO audit_events (id, created_at, action, actor_id, target_type, target_id, ip, user_agent, request_id, details)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING `+auditEventColumns,
+		uuid.New(), time.Now().UnixMicro(), arg.Action, arg.ActorID, arg.TargetType, arg.TargetID,
+		arg.Ip, arg.UserAgent, arg.RequestID, details,
+	))
+}
+
+func (s *SQLite) ListAuditEvents(ctx context.Context, arg database.ListAuditEventsParams) ([]database.AuditEvent, error) {
+	return queryAll(ctx, s.conn, scanAuditEvent, `
+		SELECT `+auditEventColumns+` FROM audit_events
+		WHERE (?1 IS NULL OR action = ?1)
+			AND (?2 IS NULL OR actor_id = ?2)
+			AND (?3 IS NULL OR target_type = ?3)
+			AND (?4 IS NULL OR target_id = ?4)
+			AND (?5 IS NULL OR created_at >= ?5)
+			AND (?6 IS NULL OR created_at < ?6)
+			AND (?7 IS NULL OR (created_at, id) < (?7, ?8))
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?9`,
+		arg.Action, arg.ActorID, arg.TargetType, arg.TargetID,
+		nullTime(arg.Since), nullTime(arg.Until), nullTime(arg.BeforeCreatedAt), arg.BeforeID, arg.Limit,
+	)
+}
+
+func scanAccountDeletion(r row) (database.AccountDeletion, error) {
+	var i database.AccountDeletion
+	err := r.Scan(&i.UserID, micros{&i.RequestedAt}, micros{&i.DeleteAfter})
+	return i, err
+}
+
+func (s *SQLite) ScheduleAccountDeletion(ctx context.Context, arg database.ScheduleAccountDeletionParams) (database.AccountDeletion, error) {
+	return scanAccountDeletion(s.conn.QueryRowContext(ctx, `
+		INSERT INTO account_deletions (user_id, requested_at, delete_after)
+		VALUES (?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE
+		SET requested_at = excluded.requested_at, delete_after = excluded.delete_after
+		RETURNING user_id, requested_at, delete_after`,
+		arg.UserID, time.Now().UnixMicro(), arg.DeleteAfter.UnixMicro(),
+	))
+}
+
+func (s *SQLite) GetAccountDeletion(ctx context.Context, userID uuid.UUID) (database.AccountDeletion, error) {
+	return scanAccountDeletion(s.conn.QueryRowContext(ctx,
+		"SELECT user_id, requested_at, delete_after FROM account_deletions WHERE user_id = ?", userID))
+}
+
+func (s *SQLite) CancelAccountDeletion(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.execRows(ctx, "DELETE FROM account_deletions WHERE user_id = ?", userID)
+}
+
+func (s *SQLite) ListDueAccountDeletions(ctx context.Context, arg database.ListDueAccountDeletionsParams) ([]database.AccountDeletion, error) {
+	return queryAll(ctx, s.conn, scanAccountDeletion, `
+		SELECT user_id, requested_at, delete_after FROM account_deletions
+		WHERE delete_after <= ? ORDER BY delete_after ASC LIMIT ?`,
+		arg.DeleteAfter.UnixMicro(), arg.Limit,
+	)
+}
+
+const dataExportColumns = "id, user_id, status, created_at, completed_at, expires_at, archive, error"
+
+func scanDataExport(r row) (database.DataExport, error) {
+	var i database.DataExport
+	err := r.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Status,
+		micros{&i.CreatedAt},
+		nullMicros{&i.CompletedAt},
+		nullMicros{&i.ExpiresAt},
+		&i.Archive,
+		&i.Error,
+	)
+	return i, err
+}
+
+func (s *SQLite) CreateDataExport(ctx context.Context, userID uuid.UUID) (database.DataExport, error) {
+	return scanDataExport(s.conn.QueryRowContext(ctx, `
+		INSERT INTO data_exports (id, user_id, status, created_at)
+		VALUES (?, ?, 'pending', ?)
+		RETURNING `+dataExportColumns,
+		uuid.New(), userID, time.Now().UnixMicro(),
+	))
+}
+
+func (s *SQLite) GetDataExport(ctx context.Context, id uuid.UUID) (database.DataExport, error) {
+	return scanDataExport(s.conn.QueryRowContext(ctx,
+		"SELECT "+dataExportColumns+" FROM data_exports WHERE id = ?", id))
+}
+
+func (s *SQLite) GetPendingDataExportForUser(ctx context.Context, userID uuid.UUID) (database.DataExport, error) {
+	return scanDataExport(s.conn.QueryRowContext(ctx, `
+		SELECT `+dataExportColumns+` FROM data_exports
+		WHERE user_id = ? AND status = 'pending'
+		ORDER BY created_at DESC, rowid DESC LIMIT 1`,
+		userID,
+	))
+}
+
+func (s *SQLite) ListPendingDataExports(ctx context.Context, limit int32) ([]database.DataExport, error) {
+	return queryAll(ctx, s.conn, scanDataExport, `
+		SELECT `+dataExportColumns+` FROM data_exports
+		WHERE status = 'pending' ORDER BY created_at ASC, rowid ASC LIMIT ?`,
+		limit,
+	)
+}
+
+func (s *SQLite) FinishDataExport(ctx context.Context, arg database.FinishDataExportParams) (int64, error) {
+	return s.execRows(ctx, `
+		UPDATE data_exports
+		SET status = ?, completed_at = ?, expires_at = ?, archive = ?, error = ?
+		WHERE id = ? AND status = 'pending'`,
+		arg.Status, time.Now().UnixMicro(), nullTime(arg.ExpiresAt), arg.Archive, arg.Error, arg.ID,
+	)
+}
+
+func (s *SQLite) DeleteExpiredDataExports(ctx context.Context, expiresAt sql.NullTime) (int64, error) {
+	return s.execRows(ctx, "DELETE FROM data_exports WHERE expires_at <= ?", nullTime(expiresAt))
+}
@@ -0,0 +1,79 @@
+-- The Postgres schema as of sql/schema/013, for SQLite, less
+-- rate_limit_buckets, which only the Postgres rate limiter uses. Timestamps
+-- are microseconds since the Unix epoch, the precision Postgres keeps.
+
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    email TEXT NOT NULL UNIQUE,
+    hashed_password TEXT NOT NULL DEFAULT 'unset',
+    is_chirpy_red INTEGER NOT NULL DEFAULT FALSE,
+    role TEXT NOT NULL DEFAULT 'user'
+        CHECK (role IN ('user', 'moderator', 'admin')),
+    status TEXT NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'suspended', 'banned', 'shadow_banned')),
+    suspended_until INTEGER
+);
+
+CREATE TABLE chirp (
+    id TEXT PRIMARY KEY,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    body TEXT NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hidden_at INTEGER
+);
+
+CREATE INDEX chirp_user_id_created_at_idx ON chirp (user_id, created_at);
+
+CREATE TABLE refresh_tokens (
+    token TEXT PRIMARY KEY,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at INTEGER NOT NULL,
+    revoked_at INTEGER
+);
+
+CREATE TABLE banned_words (
+    word TEXT PRIMARY KEY,
+    action TEXT NOT NULL DEFAULT 'mask'
+        CHECK (action IN ('mask', 'reject', 'flag')),
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+CREATE TABLE reports (
+    id TEXT PRIMARY KEY,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    reporter_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    target_type TEXT NOT NULL
+        CHECK (target_type IN ('chirp', 'user')),
+    chirp_id TEXT REFERENCES chirp(id) ON DELETE SET NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL
+        CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'impersonation', 'profanity', 'other')),
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open'
+        CHECK (status IN ('open', 'actioned', 'dismissed')),
+    resolved_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at INTEGER,
+    resolution TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX reports_status_created_at_idx ON reports (status, created_at);
+
+CREATE TABLE moderation_actions (
+    id TEXT PRIMARY KEY,
+    created_at INTEGER NOT NULL,
+    report_id TEXT REFERENCES reports(id) ON DELETE SET NULL,
+    moderator_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL
+        CHECK (action IN ('hide_chirp', 'delete_chirp', 'warn', 'suspend', 'ban', 'shadow_ban', 'reinstate')),
+    target_user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_chirp_id TEXT,
+    reason TEXT NOT NULL,
+    expires_at INTEGER
+);
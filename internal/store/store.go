@@ -0,0 +1,200 @@
+// Package store defines the repositories the server persists through, so
+// that it can run against Postgres in production and against SQLite or
+// memory on a laptop or in tests.
+//
+// The interfaces keep the shapes of the sqlc queries in internal/database,
+// which *database.Queries satisfies as generated. Every backend reports a
+// missing row as sql.ErrNoRows, as sqlc does, and a duplicate key as
+// ErrConflict.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+)
+
+// ErrConflict is returned when a write would duplicate a unique value, such
+// as another user's email.
+var ErrConflict = errors.New("store: conflicts with an existing row")
+
+// ErrSerialization is returned by InTx when the transaction lost a race with
+// a concurrent one. Running it again may well succeed.
+var ErrSerialization = errors.New("store: transaction conflicts with a concurrent one")
+
+// UserStore persists accounts.
+type UserStore interface {
+	CreateUser(ctx context.Context, arg database.CreateUserParams) (database.CreateUserRow, error)
+	GetUser(ctx context.Context, email string) (database.User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error)
+	// ListUsers returns every user, oldest first.
+	ListUsers(ctx context.Context) ([]database.User, error)
+	EditUser(ctx context.Context, arg database.EditUserParams) (string, error)
+	UpgradeUserToRed(ctx context.Context, id uuid.UUID) (int64, error)
+	SetUserStatus(ctx context.Context, arg database.SetUserStatusParams) (int64, error)
+	SetUserRole(ctx context.Context, arg database.SetUserRoleParams) (int64, error)
+	SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) (int64, error)
+	// SeedUser inserts a user with a given id and creation time, unless the
+	// id or email is taken, and counts the users inserted.
+	SeedUser(ctx context.Context, arg database.SeedUserParams) (int64, error)
+	// DeleteUser deletes a user along with everything they own, counting
+	// the users deleted.
+	DeleteUser(ctx context.Context, id uuid.UUID) (int64, error)
+	// DeleteAllUsers deletes every user along with everything they own,
+	// counting the users deleted.
+	DeleteAllUsers(ctx context.Context) (int64, error)
+}
+
+// ChirpStore persists chirps. The listing methods leave out hidden chirps,
+// and chirps by shadow-banned users unless the viewer wrote them.
+type ChirpStore interface {
+	CreateChirpForUser(ctx context.Context, arg database.CreateChirpForUserParams) (database.Chirp, error)
+	GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error)
+	GetChirps(ctx context.Context, viewerID uuid.NullUUID) ([]database.Chirp, error)
+	GetChirpsForAuthor(ctx context.Context, arg database.GetChirpsForAuthorParams) ([]database.Chirp, error)
+	GetSortedChirps(ctx context.Context, arg database.GetSortedChirpsParams) ([]database.Chirp, error)
+	GetSortedChirpsForAuthor(ctx context.Context, arg database.GetSortedChirpsForAuthorParams) ([]database.Chirp, error)
+	// GetRecentChirpBodiesForUser returns the bodies of the user's latest
+	// chirps created after arg.CreatedAt, newest first.
+	GetRecentChirpBodiesForUser(ctx context.Context, arg database.GetRecentChirpBodiesForUserParams) ([]string, error)
+	DeleteChirp(ctx context.Context, arg database.DeleteChirpParams) error
+	DeleteChirpsForUser(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteAllChirps(ctx context.Context) (int64, error)
+	HideChirp(ctx context.Context, id uuid.UUID) error
+	// ListChirpsForUser returns every chirp a user wrote, hidden or not,
+	// oldest first.
+	ListChirpsForUser(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error)
+	// SeedChirp inserts a chirp with a given id and creation time, unless
+	// the id is taken, and counts the chirps inserted.
+	SeedChirp(ctx context.Context, arg database.SeedChirpParams) (int64, error)
+}
+
+// FollowStore persists who follows whom.
+type FollowStore interface {
+	// CreateFollow counts the follows inserted: none if it already exists.
+	CreateFollow(ctx context.Context, arg database.CreateFollowParams) (int64, error)
+	// ListFollowees returns the users a user follows, in the order they
+	// were followed.
+	ListFollowees(ctx context.Context, followerID uuid.UUID) ([]uuid.UUID, error)
+}
+
+// TokenStore persists refresh tokens.
+type TokenStore interface {
+	CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) error
+	GetUserFromRefreshToken(ctx context.Context, token string) (database.GetUserFromRefreshTokenRow, error)
+	RevokeRefreshToken(ctx context.Context, arg database.RevokeRefreshTokenParams) error
+	RevokeAllRefreshTokensForUser(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteRefreshTokensForUser(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteAllRefreshTokens(ctx context.Context) (int64, error)
+	// ListRefreshTokensForUser returns a user's refresh tokens, revoked and
+	// expired ones included, newest first.
+	ListRefreshTokensForUser(ctx context.Context, userID uuid.UUID) ([]database.RefreshToken, error)
+}
+
+// ReportStore persists abuse reports.
+type ReportStore interface {
+	CreateReport(ctx context.Context, arg database.CreateReportParams) (database.Report, error)
+	GetReport(ctx context.Context, id uuid.UUID) (database.Report, error)
+	// ListReportsByStatus returns the reports in a status, oldest first.
+	ListReportsByStatus(ctx context.Context, status string) ([]database.Report, error)
+	// ListReportsForReporter returns a user's reports, newest first.
+	ListReportsForReporter(ctx context.Context, reporterID uuid.NullUUID) ([]database.Report, error)
+	// ResolveReport closes an open report; one already resolved is
+	// sql.ErrNoRows.
+	ResolveReport(ctx context.Context, arg database.ResolveReportParams) (database.Report, error)
+}
+
+// ModerationStore persists the actions moderators take.
+type ModerationStore interface {
+	CreateModerationAction(ctx context.Context, arg database.CreateModerationActionParams) (database.ModerationAction, error)
+	// ListModerationActionsForUser returns the actions taken against a
+	// user, newest first.
+	ListModerationActionsForUser(ctx context.Context, targetUserID uuid.UUID) ([]database.ModerationAction, error)
+}
+
+// WordStore persists the profanity filter's banned words.
+type WordStore interface {
+	ListBannedWords(ctx context.Context) ([]database.BannedWord, error)
+	UpsertBannedWord(ctx context.Context, arg database.UpsertBannedWordParams) (database.BannedWord, error)
+	DeleteBannedWord(ctx context.Context, word string) (int64, error)
+}
+
+// AuditStore persists the audit log. Events are only ever added, and outlive
+// the accounts they mention.
+type AuditStore interface {
+	CreateAuditEvent(ctx context.Context, arg database.CreateAuditEventParams) (database.AuditEvent, error)
+	// ListAuditEvents lists the events arg selects, newest first.
+	ListAuditEvents(ctx context.Context, arg database.ListAuditEventsParams) ([]database.AuditEvent, error)
+}
+
+// AccountDeletionStore persists the account deletions waiting out their
+// grace period.
+type AccountDeletionStore interface {
+	// ScheduleAccountDeletion schedules a user's deletion, replacing the
+	// schedule if there already is one.
+	ScheduleAccountDeletion(ctx context.Context, arg database.ScheduleAccountDeletionParams) (database.AccountDeletion, error)
+	GetAccountDeletion(ctx context.Context, userID uuid.UUID) (database.AccountDeletion, error)
+	// CancelAccountDeletion counts the deletions cancelled: none if the user
+	// had none scheduled.
+	CancelAccountDeletion(ctx context.Context, userID uuid.UUID) (int64, error)
+	// ListDueAccountDeletions returns up to arg.Limit deletions due at
+	// arg.DeleteAfter, longest overdue first.
+	ListDueAccountDeletions(ctx context.Context, arg database.ListDueAccountDeletionsParams) ([]database.AccountDeletion, error)
+}
+
+// DataExportStore persists the exports users ask for of their data.
+type DataExportStore interface {
+	CreateDataExport(ctx context.Context, userID uuid.UUID) (database.DataExport, error)
+	GetDataExport(ctx context.Context, id uuid.UUID) (database.DataExport, error)
+	// GetPendingDataExportForUser returns the user's latest export that is
+	// still to be built.
+	GetPendingDataExportForUser(ctx context.Context, userID uuid.UUID) (database.DataExport, error)
+	// ListPendingDataExports returns up to limit exports still to be built,
+	// oldest first.
+	ListPendingDataExports(ctx context.Context, limit int32) ([]database.DataExport, error)
+	// FinishDataExport records the outcome of a pending export, counting
+	// the exports updated: none if it was no longer pending.
+	FinishDataExport(ctx context.Context, arg database.FinishDataExportParams) (int64, error)
+	// DeleteExpiredDataExports deletes the exports that expired by
+	// expiresAt and counts them.
+	DeleteExpiredDataExports(ctx context.Context, expiresAt sql.NullTime) (int64, error)
+}
+
+// Tx is the repositories as seen from inside a transaction.
+type Tx interface {
+	UserStore
+	ChirpStore
+	FollowStore
+	TokenStore
+	ReportStore
+	ModerationStore
+	WordStore
+	AuditStore
+	AccountDeletionStore
+	DataExportStore
+}
+
+// Store is everything the server persists.
+type Store interface {
+	Tx
+
+	// InTx runs fn in a transaction, committed if fn returns nil and rolled
+	// back otherwise. fn must query through tx, not the store.
+	InTx(ctx context.Context, fn func(tx Tx) error) error
+	// Ping reports whether the backend can be reached.
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// defaultBannedWords are the words a new database starts with, as seeded by
+// the Postgres migrations.
+var defaultBannedWords = []string{"kerfuffle", "sharbert", "fornax"}
+
+// refreshTokenTTL is how long a refresh token is valid for, as in
+// CreateRefreshToken.
+const refreshTokenTTL = 60 * 24 * time.Hour
@@ -0,0 +1,162 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/migrate"
+	"github.com/Vikuuu/Chirpy/internal/store"
+	"github.com/Vikuuu/Chirpy/internal/store/storetest"
+)
+
+func TestMemory(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return store.NewMemory()
+	})
+}
+
+func TestMemoryTxIsolation(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	inTx, rolledBack := make(chan struct{}), make(chan struct{})
+	failed := errors.New("failed")
+	go func() {
+		defer close(rolledBack)
+		s.InTx(ctx, func(tx store.Tx) error {
+			_, err := tx.CreateUser(ctx, database.CreateUserParams{Email: "a@example.com", HashedPassword: "hash"})
+			require.NoError(t, err)
+			close(inTx)
+			// Give the queries below a chance to run, if they weren't held off.
+			time.Sleep(50 * time.Millisecond)
+			return failed
+		})
+	}()
+	<-inTx
+
+	_, err := s.GetUser(ctx, "a@example.com")
+	assert.ErrorIs(t, err, sql.ErrNoRows, "uncommitted rows are not seen")
+	_, err = s.CreateUser(ctx, database.CreateUserParams{Email: "b@example.com", HashedPassword: "hash"})
+	require.NoError(t, err)
+	<-rolledBack
+
+	_, err = s.GetUser(ctx, "b@example.com")
+	assert.NoError(t, err, "a rollback keeps writes made outside the transaction")
+}
+
+func TestSQLite(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		s, err := store.OpenSQLite(context.Background(), filepath.Join(t.TempDir(), "chirpy.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { s.Close() })
+		return s
+	})
+}
+
+func TestSQLiteReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "chirpy.db")
+	s, err := store.OpenSQLite(ctx, path)
+	require.NoError(t, err)
+	_, err = s.CreateUser(ctx, database.CreateUserParams{Email: "a@example.com", HashedPassword: "hash"})
+	require.NoError(t, err)
+	_, err = s.DeleteBannedWord(ctx, "fornax")
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	s, err = store.OpenSQLite(ctx, path)
+	require.NoError(t, err)
+	defer s.Close()
+	_, err = s.GetUser(ctx, "a@example.com")
+	require.NoError(t, err)
+	words, err := s.ListBannedWords(ctx)
+	require.NoError(t, err)
+	require.Len(t, words, 2, "deleted words stay deleted")
+}
+
+func TestSQLiteUpgrade(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "chirpy.db")
+	s, err := store.OpenSQLite(ctx, path)
+	require.NoError(t, err)
+	a, err := s.CreateUser(ctx, database.CreateUserParams{Email: "a@example.com", HashedPassword: "hash"})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	// Take the file back to the first schema, as an older build made it.
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = db.Exec("DROP TABLE follows; DROP TABLE audit_events; DROP TABLE account_deletions; DROP TABLE data_exports; PRAGMA user_version = 1")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	s, err = store.OpenSQLite(ctx, path)
+	require.NoError(t, err)
+	defer s.Close()
+	b, err := s.CreateUser(ctx, database.CreateUserParams{Email: "b@example.com", HashedPassword: "hash"})
+	require.NoError(t, err)
+	n, err := s.CreateFollow(ctx, database.CreateFollowParams{FollowerID: a.ID, FolloweeID: b.ID, CreatedAt: time.Now()})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+	_, err = s.CreateAuditEvent(ctx, database.CreateAuditEventParams{Action: "admin.reset"})
+	require.NoError(t, err)
+}
+
+func TestSQLiteAuditLogIsAppendOnly(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "chirpy.db")
+	s, err := store.OpenSQLite(ctx, path)
+	require.NoError(t, err)
+	_, err = s.CreateAuditEvent(ctx, database.CreateAuditEventParams{Action: "admin.reset"})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer db.Close()
+	for _, stmt := range []string{
+		"UPDATE audit_events SET action = 'nothing'",
+		"DELETE FROM audit_events",
+	} {
+		_, err := db.Exec(stmt)
+		assert.ErrorContains(t, err, "append-only", stmt)
+	}
+}
+
+// TestPostgres runs the suite against the database in TEST_DB_URL, which it
+// migrates and empties between subtests. It is skipped without one.
+func TestPostgres(t *testing.T) {
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		t.Skip("TEST_DB_URL not set")
+	}
+	db, err := sql.Open("postgres", url)
+	require.NoError(t, err)
+	migrator, err := migrate.New(db)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	s := store.NewPostgres(db, nil)
+	t.Cleanup(func() { s.Close() })
+
+	storetest.Run(t, func(t *testing.T) store.Store {
+		for _, stmt := range []string{
+			"DELETE FROM users",
+			"DELETE FROM banned_words",
+			"INSERT INTO banned_words (word, action, created_at, updated_at) VALUES ('kerfuffle', 'mask', NOW(), NOW()), ('sharbert', 'mask', NOW(), NOW()), ('fornax', 'mask', NOW(), NOW())",
+		} {
+			_, err := db.Exec(stmt)
+			require.NoError(t, err)
+		}
+		return s
+	})
+}
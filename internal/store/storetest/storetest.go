@@ -0,0 +1,940 @@
+// Package storetest is the conformance suite every store.Store backend must
+// pass, so that the server behaves the same whichever it runs against.
+package storetest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// Run runs the suite. open must return an empty store, as a fresh database
+// would be, for each subtest.
+func Run(t *testing.T, open func(t *testing.T) store.Store) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s store.Store)
+	}{
+		{name: "Users", run: testUsers},
+		{name: "Duplicate Email", run: testDuplicateEmail},
+		{name: "User Updates", run: testUserUpdates},
+		{name: "Delete All Users", run: testDeleteAllUsers},
+		{name: "Delete User", run: testDeleteUser},
+		{name: "Chirps", run: testChirps},
+		{name: "Chirp Visibility", run: testChirpVisibility},
+		{name: "Chirp Deletion", run: testChirpDeletion},
+		{name: "Delete All Chirps", run: testDeleteAllChirps},
+		{name: "Recent Chirp Bodies", run: testRecentChirpBodies},
+		{name: "Refresh Tokens", run: testRefreshTokens},
+		{name: "Refresh Token Deletion", run: testRefreshTokenDeletion},
+		{name: "Reports", run: testReports},
+		{name: "Moderation Actions", run: testModerationActions},
+		{name: "Banned Words", run: testBannedWords},
+		{name: "Seeding", run: testSeeding},
+		{name: "Follows", run: testFollows},
+		{name: "Audit Events", run: testAuditEvents},
+		{name: "Personal Data", run: testPersonalData},
+		{name: "Account Deletions", run: testAccountDeletions},
+		{name: "Data Exports", run: testDataExports},
+		{name: "Transactions", run: testTransactions},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, open(t))
+		})
+	}
+}
+
+// createUser creates a user with email, failing the test if it can't.
+func createUser(t *testing.T, s store.Store, email string) database.User {
+	t.Helper()
+	ctx := context.Background()
+	row, err := s.CreateUser(ctx, database.CreateUserParams{Email: email, HashedPassword: "hash"})
+	require.NoError(t, err)
+	u, err := s.GetUserByID(ctx, row.ID)
+	require.NoError(t, err)
+	return u
+}
+
+// createChirp creates a chirp by user, failing the test if it can't.
+func createChirp(t *testing.T, s store.Store, user uuid.UUID, body string) database.Chirp {
+	t.Helper()
+	c, err := s.CreateChirpForUser(context.Background(), database.CreateChirpForUserParams{Body: body, UserID: user})
+	require.NoError(t, err)
+	return c
+}
+
+func bodies(chirps []database.Chirp) []string {
+	var out []string
+	for _, c := range chirps {
+		out = append(out, c.Body)
+	}
+	return out
+}
+
+func viewer(id uuid.UUID) uuid.NullUUID { return uuid.NullUUID{UUID: id, Valid: true} }
+
+func testUsers(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	row, err := s.CreateUser(ctx, database.CreateUserParams{Email: "a@example.com", HashedPassword: "hash"})
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, row.ID)
+	assert.Equal(t, "a@example.com", row.Email)
+	assert.False(t, row.IsChirpyRed)
+	assert.WithinDuration(t, time.Now(), row.CreatedAt, time.Minute)
+
+	u, err := s.GetUser(ctx, "a@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, row.ID, u.ID)
+	assert.Equal(t, "hash", u.HashedPassword)
+	assert.Equal(t, "user", u.Role)
+	assert.Equal(t, "active", u.Status)
+	assert.False(t, u.SuspendedUntil.Valid)
+
+	createUser(t, s, "b@example.com")
+	users, err := s.ListUsers(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "a@example.com", users[0].Email)
+	assert.Equal(t, "b@example.com", users[1].Email)
+
+	_, err = s.GetUser(ctx, "nobody@example.com")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = s.GetUserByID(ctx, uuid.New())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func testDuplicateEmail(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	createUser(t, s, "a@example.com")
+	b := createUser(t, s, "b@example.com")
+
+	_, err := s.CreateUser(ctx, database.CreateUserParams{Email: "a@example.com", HashedPassword: "hash"})
+	assert.ErrorIs(t, err, store.ErrConflict)
+
+	_, err = s.EditUser(ctx, database.EditUserParams{Email: "a@example.com", HashedPassword: "hash", UpdatedAt: time.Now(), ID: b.ID})
+	assert.ErrorIs(t, err, store.ErrConflict)
+}
+
+func testUserUpdates(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	u := createUser(t, s, "a@example.com")
+	until := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	email, err := s.EditUser(ctx, database.EditUserParams{Email: "new@example.com", HashedPassword: "new-hash", UpdatedAt: time.Now(), ID: u.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", email)
+
+	for name, update := range map[string]func() (int64, error){
+		"UpgradeUserToRed": func() (int64, error) { return s.UpgradeUserToRed(ctx, u.ID) },
+		"SetUserRole": func() (int64, error) {
+			return s.SetUserRole(ctx, database.SetUserRoleParams{Role: "moderator", ID: u.ID})
+		},
+		"SetUserStatus": func() (int64, error) {
+			return s.SetUserStatus(ctx, database.SetUserStatusParams{
+				Status:         "suspended",
+				SuspendedUntil: sql.NullTime{Time: until, Valid: true},
+				ID:             u.ID,
+			})
+		},
+		"SetUserPassword": func() (int64, error) {
+			return s.SetUserPassword(ctx, database.SetUserPasswordParams{HashedPassword: "newer-hash", ID: u.ID})
+		},
+	} {
+		n, err := update()
+		require.NoError(t, err, name)
+		assert.Equal(t, int64(1), n, name)
+	}
+
+	got, err := s.GetUserByID(ctx, u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", got.Email)
+	assert.Equal(t, "newer-hash", got.HashedPassword)
+	assert.True(t, got.IsChirpyRed)
+	assert.Equal(t, "moderator", got.Role)
+	assert.Equal(t, "suspended", got.Status)
+	assert.True(t, got.SuspendedUntil.Valid)
+	assert.True(t, until.Equal(got.SuspendedUntil.Time), "suspended until %v, want %v", got.SuspendedUntil.Time, until)
+
+	n, err := s.UpgradeUserToRed(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Zero(t, n, "missing users aren't counted")
+	_, err = s.EditUser(ctx, database.EditUserParams{Email: "x@example.com", UpdatedAt: time.Now(), ID: uuid.New()})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func testDeleteAllUsers(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	u := createUser(t, s, "a@example.com")
+	c := createChirp(t, s, u.ID, "hello")
+	require.NoError(t, s.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{Token: "token", UserID: u.ID}))
+
+	n, err := s.DeleteAllUsers(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+
+	users, err := s.ListUsers(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+	_, err = s.GetChirp(ctx, c.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows, "chirps go with their author")
+	_, err = s.GetUserFromRefreshToken(ctx, "token")
+	assert.ErrorIs(t, err, sql.ErrNoRows, "tokens go with their user")
+	words, err := s.ListBannedWords(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, words, "banned words aren't users'")
+}
+
+func testDeleteUser(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	gone := createUser(t, s, "gone@example.com")
+	stays := createUser(t, s, "stays@example.com")
+	goneChirp := createChirp(t, s, gone.ID, "bye")
+	staysChirp := createChirp(t, s, stays.ID, "hi")
+	require.NoError(t, s.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{Token: "gone", UserID: gone.ID}))
+	require.NoError(t, s.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{Token: "stays", UserID: stays.ID}))
+	_, err := s.CreateFollow(ctx, database.CreateFollowParams{FollowerID: stays.ID, FolloweeID: gone.ID, CreatedAt: time.Now()})
+	require.NoError(t, err)
+	against, err := s.CreateReport(ctx, database.CreateReportParams{
+		ReporterID: viewer(stays.ID), TargetType: "user", UserID: gone.ID, Reason: "spam",
+	})
+	require.NoError(t, err)
+	filed, err := s.CreateReport(ctx, database.CreateReportParams{
+		ReporterID: viewer(gone.ID), TargetType: "chirp", ChirpID: viewer(staysChirp.ID), UserID: stays.ID, Reason: "spam",
+	})
+	require.NoError(t, err)
+	_, err = s.CreateModerationAction(ctx, database.CreateModerationActionParams{
+		ModeratorID: viewer(gone.ID), Action: "warn", TargetUserID: stays.ID, Reason: "be nice",
+	})
+	require.NoError(t, err)
+
+	n, err := s.DeleteUser(ctx, gone.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+	n, err = s.DeleteUser(ctx, gone.ID)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	_, err = s.GetUserByID(ctx, gone.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = s.GetChirp(ctx, goneChirp.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows, "chirps go with their author")
+	_, err = s.GetUserFromRefreshToken(ctx, "gone")
+	assert.ErrorIs(t, err, sql.ErrNoRows, "tokens go with their user")
+	_, err = s.GetReport(ctx, against.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows, "reports against the user go with them")
+	followees, err := s.ListFollowees(ctx, stays.ID)
+	require.NoError(t, err)
+	assert.Empty(t, followees)
+
+	kept, err := s.GetReport(ctx, filed.ID)
+	require.NoError(t, err)
+	assert.False(t, kept.ReporterID.Valid, "reports the user filed stay, without them")
+	actions, err := s.ListModerationActionsForUser(ctx, stays.ID)
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	assert.False(t, actions[0].ModeratorID.Valid)
+	_, err = s.GetChirp(ctx, staysChirp.ID)
+	require.NoError(t, err)
+	_, err = s.GetUserFromRefreshToken(ctx, "stays")
+	require.NoError(t, err)
+}
+
+func testChirps(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	a := createUser(t, s, "a@example.com")
+	b := createUser(t, s, "b@example.com")
+	first := createChirp(t, s, a.ID, "first")
+	createChirp(t, s, b.ID, "second")
+	createChirp(t, s, a.ID, "third")
+
+	assert.Equal(t, a.ID, first.UserID)
+	assert.False(t, first.HiddenAt.Valid)
+	got, err := s.GetChirp(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "first", got.Body)
+	_, err = s.GetChirp(ctx, uuid.New())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	all, err := s.GetChirps(ctx, uuid.NullUUID{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second", "third"}, bodies(all))
+
+	desc, err := s.GetSortedChirps(ctx, database.GetSortedChirpsParams{Sort: "desc"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"third", "second", "first"}, bodies(desc))
+
+	asc, err := s.GetSortedChirps(ctx, database.GetSortedChirpsParams{Sort: "asc"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second", "third"}, bodies(asc))
+
+	byA, err := s.GetChirpsForAuthor(ctx, database.GetChirpsForAuthorParams{UserID: a.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "third"}, bodies(byA))
+
+	byADesc, err := s.GetSortedChirpsForAuthor(ctx, database.GetSortedChirpsForAuthorParams{UserID: a.ID, Sort: "desc"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"third", "first"}, bodies(byADesc))
+}
+
+func testChirpVisibility(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	a := createUser(t, s, "a@example.com")
+	banned := createUser(t, s, "banned@example.com")
+	createChirp(t, s, a.ID, "visible")
+	hidden := createChirp(t, s, a.ID, "hidden")
+	createChirp(t, s, banned.ID, "shadowed")
+	require.NoError(t, s.HideChirp(ctx, hidden.ID))
+	_, err := s.SetUserStatus(ctx, database.SetUserStatusParams{Status: "shadow_banned", ID: banned.ID})
+	require.NoError(t, err)
+
+	got, err := s.GetChirp(ctx, hidden.ID)
+	require.NoError(t, err, "hidden chirps can still be fetched by ID")
+	assert.True(t, got.HiddenAt.Valid)
+
+	tests := []struct {
+		name     string
+		list     func() ([]database.Chirp, error)
+		expected []string
+	}{
+		{
+			name:     "Anonymous",
+			list:     func() ([]database.Chirp, error) { return s.GetChirps(ctx, uuid.NullUUID{}) },
+			expected: []string{"visible"},
+		},
+		{
+			name:     "Other User",
+			list:     func() ([]database.Chirp, error) { return s.GetChirps(ctx, viewer(a.ID)) },
+			expected: []string{"visible"},
+		},
+		{
+			name:     "Shadow Banned Author",
+			list:     func() ([]database.Chirp, error) { return s.GetChirps(ctx, viewer(banned.ID)) },
+			expected: []string{"visible", "shadowed"},
+		},
+		{
+			name: "Sorted",
+			list: func() ([]database.Chirp, error) {
+				return s.GetSortedChirps(ctx, database.GetSortedChirpsParams{ViewerID: viewer(banned.ID), Sort: "desc"})
+			},
+			expected: []string{"shadowed", "visible"},
+		},
+		{
+			name: "By Shadow Banned Author",
+			list: func() ([]database.Chirp, error) {
+				return s.GetChirpsForAuthor(ctx, database.GetChirpsForAuthorParams{UserID: banned.ID, ViewerID: viewer(a.ID)})
+			},
+			expected: nil,
+		},
+		{
+			name: "By Self While Shadow Banned",
+			list: func() ([]database.Chirp, error) {
+				return s.GetSortedChirpsForAuthor(ctx, database.GetSortedChirpsForAuthorParams{UserID: banned.ID, ViewerID: viewer(banned.ID), Sort: "asc"})
+			},
+			expected: []string{"shadowed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chirps, err := tt.list()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, bodies(chirps))
+		})
+	}
+}
+
+func testChirpDeletion(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	a := createUser(t, s, "a@example.com")
+	b := createUser(t, s, "b@example.com")
+	c := createChirp(t, s, a.ID, "one")
+	createChirp(t, s, a.ID, "two")
+	report, err := s.CreateReport(ctx, database.CreateReportParams{
+		ReporterID: viewer(b.ID),
+		TargetType: "chirp",
+		ChirpID:    viewer(c.ID),
+		UserID:     a.ID,
+		Reason:     "spam",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteChirp(ctx, database.DeleteChirpParams{UserID: b.ID, ID: c.ID}))
+	_, err = s.GetChirp(ctx, c.ID)
+	require.NoError(t, err, "only the author's delete applies")
+
+	require.NoError(t, s.DeleteChirp(ctx, database.DeleteChirpParams{UserID: a.ID, ID: c.ID}))
+	_, err = s.GetChirp(ctx, c.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	report, err = s.GetReport(ctx, report.ID)
+	require.NoError(t, err, "reports outlive the chirp")
+	assert.False(t, report.ChirpID.Valid)
+
+	n, err := s.DeleteChirpsForUser(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func testDeleteAllChirps(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	a := createUser(t, s, "a@example.com")
+	createChirp(t, s, a.ID, "one")
+	createChirp(t, s, a.ID, "two")
+
+	n, err := s.DeleteAllChirps(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	all, err := s.GetChirps(ctx, uuid.NullUUID{})
+	require.NoError(t, err)
+	assert.Empty(t, all)
+	_, err = s.GetUserByID(ctx, a.ID)
+	require.NoError(t, err, "authors stay")
+}
+
+func testRecentChirpBodies(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	a := createUser(t, s, "a@example.com")
+	b := createUser(t, s, "b@example.com")
+	createChirp(t, s, a.ID, "old")
+	time.Sleep(2 * time.Millisecond)
+	since := time.Now()
+	time.Sleep(2 * time.Millisecond)
+	createChirp(t, s, a.ID, "one")
+	createChirp(t, s, b.ID, "other")
+	createChirp(t, s, a.ID, "two")
+
+	got, err := s.GetRecentChirpBodiesForUser(ctx, database.GetRecentChirpBodiesForUserParams{UserID: a.ID, CreatedAt: since})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"two", "one"}, got)
+}
+
+func testRefreshTokens(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	u := createUser(t, s, "a@example.com")
+	for _, token := range []string{"one", "two", "three"} {
+		require.NoError(t, s.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{Token: token, UserID: u.ID}))
+	}
+
+	got, err := s.GetUserFromRefreshToken(ctx, "one")
+	require.NoError(t, err)
+	assert.Equal(t, u.ID, got.UserID)
+	assert.WithinDuration(t, time.Now().Add(60*24*time.Hour), got.ExpiresAt, time.Minute)
+	assert.False(t, got.RevokedAt.Valid)
+	_, err = s.GetUserFromRefreshToken(ctx, "missing")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	now := time.Now()
+	require.NoError(t, s.RevokeRefreshToken(ctx, database.RevokeRefreshTokenParams{
+		RevokedAt: sql.NullTime{Time: now, Valid: true},
+		UpdatedAt: now,
+		Token:     "one",
+	}))
+	got, err = s.GetUserFromRefreshToken(ctx, "one")
+	require.NoError(t, err)
+	assert.True(t, got.RevokedAt.Valid)
+
+	n, err := s.RevokeAllRefreshTokensForUser(ctx, u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n, "tokens already revoked aren't counted")
+	got, err = s.GetUserFromRefreshToken(ctx, "three")
+	require.NoError(t, err)
+	assert.True(t, got.RevokedAt.Valid)
+}
+
+func testRefreshTokenDeletion(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	a := createUser(t, s, "a@example.com")
+	b := createUser(t, s, "b@example.com")
+	for _, tok := range []database.CreateRefreshTokenParams{{Token: "a1", UserID: a.ID}, {Token: "a2", UserID: a.ID}, {Token: "b1", UserID: b.ID}} {
+		require.NoError(t, s.CreateRefreshToken(ctx, tok))
+	}
+
+	n, err := s.DeleteRefreshTokensForUser(ctx, a.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	_, err = s.GetUserFromRefreshToken(ctx, "a1")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = s.GetUserFromRefreshToken(ctx, "b1")
+	require.NoError(t, err)
+
+	n, err = s.DeleteAllRefreshTokens(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+	_, err = s.GetUserFromRefreshToken(ctx, "b1")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func testReports(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	reporter := createUser(t, s, "reporter@example.com")
+	target := createUser(t, s, "target@example.com")
+	moderator := createUser(t, s, "moderator@example.com")
+	c := createChirp(t, s, target.ID, "spam spam spam")
+
+	var reports []database.Report
+	for _, reason := range []string{"spam", "harassment", "other"} {
+		r, err := s.CreateReport(ctx, database.CreateReportParams{
+			ReporterID: viewer(reporter.ID),
+			TargetType: "chirp",
+			ChirpID:    viewer(c.ID),
+			UserID:     target.ID,
+			Reason:     reason,
+			Details:    "details",
+		})
+		require.NoError(t, err)
+		reports = append(reports, r)
+	}
+	assert.Equal(t, "open", reports[0].Status)
+	assert.Equal(t, "details", reports[0].Details)
+	assert.False(t, reports[0].ResolvedAt.Valid)
+
+	resolved, err := s.ResolveReport(ctx, database.ResolveReportParams{
+		Status:     "dismissed",
+		ResolvedBy: viewer(moderator.ID),
+		Resolution: "not spam",
+		ID:         reports[1].ID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "dismissed", resolved.Status)
+	assert.Equal(t, "not spam", resolved.Resolution)
+	assert.Equal(t, viewer(moderator.ID), resolved.ResolvedBy)
+	assert.True(t, resolved.ResolvedAt.Valid)
+
+	_, err = s.ResolveReport(ctx, database.ResolveReportParams{Status: "actioned", ID: reports[1].ID})
+	assert.ErrorIs(t, err, sql.ErrNoRows, "a resolved report can't be resolved again")
+
+	got, err := s.GetReport(ctx, reports[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "dismissed", got.Status)
+	_, err = s.GetReport(ctx, uuid.New())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	open, err := s.ListReportsByStatus(ctx, "open")
+	require.NoError(t, err)
+	require.Len(t, open, 2)
+	assert.Equal(t, "spam", open[0].Reason, "oldest first")
+	assert.Equal(t, "other", open[1].Reason)
+
+	mine, err := s.ListReportsForReporter(ctx, viewer(reporter.ID))
+	require.NoError(t, err)
+	require.Len(t, mine, 3)
+	assert.Equal(t, "other", mine[0].Reason, "newest first")
+
+	none, err := s.ListReportsForReporter(ctx, viewer(target.ID))
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+func testModerationActions(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	moderator := createUser(t, s, "moderator@example.com")
+	target := createUser(t, s, "target@example.com")
+	expires := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+
+	warn, err := s.CreateModerationAction(ctx, database.CreateModerationActionParams{
+		ModeratorID:  viewer(moderator.ID),
+		Action:       "warn",
+		TargetUserID: target.ID,
+		Reason:       "be nice",
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, warn.ID)
+	assert.False(t, warn.ExpiresAt.Valid)
+
+	suspend, err := s.CreateModerationAction(ctx, database.CreateModerationActionParams{
+		ModeratorID:  viewer(moderator.ID),
+		Action:       "suspend",
+		TargetUserID: target.ID,
+		Reason:       "told you",
+		ExpiresAt:    sql.NullTime{Time: expires, Valid: true},
+	})
+	require.NoError(t, err)
+	assert.True(t, expires.Equal(suspend.ExpiresAt.Time))
+
+	actions, err := s.ListModerationActionsForUser(ctx, target.ID)
+	require.NoError(t, err)
+	require.Len(t, actions, 2)
+	assert.Equal(t, "suspend", actions[0].Action, "newest first")
+	assert.Equal(t, "warn", actions[1].Action)
+
+	actions, err = s.ListModerationActionsForUser(ctx, moderator.ID)
+	require.NoError(t, err)
+	assert.Empty(t, actions)
+}
+
+func testBannedWords(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	words := func() map[string]string {
+		t.Helper()
+		list, err := s.ListBannedWords(ctx)
+		require.NoError(t, err)
+		out := map[string]string{}
+		for i, w := range list {
+			if i > 0 {
+				assert.Less(t, list[i-1].Word, w.Word, "sorted by word")
+			}
+			out[w.Word] = w.Action
+		}
+		return out
+	}
+
+	assert.Equal(t, map[string]string{"fornax": "mask", "kerfuffle": "mask", "sharbert": "mask"}, words())
+
+	w, err := s.UpsertBannedWord(ctx, database.UpsertBannedWordParams{Word: "blorp", Action: "reject"})
+	require.NoError(t, err)
+	assert.Equal(t, "reject", w.Action)
+	_, err = s.UpsertBannedWord(ctx, database.UpsertBannedWordParams{Word: "fornax", Action: "flag"})
+	require.NoError(t, err)
+
+	n, err := s.DeleteBannedWord(ctx, "sharbert")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	n, err = s.DeleteBannedWord(ctx, "sharbert")
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	assert.Equal(t, map[string]string{"blorp": "reject", "fornax": "flag", "kerfuffle": "mask"}, words())
+}
+
+func testSeeding(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	now := createUser(t, s, "now@example.com")
+	createChirp(t, s, now.ID, "today")
+	past := time.Now().Add(-48 * time.Hour).UTC().Truncate(time.Microsecond)
+	seeded := database.SeedUserParams{ID: uuid.New(), CreatedAt: past, Email: "seed@example.com", HashedPassword: "hash", IsChirpyRed: true}
+
+	n, err := s.SeedUser(ctx, seeded)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+	u, err := s.GetUserByID(ctx, seeded.ID)
+	require.NoError(t, err)
+	assert.Equal(t, past, u.CreatedAt)
+	assert.True(t, u.IsChirpyRed)
+	assert.Equal(t, "active", u.Status)
+	users, err := s.ListUsers(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, seeded.ID, users[0].ID, "users are listed by creation time")
+
+	n, err = s.SeedUser(ctx, seeded)
+	require.NoError(t, err)
+	assert.Zero(t, n, "a seeded user is inserted once")
+	n, err = s.SeedUser(ctx, database.SeedUserParams{ID: uuid.New(), CreatedAt: past, Email: "now@example.com", HashedPassword: "hash"})
+	require.NoError(t, err)
+	assert.Zero(t, n, "taken emails are skipped")
+
+	chirp := database.SeedChirpParams{ID: uuid.New(), CreatedAt: past.Add(time.Hour), Body: "yesterday", UserID: seeded.ID}
+	n, err = s.SeedChirp(ctx, chirp)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+	n, err = s.SeedChirp(ctx, chirp)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	all, err := s.GetChirps(ctx, uuid.NullUUID{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"yesterday", "today"}, bodies(all), "chirps are listed by creation time")
+}
+
+func testFollows(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	a := createUser(t, s, "a@example.com")
+	b := createUser(t, s, "b@example.com")
+	c := createUser(t, s, "c@example.com")
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	follow := func(follower, followee uuid.UUID, at time.Time) int64 {
+		t.Helper()
+		n, err := s.CreateFollow(ctx, database.CreateFollowParams{FollowerID: follower, FolloweeID: followee, CreatedAt: at})
+		require.NoError(t, err)
+		return n
+	}
+	assert.EqualValues(t, 1, follow(a.ID, c.ID, now))
+	assert.EqualValues(t, 1, follow(a.ID, b.ID, now.Add(-time.Hour)))
+	assert.Zero(t, follow(a.ID, b.ID, now), "a follow is inserted once")
+	assert.EqualValues(t, 1, follow(b.ID, a.ID, now))
+
+	followees, err := s.ListFollowees(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{b.ID, c.ID}, followees)
+	followees, err = s.ListFollowees(ctx, c.ID)
+	require.NoError(t, err)
+	assert.Empty(t, followees)
+
+	_, err = s.DeleteAllUsers(ctx)
+	require.NoError(t, err)
+	followees, err = s.ListFollowees(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Empty(t, followees, "follows go with their users")
+}
+
+func testAuditEvents(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	admin := createUser(t, s, "admin@example.com")
+
+	e, err := s.CreateAuditEvent(ctx, database.CreateAuditEventParams{
+		Action:     "admin.reset",
+		ActorID:    viewer(admin.ID),
+		TargetType: "users",
+		TargetID:   "*@example.com",
+		Ip:         "192.0.2.1",
+		UserAgent:  "curl/8.0",
+		RequestID:  "req-1",
+		Details:    json.RawMessage(`{"users": 3}`),
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, e.ID)
+	assert.Equal(t, "admin.reset", e.Action)
+	assert.Equal(t, viewer(admin.ID), e.ActorID)
+	assert.Equal(t, "192.0.2.1", e.Ip)
+	assert.JSONEq(t, `{"users": 3}`, string(e.Details))
+
+	bare, err := s.CreateAuditEvent(ctx, database.CreateAuditEventParams{Action: "admin.reset"})
+	require.NoError(t, err)
+	assert.False(t, bare.ActorID.Valid)
+	assert.JSONEq(t, `{}`, string(bare.Details), "no details is an empty object")
+
+	// The log outlives test runs on Postgres, so the listings are limited
+	// to this run's actor.
+	var created []database.AuditEvent
+	for i, action := range []string{"auth.login", "user.update", "auth.login", "chirp.delete", "auth.login"} {
+		e, err := s.CreateAuditEvent(ctx, database.CreateAuditEventParams{
+			Action:     action,
+			ActorID:    viewer(admin.ID),
+			TargetType: "user",
+			TargetID:   fmt.Sprint(i % 2),
+		})
+		require.NoError(t, err)
+		created = append(created, e)
+	}
+	list := func(arg database.ListAuditEventsParams) []database.AuditEvent {
+		t.Helper()
+		arg.ActorID = viewer(admin.ID)
+		if arg.Limit == 0 {
+			arg.Limit = 100
+		}
+		events, err := s.ListAuditEvents(ctx, arg)
+		require.NoError(t, err)
+		return events
+	}
+
+	all := list(database.ListAuditEventsParams{})
+	assert.Len(t, all, 6)
+	assert.True(t, slices.IsSortedFunc(all, func(a, b database.AuditEvent) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	}), "newest first")
+	assert.Len(t, list(database.ListAuditEventsParams{Action: sql.NullString{String: "auth.login", Valid: true}}), 3)
+	assert.Len(t, list(database.ListAuditEventsParams{
+		TargetType: sql.NullString{String: "user", Valid: true},
+		TargetID:   sql.NullString{String: "1", Valid: true},
+	}), 2)
+	assert.Empty(t, list(database.ListAuditEventsParams{Since: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true}}))
+	assert.Empty(t, list(database.ListAuditEventsParams{Until: sql.NullTime{Time: e.CreatedAt, Valid: true}}))
+	assert.Len(t, list(database.ListAuditEventsParams{Since: sql.NullTime{Time: created[0].CreatedAt, Valid: true}}), 5)
+
+	// Paging from the last event seen goes through every event once.
+	var paged []database.AuditEvent
+	arg := database.ListAuditEventsParams{Limit: 2}
+	for {
+		page := list(arg)
+		if len(page) == 0 {
+			break
+		}
+		paged = append(paged, page...)
+		last := page[len(page)-1]
+		arg.BeforeCreatedAt = sql.NullTime{Time: last.CreatedAt, Valid: true}
+		arg.BeforeID = viewer(last.ID)
+	}
+	assert.Equal(t, all, paged)
+}
+
+func testPersonalData(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	u := createUser(t, s, "u@example.com")
+	other := createUser(t, s, "other@example.com")
+	createChirp(t, s, u.ID, "first")
+	hidden := createChirp(t, s, u.ID, "second")
+	createChirp(t, s, other.ID, "not mine")
+	require.NoError(t, s.HideChirp(ctx, hidden.ID))
+	for _, tok := range []database.CreateRefreshTokenParams{{Token: "u1", UserID: u.ID}, {Token: "u2", UserID: u.ID}, {Token: "o1", UserID: other.ID}} {
+		require.NoError(t, s.CreateRefreshToken(ctx, tok))
+	}
+	require.NoError(t, s.RevokeRefreshToken(ctx, database.RevokeRefreshTokenParams{
+		RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		UpdatedAt: time.Now(),
+		Token:     "u1",
+	}))
+
+	chirps, err := s.ListChirpsForUser(ctx, u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, bodies(chirps), "hidden chirps included, oldest first")
+
+	tokens, err := s.ListRefreshTokensForUser(ctx, u.ID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	assert.Equal(t, "u2", tokens[0].Token, "newest first")
+	assert.False(t, tokens[0].RevokedAt.Valid)
+	assert.True(t, tokens[1].RevokedAt.Valid, "revoked tokens included")
+}
+
+func testAccountDeletions(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	a := createUser(t, s, "a@example.com")
+	b := createUser(t, s, "b@example.com")
+	now := time.Now().UTC()
+
+	_, err := s.GetAccountDeletion(ctx, a.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	d, err := s.ScheduleAccountDeletion(ctx, database.ScheduleAccountDeletionParams{UserID: a.ID, DeleteAfter: now.Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, a.ID, d.UserID)
+	assert.WithinDuration(t, now.Add(time.Hour), d.DeleteAfter, time.Millisecond)
+	d, err = s.ScheduleAccountDeletion(ctx, database.ScheduleAccountDeletionParams{UserID: a.ID, DeleteAfter: now.Add(-time.Minute)})
+	require.NoError(t, err, "scheduling again replaces the schedule")
+	got, err := s.GetAccountDeletion(ctx, a.ID)
+	require.NoError(t, err)
+	assert.WithinDuration(t, now.Add(-time.Minute), got.DeleteAfter, time.Millisecond)
+	_, err = s.ScheduleAccountDeletion(ctx, database.ScheduleAccountDeletionParams{UserID: b.ID, DeleteAfter: now.Add(-time.Hour)})
+	require.NoError(t, err)
+
+	due := func(at time.Time) []uuid.UUID {
+		t.Helper()
+		ds, err := s.ListDueAccountDeletions(ctx, database.ListDueAccountDeletionsParams{DeleteAfter: at, Limit: 10})
+		require.NoError(t, err)
+		var ids []uuid.UUID
+		for _, d := range ds {
+			ids = append(ids, d.UserID)
+		}
+		return ids
+	}
+	assert.Equal(t, []uuid.UUID{b.ID, a.ID}, due(now), "longest overdue first")
+	assert.Equal(t, []uuid.UUID{b.ID}, due(now.Add(-30*time.Minute)))
+
+	n, err := s.CancelAccountDeletion(ctx, a.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+	n, err = s.CancelAccountDeletion(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	_, err = s.DeleteUser(ctx, b.ID)
+	require.NoError(t, err)
+	assert.Empty(t, due(now), "deleting the user deletes the schedule")
+}
+
+func testDataExports(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	u := createUser(t, s, "u@example.com")
+	other := createUser(t, s, "other@example.com")
+
+	first, err := s.CreateDataExport(ctx, u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", first.Status)
+	assert.Nil(t, first.Archive)
+	second, err := s.CreateDataExport(ctx, u.ID)
+	require.NoError(t, err)
+	theirs, err := s.CreateDataExport(ctx, other.ID)
+	require.NoError(t, err)
+
+	pending, err := s.GetPendingDataExportForUser(ctx, u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, pending.ID, "the latest one")
+	list, err := s.ListPendingDataExports(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, first.ID, list[0].ID, "oldest first")
+
+	expires := time.Now().UTC().Add(time.Hour)
+	for _, e := range []database.DataExport{first, second} {
+		n, err := s.FinishDataExport(ctx, database.FinishDataExportParams{
+			ID:        e.ID,
+			Status:    "ready",
+			ExpiresAt: sql.NullTime{Time: expires, Valid: true},
+			Archive:   []byte("PK"),
+		})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, n)
+	}
+	n, err := s.FinishDataExport(ctx, database.FinishDataExportParams{ID: first.ID, Status: "failed", Error: "again"})
+	require.NoError(t, err)
+	assert.Zero(t, n, "only pending exports are finished")
+
+	got, err := s.GetDataExport(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "ready", got.Status)
+	assert.Equal(t, []byte("PK"), got.Archive)
+	assert.True(t, got.CompletedAt.Valid)
+	assert.WithinDuration(t, expires, got.ExpiresAt.Time, time.Millisecond)
+	_, err = s.GetPendingDataExportForUser(ctx, u.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	n, err = s.DeleteExpiredDataExports(ctx, sql.NullTime{Time: expires.Add(-time.Minute), Valid: true})
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	n, err = s.DeleteExpiredDataExports(ctx, sql.NullTime{Time: expires, Valid: true})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n, "pending exports don't expire")
+	_, err = s.GetDataExport(ctx, first.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = s.DeleteUser(ctx, other.ID)
+	require.NoError(t, err)
+	_, err = s.GetDataExport(ctx, theirs.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows, "deleting the user deletes their exports")
+}
+
+func testTransactions(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	u := createUser(t, s, "a@example.com")
+
+	err := s.InTx(ctx, func(tx store.Tx) error {
+		_, err := tx.CreateChirpForUser(ctx, database.CreateChirpForUserParams{Body: "kept", UserID: u.ID})
+		return err
+	})
+	require.NoError(t, err)
+
+	failed := errors.New("failed")
+	err = s.InTx(ctx, func(tx store.Tx) error {
+		if _, err := tx.CreateChirpForUser(ctx, database.CreateChirpForUserParams{Body: "rolled back", UserID: u.ID}); err != nil {
+			return err
+		}
+		if _, err := tx.UpgradeUserToRed(ctx, u.ID); err != nil {
+			return err
+		}
+		if _, err := tx.CreateUser(ctx, database.CreateUserParams{Email: "b@example.com", HashedPassword: "hash"}); err != nil {
+			return err
+		}
+		return failed
+	})
+	require.ErrorIs(t, err, failed)
+
+	chirps, err := s.GetChirps(ctx, uuid.NullUUID{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kept"}, bodies(chirps))
+	got, err := s.GetUserByID(ctx, u.ID)
+	require.NoError(t, err)
+	assert.False(t, got.IsChirpyRed)
+	_, err = s.GetUser(ctx, "b@example.com")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = s.InTx(ctx, func(tx store.Tx) error {
+		_, err := tx.CreateUser(ctx, database.CreateUserParams{Email: "a@example.com", HashedPassword: "hash"})
+		return err
+	})
+	assert.ErrorIs(t, err, store.ErrConflict, "errors inside a transaction are reported as outside one")
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Vikuuu/Chirpy/internal/database"
+)
+
+// instrumentationName names the tracer of this package's spans.
+const instrumentationName = "github.com/Vikuuu/Chirpy/internal/tracing"
+
+// InstrumentDB starts a client span, named after the sqlc query, for every
+// query run through db.
+func InstrumentDB(tp trace.TracerProvider, db database.DBTX) database.DBTX {
+	return &tracedDB{db: db, tracer: tp.Tracer(instrumentationName)}
+}
+
+type tracedDB struct {
+	db     database.DBTX
+	tracer trace.Tracer
+}
+
+func (t *tracedDB) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	name := database.QueryName(query)
+	return t.tracer.Start(ctx, "db "+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(name),
+			attribute.String("db.query.text", query),
+		),
+	)
+}
+
+func end(span trace.Span, err error) {
+	// No rows is an answer, not a failure.
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (t *tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := t.start(ctx, query)
+	res, err := t.db.ExecContext(ctx, query, args...)
+	end(span, err)
+	return res, err
+}
+
+func (t *tracedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.db.PrepareContext(ctx, query)
+}
+
+func (t *tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := t.start(ctx, query)
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	end(span, err)
+	return rows, err
+}
+
+func (t *tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := t.start(ctx, query)
+	row := t.db.QueryRowContext(ctx, query, args...)
+	end(span, row.Err())
+	return row
+}
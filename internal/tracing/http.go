@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Vikuuu/Chirpy/internal/logging"
+)
+
+// Middleware starts a server span for each request, continuing the trace
+// named by an incoming traceparent header, and adds the trace ID to the
+// request's logging scope.
+func Middleware(tp trace.TracerProvider, next http.Handler) http.Handler {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+			logging.Add(r.Context(), slog.String("trace_id", sc.TraceID().String()))
+		}
+		next.ServeHTTP(w, r)
+	})
+	return otelhttp.NewHandler(inner, "http.request",
+		otelhttp.WithTracerProvider(tp),
+		otelhttp.WithPropagators(propagator),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method
+		}),
+	)
+}
+
+// Route names the server span after the route pattern that matched, e.g.
+// "GET /api/chirps/{chirpID}", and wraps next in a span of its own so that
+// time spent in the handler stands apart from middleware.
+func Route(tp trace.TracerProvider, pattern string, next http.Handler) http.Handler {
+	tracer := tp.Tracer(instrumentationName)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.Method + " " + pattern
+		server := trace.SpanFromContext(r.Context())
+		server.SetName(name)
+		server.SetAttributes(semconv.HTTPRoute(pattern))
+
+		ctx, span := tracer.Start(r.Context(), "handler "+pattern,
+			trace.WithAttributes(attribute.String("handler.route", pattern)))
+		defer span.End()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,83 @@
+// Package tracing sets up OpenTelemetry tracing: the exporter spans are sent
+// to, W3C trace context propagation, and spans for HTTP routes and database
+// queries.
+//
+// Packages that only create spans use the global provider through
+// otel.Tracer, so they need nothing from here.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// ServiceName identifies this service's spans.
+const ServiceName = "chirpy"
+
+// Exporters accepted by NewExporter, named as in OTEL_TRACES_EXPORTER.
+const (
+	ExporterNone    = "none"
+	ExporterOTLP    = "otlp"
+	ExporterConsole = "console"
+)
+
+// NewExporter returns the span exporter called name, or nil for "none".
+// The OTLP exporter sends spans to endpoint, a collector URL such as
+// "http://localhost:4318"; left empty, the standard OTEL_EXPORTER_OTLP_*
+// variables decide. The console exporter writes spans to w.
+func NewExporter(ctx context.Context, name, endpoint string, w io.Writer) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case ExporterConsole:
+		return stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", name)
+	}
+}
+
+// NewProvider returns a tracer provider batching spans to exp. A nil exp
+// gives a provider that samples nothing, so spans cost next to nothing but
+// incoming trace context is still passed on.
+func NewProvider(exp sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	res := resource.NewSchemaless(semconv.ServiceName(ServiceName))
+	if exp == nil {
+		return sdktrace.NewTracerProvider(
+			sdktrace.WithResource(res),
+			sdktrace.WithSampler(sdktrace.NeverSample()),
+		)
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithResource(res),
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+	)
+}
+
+// propagator reads and writes W3C traceparent and baggage headers.
+var propagator = propagation.NewCompositeTextMapPropagator(
+	propagation.TraceContext{},
+	propagation.Baggage{},
+)
+
+// Install makes tp the global tracer provider and W3C trace context the
+// global propagator, for outgoing requests.
+func Install(tp *sdktrace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagator)
+}
@@ -0,0 +1,147 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestProvider records every span in memory.
+func newTestProvider(t *testing.T) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+	return tp, exp
+}
+
+func spanNamed(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, s := range spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	require.Failf(t, "span not found", "no span named %q in %d spans", name, len(spans))
+	return tracetest.SpanStub{}
+}
+
+func TestMiddlewareContinuesTrace(t *testing.T) {
+	tp, exp := newTestProvider(t)
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/chirps/{chirpID}", Route(tp, "/api/chirps/{chirpID}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	h := Middleware(tp, mux)
+	req := httptest.NewRequest("GET", "/api/chirps/123", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exp.GetSpans()
+	server := spanNamed(t, spans, "GET /api/chirps/{chirpID}")
+	handler := spanNamed(t, spans, "handler /api/chirps/{chirpID}")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+	assert.Contains(t, server.Attributes, attribute.String("http.route", "/api/chirps/{chirpID}"))
+	assert.Equal(t, server.SpanContext.SpanID(), handler.Parent.SpanID())
+}
+
+func TestMiddlewareStartsTrace(t *testing.T) {
+	tp, exp := newTestProvider(t)
+	h := Middleware(tp, http.NotFoundHandler())
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nope", nil))
+
+	spans := exp.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET", spans[0].Name)
+	assert.False(t, spans[0].Parent.IsValid())
+}
+
+type fakeResult struct{}
+
+func (fakeResult) LastInsertId() (int64, error) { return 0, nil }
+func (fakeResult) RowsAffected() (int64, error) { return 1, nil }
+
+type fakeDB struct{ err error }
+
+func (f fakeDB) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return fakeResult{}, f.err
+}
+
+func (fakeDB) PrepareContext(context.Context, string) (*sql.Stmt, error) { return nil, nil }
+
+func (f fakeDB) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, f.err
+}
+
+func (fakeDB) QueryRowContext(context.Context, string, ...interface{}) *sql.Row { return nil }
+
+func TestInstrumentDB(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected codes.Code
+	}{
+		{name: "Success", expected: codes.Unset},
+		{name: "No Rows", err: sql.ErrNoRows, expected: codes.Unset},
+		{name: "Failure", err: errors.New("connection reset"), expected: codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tp, exp := newTestProvider(t)
+			db := InstrumentDB(tp, fakeDB{err: tt.err})
+			ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+
+			db.ExecContext(ctx, "-- name: DeleteChirp :exec\nDELETE FROM chirps WHERE id = $1", 1)
+			parent.End()
+
+			span := spanNamed(t, exp.GetSpans(), "db DeleteChirp")
+			assert.Equal(t, trace.SpanKindClient, span.SpanKind)
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+			assert.Contains(t, span.Attributes, attribute.String("db.operation.name", "DeleteChirp"))
+			assert.Equal(t, tt.expected, span.Status.Code)
+		})
+	}
+}
+
+func TestNewExporter(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "", wantNil: true},
+		{name: ExporterNone, wantNil: true},
+		{name: ExporterConsole},
+		{name: ExporterOTLP},
+		{name: "zipkin", wantNil: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp, err := NewExporter(context.Background(), tt.name, "http://localhost:4318", io.Discard)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantNil, exp == nil)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// Package validate checks request structs against rules declared in their
+// `validate` struct tags.
+//
+// Rules are separated by commas:
+//
+//	required     the field must not be its zero value
+//	email        a bare address such as "walt@breakingbad.com"
+//	min=N, max=N length bounds in characters for strings
+//	maxbytes=N   length bound in bytes, for strings whose consumer counts
+//	             bytes, such as bcrypt
+//	oneof=a b c  the value must be one of the listed words
+//
+// All rules but required pass on a zero value, so optional fields only need
+// to satisfy them when set. Fields are reported by their JSON names.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Error codes reported in FieldError.Code.
+const (
+	CodeRequired     = "required"
+	CodeInvalidEmail = "invalid_email"
+	CodeTooShort     = "too_short"
+	CodeTooLong      = "too_long"
+	CodeInvalidValue = "invalid_choice"
+)
+
+// FieldError is a rule that a field broke.
+type FieldError struct {
+	Field   string
+	Code    string
+	Message string
+}
+
+// Struct checks v, a struct or pointer to one, and returns every rule that
+// failed, at most one per field. It panics on a malformed tag, which is a bug
+// in the struct declaration rather than in the request.
+func Struct(v any) []FieldError {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validate: %T is not a struct", v))
+	}
+	var errs []FieldError
+	check(rv, "", &errs)
+	return errs
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func check(rv reflect.Value, prefix string, errs *[]FieldError) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := prefix + jsonName(f)
+		fv := rv.Field(i)
+
+		if tag, ok := f.Tag.Lookup("validate"); ok {
+			if fe, failed := checkField(fv, name, tag); failed {
+				*errs = append(*errs, fe)
+				continue
+			}
+		}
+		if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+			check(fv, name+".", errs)
+		}
+	}
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
+
+func checkField(fv reflect.Value, name, tag string) (FieldError, bool) {
+	for _, rule := range strings.Split(tag, ",") {
+		key, arg, _ := strings.Cut(rule, "=")
+		if key == "required" {
+			if fv.IsZero() {
+				return FieldError{Field: name, Code: CodeRequired, Message: name + " is required"}, true
+			}
+			continue
+		}
+		if fv.IsZero() {
+			continue
+		}
+
+		switch key {
+		case "email":
+			s := fv.String()
+			addr, err := mail.ParseAddress(s)
+			if err != nil || addr.Address != s {
+				return FieldError{Field: name, Code: CodeInvalidEmail, Message: name + " is not a valid email address"}, true
+			}
+		case "min":
+			if utf8.RuneCountInString(fv.String()) < atoi(name, arg) {
+				return FieldError{Field: name, Code: CodeTooShort, Message: fmt.Sprintf("%s must be at least %s characters", name, arg)}, true
+			}
+		case "max":
+			if utf8.RuneCountInString(fv.String()) > atoi(name, arg) {
+				return FieldError{Field: name, Code: CodeTooLong, Message: fmt.Sprintf("%s must be at most %s characters", name, arg)}, true
+			}
+		case "maxbytes":
+			if len(fv.String()) > atoi(name, arg) {
+				return FieldError{Field: name, Code: CodeTooLong, Message: fmt.Sprintf("%s must be at most %s bytes", name, arg)}, true
+			}
+		case "oneof":
+			choices := strings.Fields(arg)
+			if !slices.Contains(choices, fv.String()) {
+				return FieldError{Field: name, Code: CodeInvalidValue, Message: fmt.Sprintf("%s must be one of %s", name, strings.Join(choices, ", "))}, true
+			}
+		default:
+			panic(fmt.Sprintf("validate: unknown rule %q on %s", key, name))
+		}
+	}
+	return FieldError{}, false
+}
+
+func atoi(name, arg string) int {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		panic(fmt.Sprintf("validate: bad length %q on %s", arg, name))
+	}
+	return n
+}
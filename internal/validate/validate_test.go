@@ -0,0 +1,109 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type signup struct {
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"required,min=8,maxbytes=72"`
+	Plan     string `json:"plan" validate:"oneof=free red"`
+	Profile  struct {
+		Bio string `json:"bio" validate:"max=10"`
+	} `json:"profile"`
+	Internal string
+}
+
+func TestStruct(t *testing.T) {
+	valid := func() signup {
+		return signup{Email: "walt@breakingbad.com", Password: "04234abcd"}
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(s *signup)
+		expected []FieldError
+	}{
+		{name: "Valid", modify: func(s *signup) {}},
+		{name: "Optional Oneof Set", modify: func(s *signup) { s.Plan = "red" }},
+		{
+			name:   "Missing Email",
+			modify: func(s *signup) { s.Email = "" },
+			expected: []FieldError{
+				{Field: "email", Code: CodeRequired, Message: "email is required"},
+			},
+		},
+		{
+			name:   "Bad Email",
+			modify: func(s *signup) { s.Email = "walt" },
+			expected: []FieldError{
+				{Field: "email", Code: CodeInvalidEmail, Message: "email is not a valid email address"},
+			},
+		},
+		{
+			name:   "Email With Display Name",
+			modify: func(s *signup) { s.Email = "Walt <walt@breakingbad.com>" },
+			expected: []FieldError{
+				{Field: "email", Code: CodeInvalidEmail, Message: "email is not a valid email address"},
+			},
+		},
+		{
+			name:   "Short Password",
+			modify: func(s *signup) { s.Password = "short" },
+			expected: []FieldError{
+				{Field: "password", Code: CodeTooShort, Message: "password must be at least 8 characters"},
+			},
+		},
+		{
+			name:   "Byte Length Counts Bytes",
+			modify: func(s *signup) { s.Password = strings.Repeat("é", 40) },
+			expected: []FieldError{
+				{Field: "password", Code: CodeTooLong, Message: "password must be at most 72 bytes"},
+			},
+		},
+		{
+			name:   "Length Counts Characters",
+			modify: func(s *signup) { s.Profile.Bio = "ünïcödé!!!" },
+		},
+		{
+			name:   "Nested Too Long",
+			modify: func(s *signup) { s.Profile.Bio = "far too long a bio" },
+			expected: []FieldError{
+				{Field: "profile.bio", Code: CodeTooLong, Message: "profile.bio must be at most 10 characters"},
+			},
+		},
+		{
+			name:   "Bad Choice",
+			modify: func(s *signup) { s.Plan = "gold" },
+			expected: []FieldError{
+				{Field: "plan", Code: CodeInvalidValue, Message: "plan must be one of free, red"},
+			},
+		},
+		{
+			name:   "Several Fields",
+			modify: func(s *signup) { s.Email, s.Password = "", "" },
+			expected: []FieldError{
+				{Field: "email", Code: CodeRequired, Message: "email is required"},
+				{Field: "password", Code: CodeRequired, Message: "password is required"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid()
+			tt.modify(&s)
+			assert.Equal(t, tt.expected, Struct(&s))
+		})
+	}
+}
+
+func TestStructBadTag(t *testing.T) {
+	type bad struct {
+		Name string `validate:"shiny"`
+	}
+	assert.Panics(t, func() { Struct(bad{Name: "x"}) })
+}
@@ -1,73 +1,262 @@
 package main
 
 import (
-	"database/sql"
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
 	"sync/atomic"
+	"syscall"
+	"time"
+	"unicode/utf8"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"go.opentelemetry.io/otel"
 
+	"github.com/Vikuuu/Chirpy/internal/config"
 	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/health"
+	"github.com/Vikuuu/Chirpy/internal/logging"
+	"github.com/Vikuuu/Chirpy/internal/metrics"
+	"github.com/Vikuuu/Chirpy/internal/profanity"
+	"github.com/Vikuuu/Chirpy/internal/ratelimit"
+	"github.com/Vikuuu/Chirpy/internal/service"
+	"github.com/Vikuuu/Chirpy/internal/spam"
+	"github.com/Vikuuu/Chirpy/internal/store"
+	"github.com/Vikuuu/Chirpy/internal/tracing"
 )
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
-	db             *database.Queries
+	db             store.Store
+	svc            *service.Service
 	secret         string
 	polkaKey       string
+	platform       string
+	filter         *profanity.Filter
+	rateLimiter    ratelimit.Store
+	rateLimits     map[string]ratelimit.Policy
+	trustProxy     bool
+	queryTimeouts  queryTimeouts
+	health         *health.Checker
+	metrics        *metrics.Metrics
+	deletionGrace  time.Duration
+
+	// writeTimeout is the server's time allowed to write a response, which
+	// streaming handlers extend as they go.
+	writeTimeout time.Duration
+	// wordsReloadInterval is how often the banned words are reloaded.
+	wordsReloadInterval time.Duration
+
+	// draining is set once shutdown starts.
+	draining atomic.Bool
 }
 
 func main() {
-	const filepathRoot = "."
-	const port = "8080"
 	godotenv.Load()
 
-	dbURL := os.Getenv("DB_URL")
-	db, err := sql.Open("postgres", dbURL)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := execute(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run serves the API until ctx is done. On the way out it drains requests,
+// then stops background workers, and closes the database last since both of
+// those may still be using it.
+func run(ctx context.Context, conf config.Config) error {
+	tp, err := newTracerProvider(ctx, conf.Tracing)
 	if err != nil {
-		log.Fatal("Connection cannot be made to DB")
+		return fmt.Errorf("setting up tracing: %w", err)
 	}
-	defer db.Close()
+	tracing.Install(tp)
+	// Flush spans after everything else has shut down.
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tp.Shutdown(ctx); err != nil {
+			slog.Error("flushing spans", "err", err)
+		}
+	}()
 
-	dbQueries := database.New(db)
+	m := metrics.New()
+	st, migrator, err := newStore(ctx, conf.Database, func(db database.DBTX) database.DBTX {
+		return tracing.InstrumentDB(tp, m.InstrumentDB(db))
+	})
+	if err != nil {
+		return fmt.Errorf("opening %s store: %w", conf.Database.Store, err)
+	}
+	defer st.Close()
+	if pg, ok := st.(*store.Postgres); ok {
+		m.RegisterDBStats(pg.DB())
+	}
 
-	mux := http.NewServeMux()
+	rateLimiter, err := newRateLimitStore(conf.RateLimit.Store, st)
+	if err != nil {
+		return err
+	}
+	rateLimits, err := parseRateLimits(conf.RateLimit.Routes)
+	if err != nil {
+		return err
+	}
+	spamCfg, err := spamConfig(conf.Spam)
+	if err != nil {
+		return err
+	}
+
+	apiCfg := &apiConfig{
+		db:            st,
+		secret:        conf.Auth.Secret,
+		polkaKey:      conf.Auth.PolkaKey,
+		platform:      conf.Platform,
+		filter:        profanity.New(profanityOptions(conf.Profanity)),
+		rateLimiter:   rateLimiter,
+		rateLimits:    rateLimits,
+		trustProxy:    conf.Server.TrustProxy,
+		queryTimeouts: newQueryTimeouts(conf.Queries),
+		writeTimeout:  conf.Server.WriteTimeout,
+		health:        health.New(2 * time.Second),
+		metrics:       m,
+		deletionGrace: conf.Accounts.DeletionGracePeriod,
+
+		wordsReloadInterval: conf.Profanity.ReloadInterval,
+	}
+	apiCfg.svc = service.New(st, service.Config{
+		Filter:        apiCfg.filter,
+		Spam:          spamCfg,
+		Secret:        apiCfg.secret,
+		DeletionGrace: apiCfg.deletionGrace,
+	})
+	m.RegisterCounterFunc("fileserver_hits_total", "Requests for the web app under /app/.", func() float64 {
+		return float64(apiCfg.fileserverHits.Load())
+	})
+	if err := apiCfg.loadBannedWords(ctx); err != nil {
+		return fmt.Errorf("loading banned words: %w", err)
+	}
+
+	bg := newWorkers()
+	defer bg.Stop()
+	bg.Go("rate_limit_sweeper", apiCfg.sweepRateLimits)
+	bg.Go("account_jobs", apiCfg.runAccountJobs)
+	bg.Go("banned_words_reloader", apiCfg.reloadBannedWords)
+	apiCfg.registerHealthChecks(st, migrator, bg)
 
 	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: mux,
+		Addr:              ":" + strconv.Itoa(conf.Server.Port),
+		Handler:           apiCfg.routes(conf.Server.Root),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       conf.Server.ReadTimeout,
+		WriteTimeout:      conf.Server.WriteTimeout,
+		IdleTimeout:       conf.Server.IdleTimeout,
 	}
-	apiCfg := apiConfig{
-		fileserverHits: atomic.Int32{},
-		db:             dbQueries,
-		secret:         os.Getenv("SECRET"),
-		polkaKey:       os.Getenv("POLKA_KEY"),
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
 	}
 
-	mux.Handle(
+	slog.Info("serving", "root", conf.Server.Root, "addr", ln.Addr().String())
+	return apiCfg.serve(ctx, srv, ln, shutdownOptions{
+		Delay:   conf.Server.ShutdownDelay,
+		Timeout: conf.Server.ShutdownTimeout,
+	})
+}
+
+// routes builds the handler serving the whole API, with static files for the
+// web app served from filepathRoot.
+func (apiCfg *apiConfig) routes(filepathRoot string) http.Handler {
+	tp := otel.GetTracerProvider()
+	mux := http.NewServeMux()
+	handle := func(pattern string, h http.Handler) {
+		route := routeLabel(pattern)
+		h = apiCfg.metrics.InstrumentHandler(route, h)
+		mux.Handle(pattern, tracing.Route(tp, route, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logging.Add(r.Context(), slog.String("route", route))
+			h.ServeHTTP(w, r)
+		})))
+	}
+
+	handle(
 		"/app/",
 		http.StripPrefix(
 			"/app",
 			apiCfg.middlewareMetricsInc(http.FileServer(http.Dir(filepathRoot))),
 		),
 	)
-	mux.HandleFunc("GET  /api/healthz", handlerHealth)
-	mux.HandleFunc("GET  /admin/metrics", apiCfg.handlerMetric)
-	mux.HandleFunc("POST /admin/reset", apiCfg.handlerReset)
-	mux.HandleFunc("POST /api/chirps", apiCfg.handlerPostChirp)
-	mux.HandleFunc("POST /api/users", apiCfg.handlerUser)
-	mux.HandleFunc("GET  /api/chirps", apiCfg.handlerGetChirps)
-	mux.HandleFunc("GET  /api/chirps/{chirpID}", apiCfg.handlerGetChirp)
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handlerDeleteChirp)
-	mux.HandleFunc("POST /api/login", apiCfg.handlerLogin)
-	mux.HandleFunc("POST /api/refresh", apiCfg.handlerRefresh)
-	mux.HandleFunc("POST /api/revoke", apiCfg.handlerRevoke)
-	mux.HandleFunc("PUT  /api/users", apiCfg.handlerUpdateUser)
-	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlerPolkaWebhook)
-
-	log.Printf("Serving file from %s on port: %s\n", filepathRoot, port)
-	log.Fatal(srv.ListenAndServe())
+	handle("GET  /api/livez", health.LiveHandler())
+	handle("GET  /api/readyz", apiCfg.health.ReadyHandler())
+	// Kept for clients that predate /api/readyz.
+	handle("GET  /api/healthz", apiCfg.health.ReadyHandler())
+	handle("GET  /metrics", apiCfg.metrics.Handler())
+	handle("GET  /admin/metrics", http.HandlerFunc(apiCfg.handlerMetric))
+	handle("POST /admin/reset", apiCfg.route("admin_reset", apiCfg.handlerReset))
+	handle("POST /api/chirps", apiCfg.rateLimit("chirps_create", apiCfg.route("chirps_create", apiCfg.handlerPostChirp)))
+	handle("POST /api/users", apiCfg.rateLimit("users_create", apiCfg.route("users_create", apiCfg.handlerUser)))
+	handle("GET  /api/chirps", apiCfg.route("chirps_list", apiCfg.handlerGetChirps))
+	handle("GET  /api/chirps/{chirpID}", apiCfg.route("chirps_get", apiCfg.handlerGetChirp))
+	handle("DELETE /api/chirps/{chirpID}", apiCfg.route("chirps_delete", apiCfg.handlerDeleteChirp))
+	handle("POST /api/login", apiCfg.rateLimit("login", apiCfg.route("login", apiCfg.handlerLogin)))
+	handle("POST /api/refresh", apiCfg.route("refresh", apiCfg.handlerRefresh))
+	handle("POST /api/revoke", apiCfg.route("revoke", apiCfg.handlerRevoke))
+	handle("PUT  /api/users", apiCfg.route("users_update", apiCfg.handlerUpdateUser))
+	handle("DELETE /api/users", apiCfg.route("users_delete", apiCfg.handlerDeleteUser))
+	handle("POST /api/users/export", apiCfg.route("users_export", apiCfg.handlerRequestExport))
+	handle("GET  /api/users/export/{exportID}", apiCfg.route("users_export_get", apiCfg.handlerGetExport))
+	handle("GET  /api/users/export/{exportID}/download", apiCfg.route("users_export_download", apiCfg.handlerDownloadExport))
+	handle("POST /api/polka/webhooks", apiCfg.route("polka_webhooks", apiCfg.handlerPolkaWebhook))
+	handle("GET  /admin/words", apiCfg.route("words_list", apiCfg.handlerListWords))
+	handle("POST /admin/words", apiCfg.route("words_put", apiCfg.handlerPutWord))
+	handle("DELETE /admin/words/{word}", apiCfg.route("words_delete", apiCfg.handlerDeleteWord))
+	handle("POST /api/reports", apiCfg.route("reports_create", apiCfg.handlerCreateReport))
+	handle("GET  /api/reports", apiCfg.route("reports_list", apiCfg.handlerListMyReports))
+	handle("GET  /api/moderation-actions", apiCfg.route("moderation_actions_list", apiCfg.handlerListMyModerationActions))
+	handle("GET  /admin/reports", apiCfg.route("admin_reports_list", apiCfg.handlerListReports))
+	handle("POST /admin/reports/{reportID}/actions", apiCfg.route("admin_reports_action", apiCfg.handlerModerateReport))
+	handle("POST /admin/reports/{reportID}/dismiss", apiCfg.route("admin_reports_dismiss", apiCfg.handlerDismissReport))
+	handle("POST /admin/users/{userID}/actions", apiCfg.route("admin_users_action", apiCfg.handlerModerateUser))
+	handle("GET  /admin/audit-events", apiCfg.route("admin_audit_events_list", apiCfg.handlerListAuditEvents))
+	handle("GET  /admin/audit-events/export", apiCfg.streamRoute("admin_audit_events_export", apiCfg.handlerExportAuditEvents))
+
+	return logging.AccessLog(slog.Default(), tracing.Middleware(tp, withRequestID(recoverPanics(problemFallback(mux)))))
+}
+
+// routeLabel is the path of a mux pattern such as "GET  /api/chirps/{chirpID}",
+// used to label metrics and access logs without one series per chirp.
+func routeLabel(pattern string) string {
+	fields := strings.Fields(pattern)
+	return fields[len(fields)-1]
+}
+
+// profanityOptions converts the configured masking style.
+func profanityOptions(c config.Profanity) profanity.Options {
+	opts := profanity.Options{KeepFirst: c.KeepFirst}
+	if c.Mask != "" {
+		opts.MaskRune, _ = utf8.DecodeRuneInString(c.Mask)
+	}
+	return opts
+}
+
+// spamConfig parses the configured spam thresholds.
+func spamConfig(c config.Spam) (spam.Config, error) {
+	cfg := spam.Config{Window: c.Window, NearDistance: c.NearDistance}
+	for name, t := range map[string]struct {
+		spec string
+		dst  *spam.Threshold
+	}{
+		"duplicates": {c.Duplicates, &cfg.Duplicates},
+		"link_only":  {c.LinkOnly, &cfg.LinkOnly},
+		"mentions":   {c.Mentions, &cfg.Mentions},
+	} {
+		parsed, err := spam.ParseThreshold(t.spec)
+		if err != nil {
+			return cfg, fmt.Errorf("spam %s: %w", name, err)
+		}
+		*t.dst = parsed
+	}
+	return cfg, nil
 }
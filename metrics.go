@@ -3,18 +3,36 @@ package main
 import (
 	"fmt"
 	"net/http"
+
+	"github.com/Vikuuu/Chirpy/internal/metrics"
 )
 
+// handlerMetric renders a summary of the Prometheus registry for admins.
 func (cfg *apiConfig) handlerMetric(w http.ResponseWriter, r *http.Request) {
-	r.Header.Add("Content-Type", "text/html")
+	m := cfg.metrics
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(fmt.Sprintf(`
 		<html>
 		  <body>
 			<h1>Welcome, Chirpy Admin</h1>
 			<p>Chirpy has been visited %d times!</p>
+			<p>Chirps created: %d</p>
+			<p>Logins: %d succeeded, %d failed, %d refused for restricted accounts</p>
+			<p>Failed requests: %d</p>
+			<p>Requests cancelled by the client: %d</p>
+			<p>Requests timed out: %d</p>
 		  </body>
-		</html>`, cfg.fileserverHits.Load())))
+		</html>`,
+		int64(m.Total("chirpy_fileserver_hits_total")),
+		int64(m.Total("chirpy_chirps_created_total")),
+		int64(m.Total("chirpy_logins_total", "outcome", metrics.LoginSuccess)),
+		int64(m.Total("chirpy_logins_total", "outcome", metrics.LoginFailure)),
+		int64(m.Total("chirpy_logins_total", "outcome", metrics.LoginRestricted)),
+		int64(m.Total("chirpy_http_request_errors_total", "kind", metrics.ErrorFailed)),
+		int64(m.Total("chirpy_http_request_errors_total", "kind", metrics.ErrorCancelled)),
+		int64(m.Total("chirpy_http_request_errors_total", "kind", metrics.ErrorTimedOut)),
+	)))
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
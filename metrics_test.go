@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsEndpoints(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.metrics.RegisterCounterFunc("fileserver_hits_total", "Requests for the web app under /app/.", func() float64 {
+		return float64(cfg.fileserverHits.Load())
+	})
+	handler := cfg.routes(".")
+
+	for _, path := range []string{"/app/", "/api/chirps/not-a-uuid"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `chirpy_http_requests_total{method="GET",route="/api/chirps/{chirpID}",status="400"} 1`)
+	assert.Contains(t, rec.Body.String(), `chirpy_fileserver_hits_total 1`)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/metrics", nil))
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "Chirpy has been visited 1 times!")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/Vikuuu/Chirpy/internal/config"
+	"github.com/Vikuuu/Chirpy/internal/migrate"
+)
+
+// runMigrate applies or rolls back the embedded migrations. Only the database
+// settings need to be configured.
+func runMigrate(ctx context.Context, inv *invocation) error {
+	args, err := inv.loadConfig(config.Config.ValidateDatabase)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usageError{"want exactly one of up, down, status or redo"}
+	}
+	if inv.conf.Database.Store != config.StorePostgres {
+		return fmt.Errorf("only the %s store is migrated; %s creates its schema when opened", config.StorePostgres, inv.conf.Database.Store)
+	}
+
+	db, err := sql.Open("postgres", inv.conf.Database.URL)
+	if err != nil {
+		return fmt.Errorf("connecting to DB: %w", err)
+	}
+	defer db.Close()
+	migrator, err := migrate.New(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		results, err := migrator.Up(ctx)
+		migrate.PrintResults(inv.stdout, results...)
+		if err == nil && len(results) == 0 {
+			fmt.Fprintln(inv.stdout, "schema is up to date")
+		}
+		return err
+	case "down":
+		result, err := migrator.Down(ctx)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			fmt.Fprintln(inv.stdout, "no migrations to roll back")
+			return nil
+		}
+		migrate.PrintResults(inv.stdout, result)
+		return err
+	case "redo":
+		results, err := migrator.Redo(ctx)
+		migrate.PrintResults(inv.stdout, results...)
+		return err
+	case "status":
+		return migrator.PrintStatus(ctx, inv.stdout)
+	default:
+		return usageError{fmt.Sprintf("unknown migration command %q", args[0])}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/api"
+	"github.com/Vikuuu/Chirpy/internal/service"
+)
+
+func (cfg *apiConfig) handlerListReports(w http.ResponseWriter, r *http.Request) error {
+	if _, err := cfg.authorizeRole(r, service.RoleModerator, service.RoleAdmin); err != nil {
+		return err
+	}
+
+	reports, err := cfg.svc.ReportsByStatus(r.Context(), r.URL.Query().Get("status"))
+	if err != nil {
+		return err
+	}
+
+	return respondWithJSON(w, http.StatusOK, api.NewReports(reports, true))
+}
+
+// moderation converts a moderation request for the service.
+func moderation(params api.ModerationRequest) service.Moderation {
+	return service.Moderation{Action: params.Action, Reason: params.Reason, Duration: params.Duration}
+}
+
+func (cfg *apiConfig) handlerModerateReport(w http.ResponseWriter, r *http.Request) error {
+	moderator, err := cfg.authorizeRole(r, service.RoleModerator, service.RoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		return errValidation("invalid report id")
+	}
+
+	params := api.ModerationRequest{}
+	if err := decodeJSON(w, r, &params); err != nil {
+		return err
+	}
+
+	report, action, err := cfg.svc.ModerateReport(r.Context(), moderator.ID, reportID, moderation(params))
+	if err != nil {
+		return err
+	}
+
+	return respondWithJSON(w, http.StatusOK, api.ModerationResult{
+		Report: api.NewReport(report, true),
+		Action: api.NewModerationAction(action, true),
+	})
+}
+
+// handlerModerateUser restricts or reinstates an account directly, without a
+// report.
+func (cfg *apiConfig) handlerModerateUser(w http.ResponseWriter, r *http.Request) error {
+	moderator, err := cfg.authorizeRole(r, service.RoleModerator, service.RoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		return errValidation("invalid user id")
+	}
+
+	params := api.ModerationRequest{}
+	if err := decodeJSON(w, r, &params); err != nil {
+		return err
+	}
+
+	action, err := cfg.svc.ModerateUser(r.Context(), moderator.ID, userID, moderation(params))
+	if err != nil {
+		return err
+	}
+
+	return respondWithJSON(w, http.StatusOK, api.NewModerationAction(action, true))
+}
+
+func (cfg *apiConfig) handlerDismissReport(w http.ResponseWriter, r *http.Request) error {
+	moderator, err := cfg.authorizeRole(r, service.RoleModerator, service.RoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		return errValidation("invalid report id")
+	}
+
+	params := api.ModerationRequest{}
+	if err := decodeJSON(w, r, &params); err != nil {
+		return err
+	}
+
+	report, err := cfg.svc.DismissReport(r.Context(), moderator.ID, reportID, params.Reason)
+	if err != nil {
+		return err
+	}
+
+	return respondWithJSON(w, http.StatusOK, api.NewReport(report, true))
+}
+
+// handlerListMyModerationActions shows the caller the actions moderators took
+// against their account and chirps.
+func (cfg *apiConfig) handlerListMyModerationActions(w http.ResponseWriter, r *http.Request) error {
+	user, err := cfg.authenticate(r)
+	if err != nil {
+		return err
+	}
+
+	actions, err := cfg.svc.ModerationActionsAgainst(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	resp := make([]api.ModerationAction, 0, len(actions))
+	for _, a := range actions {
+		resp = append(resp, api.NewModerationAction(a, false))
+	}
+
+	return respondWithJSON(w, http.StatusOK, resp)
+}
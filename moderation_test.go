@@ -0,0 +1,250 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Vikuuu/Chirpy/internal/api"
+	"github.com/Vikuuu/Chirpy/internal/service"
+)
+
+func TestCreateReport(t *testing.T) {
+	h := newHarness(t)
+	walt := h.signUp("walt@example.com")
+	jesse := h.signUp("jesse@example.com")
+	c := walt.post("the cook is ready")
+
+	r := jesse.reportChirp(c, "spam")
+	assert.Equal(t, service.ReportStatusOpen, r.Status)
+	assert.Equal(t, walt.ID, r.UserID)
+	require.NotNil(t, r.ChirpID)
+	assert.Equal(t, c.ID, *r.ChirpID)
+
+	var userReport api.Report
+	jesse.do("POST", "/api/reports").
+		json(api.CreateReportRequest{TargetType: service.ReportTargetUser, UserID: walt.ID, Reason: "impersonation"}).
+		expect(http.StatusCreated).
+		decode(&userReport)
+	assert.Nil(t, userReport.ChirpID)
+
+	var mine []api.Report
+	jesse.do("GET", "/api/reports").expect(http.StatusOK).decode(&mine)
+	assert.Len(t, mine, 2)
+	walt.do("GET", "/api/reports").expect(http.StatusOK).decode(&mine)
+	assert.Empty(t, mine)
+
+	tests := []struct {
+		name     string
+		token    string
+		body     api.CreateReportRequest
+		expected int
+	}{
+		{name: "No Token", body: api.CreateReportRequest{TargetType: service.ReportTargetChirp, ChirpID: c.ID, Reason: "spam"}, expected: http.StatusUnauthorized},
+		{name: "Own Chirp", token: walt.Token, body: api.CreateReportRequest{TargetType: service.ReportTargetChirp, ChirpID: c.ID, Reason: "spam"}, expected: http.StatusBadRequest},
+		{name: "Self", token: walt.Token, body: api.CreateReportRequest{TargetType: service.ReportTargetUser, UserID: walt.ID, Reason: "spam"}, expected: http.StatusBadRequest},
+		{name: "Missing Chirp", token: jesse.Token, body: api.CreateReportRequest{TargetType: service.ReportTargetChirp, ChirpID: uuid.New(), Reason: "spam"}, expected: http.StatusNotFound},
+		{name: "Missing User", token: jesse.Token, body: api.CreateReportRequest{TargetType: service.ReportTargetUser, UserID: uuid.New(), Reason: "spam"}, expected: http.StatusNotFound},
+		{name: "Unknown Reason", token: jesse.Token, body: api.CreateReportRequest{TargetType: service.ReportTargetChirp, ChirpID: c.ID, Reason: "boring"}, expected: http.StatusBadRequest},
+		{name: "Unknown Target", token: jesse.Token, body: api.CreateReportRequest{TargetType: "planet", Reason: "spam"}, expected: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := h.do("POST", "/api/reports").json(tt.body)
+			if tt.token != "" {
+				req.bearer(tt.token)
+			}
+			req.expect(tt.expected).problem()
+		})
+	}
+}
+
+func TestListReportsNeedsModerator(t *testing.T) {
+	h := newHarness(t)
+	u := h.signUp("walt@example.com")
+	moderator := h.signUp("mod@example.com").as(service.RoleModerator)
+	admin := h.signUp("admin@example.com").as(service.RoleAdmin)
+
+	h.do("GET", "/admin/reports").expect(http.StatusUnauthorized)
+	p := u.do("GET", "/admin/reports").expect(http.StatusForbidden).problem()
+	assert.Equal(t, codeForbidden, p.Code)
+	moderator.do("GET", "/admin/reports").expect(http.StatusOK)
+	admin.do("GET", "/admin/reports").expect(http.StatusOK)
+	admin.do("GET", "/admin/reports?status=pending").expect(http.StatusBadRequest)
+}
+
+func TestModerateReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		request api.ModerationRequest
+		// visible reports whether the chirp is still listed afterwards.
+		visible bool
+		// restricted reports whether the author is locked out afterwards.
+		restricted bool
+	}{
+		{name: "Warn", request: api.ModerationRequest{Action: service.ActionWarn, Reason: "be nice"}, visible: true},
+		{name: "Hide Chirp", request: api.ModerationRequest{Action: service.ActionHideChirp, Reason: "off topic"}},
+		{name: "Delete Chirp", request: api.ModerationRequest{Action: service.ActionDeleteChirp, Reason: "illegal"}},
+		{name: "Suspend", request: api.ModerationRequest{Action: service.ActionSuspend, Reason: "cool off", Duration: "72h"}, visible: true, restricted: true},
+		{name: "Ban", request: api.ModerationRequest{Action: service.ActionBan, Reason: "gone"}, visible: true, restricted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t)
+			walt := h.signUp("walt@example.com")
+			jesse := h.signUp("jesse@example.com")
+			moderator := h.signUp("mod@example.com").as(service.RoleModerator)
+			c := walt.post("los pollos hermanos")
+			report := jesse.reportChirp(c, "other")
+
+			var result api.ModerationResult
+			moderator.do("POST", "/admin/reports/"+report.ID.String()+"/actions").
+				json(tt.request).
+				expect(http.StatusOK).
+				decode(&result)
+			assert.Equal(t, service.ReportStatusActioned, result.Report.Status)
+			assert.Equal(t, tt.request.Action, result.Action.Action)
+			assert.Equal(t, walt.ID, result.Action.TargetUserID)
+
+			if tt.visible {
+				assert.Equal(t, []string{"los pollos hermanos"}, h.chirps(nil, "/api/chirps"))
+			} else {
+				assert.Empty(t, h.chirps(nil, "/api/chirps"))
+				h.do("GET", "/api/chirps/"+c.ID.String()).expect(http.StatusNotFound)
+			}
+
+			if tt.restricted {
+				walt.do("POST", "/api/chirps").json(api.CreateChirpRequest{Body: "am I still here?"}).expect(http.StatusForbidden)
+				walt.do("GET", "/api/moderation-actions").expect(http.StatusForbidden)
+			} else {
+				walt.post("am I still here?")
+				var actions []api.ModerationAction
+				walt.do("GET", "/api/moderation-actions").expect(http.StatusOK).decode(&actions)
+				require.Len(t, actions, 1)
+				assert.Equal(t, tt.request.Action, actions[0].Action)
+			}
+
+			var mine []api.Report
+			jesse.do("GET", "/api/reports").expect(http.StatusOK).decode(&mine)
+			require.Len(t, mine, 1)
+			assert.Equal(t, service.ReportStatusActioned, mine[0].Status)
+
+			p := moderator.do("POST", "/admin/reports/"+report.ID.String()+"/actions").
+				json(tt.request).
+				expect(http.StatusConflict).
+				problem()
+			assert.Equal(t, "report is already resolved", p.Detail)
+		})
+	}
+}
+
+func TestModerateReportErrors(t *testing.T) {
+	h := newHarness(t)
+	walt := h.signUp("walt@example.com")
+	jesse := h.signUp("jesse@example.com")
+	moderator := h.signUp("mod@example.com").as(service.RoleModerator)
+	c := walt.post("no half measures")
+	chirpReport := jesse.reportChirp(c, "other")
+
+	var userReport api.Report
+	jesse.do("POST", "/api/reports").
+		json(api.CreateReportRequest{TargetType: service.ReportTargetUser, UserID: walt.ID, Reason: "harassment"}).
+		expect(http.StatusCreated).
+		decode(&userReport)
+
+	tests := []struct {
+		name     string
+		caller   *user
+		report   string
+		request  api.ModerationRequest
+		expected int
+	}{
+		{name: "Not A Moderator", caller: jesse, report: chirpReport.ID.String(), request: api.ModerationRequest{Action: service.ActionWarn, Reason: "r"}, expected: http.StatusForbidden},
+		{name: "Unknown Action", caller: moderator, report: chirpReport.ID.String(), request: api.ModerationRequest{Action: "exile", Reason: "r"}, expected: http.StatusBadRequest},
+		{name: "Missing Reason", caller: moderator, report: chirpReport.ID.String(), request: api.ModerationRequest{Action: service.ActionWarn}, expected: http.StatusBadRequest},
+		{name: "Suspend Without Duration", caller: moderator, report: chirpReport.ID.String(), request: api.ModerationRequest{Action: service.ActionSuspend, Reason: "r"}, expected: http.StatusBadRequest},
+		{name: "Chirp Action On User Report", caller: moderator, report: userReport.ID.String(), request: api.ModerationRequest{Action: service.ActionHideChirp, Reason: "r"}, expected: http.StatusBadRequest},
+		{name: "Missing Report", caller: moderator, report: uuid.NewString(), request: api.ModerationRequest{Action: service.ActionWarn, Reason: "r"}, expected: http.StatusNotFound},
+		{name: "Bad Report ID", caller: moderator, report: "nope", request: api.ModerationRequest{Action: service.ActionWarn, Reason: "r"}, expected: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.caller.do("POST", "/admin/reports/"+tt.report+"/actions").json(tt.request).expect(tt.expected).problem()
+		})
+	}
+}
+
+func TestDismissReport(t *testing.T) {
+	h := newHarness(t)
+	walt := h.signUp("walt@example.com")
+	jesse := h.signUp("jesse@example.com")
+	admin := h.signUp("admin@example.com").as(service.RoleAdmin)
+	report := jesse.reportChirp(walt.post("better call saul"), "spam")
+	path := "/admin/reports/" + report.ID.String() + "/dismiss"
+
+	jesse.do("POST", path).json(api.ModerationRequest{Reason: "not spam"}).expect(http.StatusForbidden)
+
+	var dismissed api.Report
+	admin.do("POST", path).json(api.ModerationRequest{Reason: "not spam"}).expect(http.StatusOK).decode(&dismissed)
+	assert.Equal(t, service.ReportStatusDismissed, dismissed.Status)
+	assert.Equal(t, "not spam", dismissed.Resolution)
+
+	var open, closed []api.Report
+	admin.do("GET", "/admin/reports").expect(http.StatusOK).decode(&open)
+	assert.Empty(t, open)
+	admin.do("GET", "/admin/reports?status=dismissed").expect(http.StatusOK).decode(&closed)
+	assert.Len(t, closed, 1)
+
+	admin.do("POST", path).json(api.ModerationRequest{Reason: "again"}).expect(http.StatusConflict)
+	assert.Equal(t, []string{"better call saul"}, h.chirps(nil, "/api/chirps"))
+}
+
+func TestModerateUser(t *testing.T) {
+	h := newHarness(t)
+	walt := h.signUp("walt@example.com")
+	moderator := h.signUp("mod@example.com").as(service.RoleModerator)
+	path := "/admin/users/" + walt.ID.String() + "/actions"
+	walt.post("I did it for me")
+
+	var action api.ModerationAction
+	moderator.do("POST", path).json(api.ModerationRequest{Action: service.ActionShadowBan, Reason: "spammer"}).expect(http.StatusOK).decode(&action)
+	assert.Equal(t, service.ActionShadowBan, action.Action)
+	assert.Nil(t, action.ReportID)
+
+	walt.post("nobody hears this")
+	assert.Empty(t, h.chirps(nil, "/api/chirps"), "a shadow banned user's chirps are hidden from others")
+	assert.Equal(t, []string{"I did it for me", "nobody hears this"}, h.chirps(walt, "/api/chirps"), "but not from themselves")
+
+	var actions []api.ModerationAction
+	walt.do("GET", "/api/moderation-actions").expect(http.StatusOK).decode(&actions)
+	assert.Empty(t, actions, "shadow bans are not disclosed")
+
+	moderator.do("POST", path).json(api.ModerationRequest{Action: service.ActionReinstate, Reason: "appeal"}).expect(http.StatusOK)
+	assert.Equal(t, []string{"I did it for me", "nobody hears this"}, h.chirps(nil, "/api/chirps"))
+
+	tests := []struct {
+		name     string
+		caller   *user
+		path     string
+		request  api.ModerationRequest
+		expected int
+	}{
+		{name: "Not A Moderator", caller: walt, path: "/admin/users/" + moderator.ID.String() + "/actions", request: api.ModerationRequest{Action: service.ActionBan, Reason: "r"}, expected: http.StatusForbidden},
+		{name: "Self", caller: moderator, path: "/admin/users/" + moderator.ID.String() + "/actions", request: api.ModerationRequest{Action: service.ActionBan, Reason: "r"}, expected: http.StatusBadRequest},
+		{name: "Chirp Action", caller: moderator, path: path, request: api.ModerationRequest{Action: service.ActionHideChirp, Reason: "r"}, expected: http.StatusBadRequest},
+		{name: "Missing User", caller: moderator, path: "/admin/users/" + uuid.NewString() + "/actions", request: api.ModerationRequest{Action: service.ActionBan, Reason: "r"}, expected: http.StatusNotFound},
+		{name: "Bad User ID", caller: moderator, path: "/admin/users/nope/actions", request: api.ModerationRequest{Action: service.ActionBan, Reason: "r"}, expected: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.caller.do("POST", tt.path).json(tt.request).expect(tt.expected).problem()
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/api"
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/service"
+	"github.com/Vikuuu/Chirpy/internal/store"
+	"github.com/Vikuuu/Chirpy/internal/validate"
+)
+
+// roles are the roles an operator may give an account.
+var roles = []string{service.RoleUser, service.RoleModerator, service.RoleAdmin}
+
+// findUser looks an account up by ID or email address.
+func findUser(ctx context.Context, users store.UserStore, ref string) (database.User, error) {
+	if ref == "" {
+		return database.User{}, usageError{"-user is required"}
+	}
+	var (
+		user database.User
+		err  error
+	)
+	if id, parseErr := uuid.Parse(ref); parseErr == nil {
+		user, err = users.GetUserByID(ctx, id)
+	} else {
+		user, err = users.GetUser(ctx, ref)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, fmt.Errorf("no user %q", ref)
+	}
+	if err != nil {
+		return database.User{}, fmt.Errorf("getting user: %w", err)
+	}
+	return user, nil
+}
+
+// operatorService opens the configured store for an operator command and a
+// service over it, returning the context to call the service with: the
+// audit events it records are marked as coming from the command. Closing
+// the store releases the connection.
+func (inv *invocation) operatorService(ctx context.Context) (context.Context, store.Store, *service.Service, error) {
+	st, err := inv.openStore(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ctx = service.WithOrigin(ctx, service.Origin{UserAgent: inv.flags.Name()})
+	return ctx, st, service.New(st, service.Config{}), nil
+}
+
+// generatePassword returns a random password for an operator to hand on.
+func generatePassword() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// checkCredentials applies the same rules as the API's sign-up, generating
+// the password if none was given.
+func checkCredentials(email, password string) (string, error) {
+	if password == "" {
+		var err error
+		if password, err = generatePassword(); err != nil {
+			return "", err
+		}
+	}
+	if errs := validate.Struct(api.CreateUserRequest{Email: email, Password: password}); len(errs) > 0 {
+		return "", usageError{errs[0].Message}
+	}
+	return password, nil
+}
+
+func runUsersCreate(ctx context.Context, inv *invocation) error {
+	email := inv.flags.String("email", "", "email address of the account")
+	password := inv.flags.String("password", "", "password; one is generated and printed if empty")
+	role := inv.flags.String("role", service.RoleUser, "role: user, moderator or admin")
+	if err := inv.noArgs(); err != nil {
+		return err
+	}
+	pw, err := checkCredentials(*email, *password)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(roles, *role) {
+		return usageError{fmt.Sprintf("unknown role %q", *role)}
+	}
+
+	ctx, st, svc, err := inv.operatorService(ctx)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	user, err := svc.CreateAccount(ctx, *email, pw, *role)
+	if service.KindOf(err) == service.Conflict {
+		return fmt.Errorf("%s already has an account", *email)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(inv.stdout, "created %s %s (%s)\n", *role, user.Email, user.ID)
+	if *password == "" {
+		fmt.Fprintf(inv.stdout, "password: %s\n", pw)
+	}
+	return nil
+}
+
+func runUsersList(ctx context.Context, inv *invocation) error {
+	if err := inv.noArgs(); err != nil {
+		return err
+	}
+	st, err := inv.openStore(ctx)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	users, err := st.ListUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("listing users: %w", err)
+	}
+	tw := tabwriter.NewWriter(inv.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tEMAIL\tROLE\tSTATUS\tRED\tCREATED AT")
+	for _, u := range users {
+		status := u.Status
+		if u.SuspendedUntil.Valid && status == service.StatusSuspended {
+			status += " until " + u.SuspendedUntil.Time.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\t%s\n", u.ID, u.Email, u.Role, status, u.IsChirpyRed, u.CreatedAt.UTC().Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+func runUsersPromote(ctx context.Context, inv *invocation) error {
+	ref := inv.flags.String("user", "", "ID or email address of the account")
+	role := inv.flags.String("role", service.RoleAdmin, "role to give: user, moderator or admin")
+	if err := inv.noArgs(); err != nil {
+		return err
+	}
+	if !slices.Contains(roles, *role) {
+		return usageError{fmt.Sprintf("unknown role %q", *role)}
+	}
+	ctx, st, svc, err := inv.operatorService(ctx)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	user, err := findUser(ctx, st, *ref)
+	if err != nil {
+		return err
+	}
+	old, err := svc.SetRole(ctx, user.ID, *role)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(inv.stdout, "%s is now %s (was %s)\n", user.Email, *role, old)
+	return nil
+}
+
+func runUsersSuspend(ctx context.Context, inv *invocation) error {
+	ref := inv.flags.String("user", "", "ID or email address of the account")
+	period := inv.flags.Duration("for", 0, "how long the suspension lasts, e.g. 72h")
+	reason := inv.flags.String("reason", "", "why, for the moderation log")
+	if err := inv.noArgs(); err != nil {
+		return err
+	}
+	if *period <= 0 {
+		return usageError{"-for must be a positive duration"}
+	}
+	ctx, st, svc, err := inv.operatorService(ctx)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	user, err := findUser(ctx, st, *ref)
+	if err != nil {
+		return err
+	}
+	action, err := svc.Suspend(ctx, user.ID, *period, *reason)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(inv.stdout, "%s is suspended until %s\n", user.Email, action.ExpiresAt.Time.UTC().Format(time.RFC3339))
+	return nil
+}
+
+func runUsersResetPassword(ctx context.Context, inv *invocation) error {
+	ref := inv.flags.String("user", "", "ID or email address of the account")
+	password := inv.flags.String("password", "", "new password; one is generated and printed if empty")
+	if err := inv.noArgs(); err != nil {
+		return err
+	}
+	ctx, st, svc, err := inv.operatorService(ctx)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	user, err := findUser(ctx, st, *ref)
+	if err != nil {
+		return err
+	}
+	pw, err := checkCredentials(user.Email, *password)
+	if err != nil {
+		return err
+	}
+	revoked, err := svc.ResetPassword(ctx, user.ID, pw)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(inv.stdout, "reset the password of %s and revoked %d refresh tokens\n", user.Email, revoked)
+	if *password == "" {
+		fmt.Fprintf(inv.stdout, "password: %s\n", pw)
+	}
+	return nil
+}
+
+func runTokensRevokeAll(ctx context.Context, inv *invocation) error {
+	ref := inv.flags.String("user", "", "ID or email address of the account")
+	if err := inv.noArgs(); err != nil {
+		return err
+	}
+	ctx, st, svc, err := inv.operatorService(ctx)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	user, err := findUser(ctx, st, *ref)
+	if err != nil {
+		return err
+	}
+	revoked, err := svc.RevokeSessions(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(inv.stdout, "revoked %d refresh tokens of %s\n", revoked, user.Email)
+	return nil
+}
+
+func runChirpsPurge(ctx context.Context, inv *invocation) error {
+	ref := inv.flags.String("user", "", "ID or email address of the account")
+	yes := inv.flags.Bool("yes", false, "confirm the deletion")
+	if err := inv.noArgs(); err != nil {
+		return err
+	}
+	if !*yes {
+		return usageError{"deleting chirps can't be undone; pass -yes to confirm"}
+	}
+	ctx, st, svc, err := inv.operatorService(ctx)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	user, err := findUser(ctx, st, *ref)
+	if err != nil {
+		return err
+	}
+	deleted, err := svc.PurgeChirps(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(inv.stdout, "deleted %d chirps of %s\n", deleted, user.Email)
+	return nil
+}
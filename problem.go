@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/Vikuuu/Chirpy/internal/logging"
+)
+
+// problemTypeBase prefixes a problem code to form its type URI.
+const problemTypeBase = "urn:chirpy:problem:"
+
+// problem is an RFC 9457 (formerly 7807) problem details document.
+type problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []fieldError `json:"errors,omitempty"`
+}
+
+// fieldError describes one invalid field of a request.
+type fieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// respondWithProblem writes e as an application/problem+json response.
+func respondWithProblem(w http.ResponseWriter, r *http.Request, e *apiError) {
+	code := e.Code
+	if code == "" {
+		code = codeInternal
+	}
+	p := problem{
+		Type:      problemTypeBase + code,
+		Title:     http.StatusText(e.Status),
+		Status:    e.Status,
+		Detail:    e.Message,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: requestIDFrom(r.Context()),
+		Errors:    e.Fields,
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "marshalling problem", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(e.Status)
+	w.Write(data)
+}
+
+type requestIDKey struct{}
+
+// requestIDHeader carries the request ID in both directions.
+const requestIDHeader = "X-Request-ID"
+
+// withRequestID tags every request with an ID, echoed in the response and in
+// problem documents. A well-formed ID sent by the client or a proxy is kept so
+// that logs can be correlated across hops. The ID is added to the request's
+// logging scope, so every line logged for the request carries it.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, id)
+		logging.Add(r.Context(), slog.String("request_id", id))
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// problemFallback answers requests that match no route with a problem
+// instead of the mux's plain text 404 and 405 responses.
+func problemFallback(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		probe := &statusProbe{header: http.Header{}}
+		h.ServeHTTP(probe, r)
+		if probe.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", probe.header.Get("Allow"))
+			respondWithProblem(w, r, &apiError{
+				Status:  http.StatusMethodNotAllowed,
+				Code:    codeMethodNotAllowed,
+				Message: r.Method + " is not supported here",
+			})
+			return
+		}
+		respondWithProblem(w, r, &apiError{Status: http.StatusNotFound, Code: codeNotFound, Message: "no such endpoint"})
+	})
+}
+
+// statusProbe records the status and headers a handler writes and discards
+// the body.
+type statusProbe struct {
+	header http.Header
+	status int
+}
+
+func (p *statusProbe) Header() http.Header { return p.header }
+
+func (p *statusProbe) Write(b []byte) (int, error) {
+	if p.status == 0 {
+		p.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (p *statusProbe) WriteHeader(status int) {
+	if p.status == 0 {
+		p.status = status
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Vikuuu/Chirpy/internal/logging"
+	"github.com/Vikuuu/Chirpy/internal/metrics"
+)
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) problem {
+	t.Helper()
+	var p problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+	return p
+}
+
+func TestProblemFields(t *testing.T) {
+	h := withRequestID((&apiConfig{metrics: metrics.New()}).handle(func(w http.ResponseWriter, r *http.Request) error {
+		return errFields(
+			fieldError{Field: "body", Code: "too_long", Message: "chirp is too long"},
+			fieldError{Field: "reason", Code: "required", Message: "reason is required"},
+		)
+	}))
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/api/chirps", nil))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	p := decodeProblem(t, rec)
+	assert.Equal(t, "urn:chirpy:problem:validation_failed", p.Type)
+	assert.Equal(t, "Bad Request", p.Title)
+	assert.Equal(t, "/api/chirps", p.Instance)
+	assert.Equal(t, []fieldError{
+		{Field: "body", Code: "too_long", Message: "chirp is too long"},
+		{Field: "reason", Code: "required", Message: "reason is required"},
+	}, p.Errors)
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		sent string
+		kept bool
+	}{
+		{name: "Generated", sent: "", kept: false},
+		{name: "Kept", sent: "abc-123", kept: true},
+		{name: "Spaces Replaced", sent: "abc 123", kept: false},
+		{name: "Too Long Replaced", sent: strings.Repeat("a", 129), kept: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			h := withRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = requestIDFrom(r.Context())
+			}))
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.sent != "" {
+				req.Header.Set("X-Request-ID", tt.sent)
+			}
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+
+			assert.NotEmpty(t, seen)
+			assert.Equal(t, seen, rec.Header().Get("X-Request-ID"))
+			if tt.kept {
+				assert.Equal(t, tt.sent, seen)
+			} else {
+				assert.NotEqual(t, tt.sent, seen)
+			}
+		})
+	}
+}
+
+func TestRequestIDLogged(t *testing.T) {
+	var buf bytes.Buffer
+	h := logging.AccessLog(logging.New(&buf, slog.LevelInfo), withRequestID(http.NotFoundHandler()))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "abc-123", line["request_id"])
+	assert.Equal(t, float64(http.StatusNotFound), line["status"])
+}
+
+func TestUnmatchedRoutes(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		expected int
+		code     string
+	}{
+		{name: "Unknown Path", method: "GET", path: "/api/nope", expected: http.StatusNotFound, code: "not_found"},
+		{name: "Wrong Method", method: "PATCH", path: "/api/chirps", expected: http.StatusMethodNotAllowed, code: "method_not_allowed"},
+	}
+
+	handler := newTestConfig(t).routes(".")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.expected, rec.Code)
+			assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+			assert.Equal(t, tt.code, decodeProblem(t, rec).Code)
+		})
+	}
+}
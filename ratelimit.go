@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Vikuuu/Chirpy/internal/auth"
+	"github.com/Vikuuu/Chirpy/internal/ratelimit"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// Kinds of principal a request is counted against.
+const (
+	principalAnonymous = "anonymous"
+	principalUser      = "user"
+	principalRed       = "red"
+	principalToken     = "token"
+)
+
+const (
+	rateLimitSweepInterval = 5 * time.Minute
+	rateLimitIdleAfter     = time.Hour
+)
+
+// parseRateLimits parses the policy configured for each route.
+func parseRateLimits(routes map[string]string) (map[string]ratelimit.Policy, error) {
+	limits := make(map[string]ratelimit.Policy, len(routes))
+	for route, spec := range routes {
+		p, err := ratelimit.ParsePolicy(spec)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit %s: %w", route, err)
+		}
+		limits[route] = p
+	}
+	return limits, nil
+}
+
+// newRateLimitStore returns the named bucket store. The postgres store
+// shares limits between instances, and keeps them in st, which must be
+// Postgres too.
+func newRateLimitStore(kind string, st store.Store) (ratelimit.Store, error) {
+	switch kind {
+	case "memory":
+		return ratelimit.NewMemoryStore(), nil
+	case "postgres":
+		pg, ok := st.(*store.Postgres)
+		if !ok {
+			return nil, fmt.Errorf("rate limit store %q needs the postgres store", kind)
+		}
+		return ratelimit.NewPostgresStore(pg.Queries), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", kind)
+	}
+}
+
+// rateLimit wraps next with the policy configured for route.
+func (cfg *apiConfig) rateLimit(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		kind, id := cfg.principal(r)
+		limit, ok := cfg.rateLimits[route][kind]
+		if !ok {
+			next(w, r)
+			return
+		}
+
+		d, err := cfg.rateLimiter.Take(r.Context(), route+":"+kind+":"+id, limit)
+		if err != nil {
+			// Fail open: a broken limiter shouldn't take the API down with it.
+			slog.ErrorContext(r.Context(), "taking rate limit token", "route", route, "err", err)
+			next(w, r)
+			return
+		}
+
+		ratelimit.WriteHeaders(w.Header(), limit, d)
+		if !d.Allowed {
+			respondWithProblem(w, r, &apiError{
+				Status:  http.StatusTooManyRequests,
+				Code:    codeRateLimited,
+				Message: "rate limit exceeded",
+			})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// principal identifies who r is counted against. Only credentials that check
+// out count; anything else falls back to the client IP so that sending junk
+// tokens doesn't buy a fresh bucket.
+func (cfg *apiConfig) principal(r *http.Request) (kind, id string) {
+	if apiKey, err := auth.GetAPIKey(r.Header); err == nil {
+		if cfg.polkaKey != "" && subtle.ConstantTimeCompare([]byte(apiKey), []byte(cfg.polkaKey)) == 1 {
+			return principalToken, "polka"
+		}
+	}
+
+	if token, err := auth.GetBearerToken(r.Header); err == nil {
+		if userID, err := auth.ValidateJWT(token, cfg.secret); err == nil {
+			user, err := cfg.db.GetUserByID(r.Context(), userID)
+			if err == nil && user.IsChirpyRed {
+				return principalRed, userID.String()
+			}
+			return principalUser, userID.String()
+		}
+		if _, err := cfg.db.GetUserFromRefreshToken(r.Context(), token); err == nil {
+			sum := sha256.Sum256([]byte(token))
+			return principalToken, hex.EncodeToString(sum[:8])
+		}
+	}
+
+	return principalAnonymous, cfg.clientIP(r)
+}
+
+// clientIP returns the address the request came from. X-Forwarded-For is
+// only believed when TRUST_PROXY is set, since clients can send anything.
+func (cfg *apiConfig) clientIP(r *http.Request) string {
+	if cfg.trustProxy {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			first, _, _ := strings.Cut(fwd, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// sweepRateLimits periodically drops idle buckets until ctx is done.
+func (cfg *apiConfig) sweepRateLimits(ctx context.Context) {
+	ticker := time.NewTicker(rateLimitSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := cfg.rateLimiter.Sweep(ctx, rateLimitIdleAfter); err != nil {
+				slog.ErrorContext(ctx, "sweeping rate limit buckets", "err", err)
+			}
+		}
+	}
+}
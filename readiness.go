@@ -1,10 +1,33 @@
 package main
 
-import "net/http"
+import (
+	"context"
+	"errors"
 
-func handlerHealth(w http.ResponseWriter, r *http.Request) {
-	r.Header.Add("Content-Type", "text/plain: charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	str := "OK"
-	w.Write([]byte(str))
+	"github.com/Vikuuu/Chirpy/internal/migrate"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// registerHealthChecks adds the readiness checks for the store, its schema
+// if it is migrated, and the background workers.
+func (cfg *apiConfig) registerHealthChecks(st store.Store, migrator *migrate.Migrator, bg *workers) {
+	cfg.health.Register("shutdown", cfg.checkShutdown)
+	cfg.health.Register("database", st.Ping)
+	if migrator != nil {
+		cfg.health.Register("migrations", func(ctx context.Context) error {
+			return migrator.Check(ctx)
+		})
+	}
+	cfg.health.Register("workers", func(ctx context.Context) error {
+		return bg.Alive()
+	})
+}
+
+// checkShutdown fails as soon as shutdown starts so that load balancers stop
+// routing to the instance while in-flight requests drain.
+func (cfg *apiConfig) checkShutdown(ctx context.Context) error {
+	if cfg.draining.Load() {
+		return errors.New("shutting down")
+	}
+	return nil
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Vikuuu/Chirpy/internal/health"
+	"github.com/Vikuuu/Chirpy/internal/migrate"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+func TestReadinessWithDatabaseDown(t *testing.T) {
+	cfg := newTestConfig(t)
+	db := sql.OpenDB(downConnector{})
+	defer db.Close()
+	bg := newWorkers()
+	defer bg.Stop()
+	migrator, err := migrate.New(db)
+	require.NoError(t, err)
+	cfg.registerHealthChecks(cfg.db, migrator, bg)
+	handler := cfg.routes(".")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/livez", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var report health.Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	statuses := map[string]health.Status{}
+	for _, c := range report.Checks {
+		statuses[c.Name] = c.Status
+	}
+	assert.Equal(t, map[string]health.Status{
+		"database":   health.StatusFail,
+		"migrations": health.StatusFail,
+		"shutdown":   health.StatusOK,
+		"workers":    health.StatusOK,
+	}, statuses)
+}
+
+func TestReadinessWithoutMigrations(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.db = store.NewMemory()
+	bg := newWorkers()
+	defer bg.Stop()
+	cfg.registerHealthChecks(cfg.db, nil, bg)
+
+	rec := httptest.NewRecorder()
+	cfg.routes(".").ServeHTTP(rec, httptest.NewRequest("GET", "/api/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var report health.Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	for _, c := range report.Checks {
+		assert.NotEqual(t, "migrations", c.Name, "only Postgres is migrated")
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/Vikuuu/Chirpy/internal/api"
+	"github.com/Vikuuu/Chirpy/internal/service"
+)
+
+func (cfg *apiConfig) handlerCreateReport(w http.ResponseWriter, r *http.Request) error {
+	user, err := cfg.authenticate(r)
+	if err != nil {
+		return err
+	}
+
+	params := api.CreateReportRequest{}
+	if err := decodeJSON(w, r, &params); err != nil {
+		return err
+	}
+
+	report, err := cfg.svc.Report(r.Context(), user.ID, service.NewReport{
+		TargetType: params.TargetType,
+		ChirpID:    params.ChirpID,
+		UserID:     params.UserID,
+		Reason:     params.Reason,
+		Details:    params.Details,
+	})
+	if err != nil {
+		return err
+	}
+
+	return respondWithJSON(w, http.StatusCreated, api.NewReport(report, false))
+}
+
+// handlerListMyReports lists the reports filed by the caller together with
+// their outcome.
+func (cfg *apiConfig) handlerListMyReports(w http.ResponseWriter, r *http.Request) error {
+	user, err := cfg.authenticate(r)
+	if err != nil {
+		return err
+	}
+
+	reports, err := cfg.svc.ReportsBy(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	return respondWithJSON(w, http.StatusOK, api.NewReports(reports, false))
+}
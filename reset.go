@@ -1,23 +1,143 @@
 package main
 
 import (
-	"context"
-	"log"
+	"fmt"
 	"net/http"
-	"os"
+	"strconv"
+
+	"github.com/Vikuuu/Chirpy/internal/api"
+	"github.com/Vikuuu/Chirpy/internal/auth"
+	"github.com/Vikuuu/Chirpy/internal/config"
+	"github.com/Vikuuu/Chirpy/internal/seed"
+	"github.com/Vikuuu/Chirpy/internal/service"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// Sample data /admin/reset?seed= makes, unless told otherwise, and the most
+// it makes in one request.
+const (
+	resetSeedUsers     = 25
+	resetSeedChirps    = 200
+	resetMaxSeedUsers  = 1000
+	resetMaxSeedChirps = 10000
 )
 
-func (cfg *apiConfig) handlerReset(w http.ResponseWriter, r *http.Request) {
-	paltform := os.Getenv("PLATFORM")
-	if paltform != "dev" {
-		respondWithError(w, 403, "Platform in not DEV")
+// handlerReset lets an admin on a dev server delete data in bulk, in two
+// steps: a request without a confirmation token is answered with one, and
+// the same request with the token resets. Given a seed, a reset of
+// everything then fills the database with the sample data that seed
+// generates, as "chirpy seed" does, optionally sized by users and chirps.
+func (cfg *apiConfig) handlerReset(w http.ResponseWriter, r *http.Request) error {
+	if cfg.platform != config.PlatformDev {
+		return errForbidden("Platform in not DEV")
 	}
-	err := cfg.db.DeleteAllUsers(context.Background())
+	admin, err := cfg.authorizeRole(r, service.RoleAdmin)
 	if err != nil {
-		log.Fatalf("Error deleting all the users: %s", err)
-		w.WriteHeader(500)
-		return
+		return err
+	}
+
+	params := api.ResetRequest{}
+	if err := decodeJSON(w, r, &params); err != nil {
+		return err
+	}
+	scope := service.ResetScope{Scope: params.Scope, Pattern: params.Pattern}
+
+	var opts *seed.Options
+	if q := r.URL.Query(); q.Has("seed") {
+		o, err := seedOptions(q.Get("seed"), q.Get("users"), q.Get("chirps"))
+		if err != nil {
+			return err
+		}
+		if scope.Scope != service.ResetAll {
+			return errFields(fieldError{Field: "seed", Code: "unexpected", Message: "seed is only used when resetting everything"})
+		}
+		opts = &o
+	}
+
+	if params.Confirm == "" {
+		token, expires, err := cfg.svc.ResetConfirmation(admin.ID, scope)
+		if err != nil {
+			return err
+		}
+		return respondWithJSON(w, http.StatusAccepted, api.ResetConfirmation{
+			Scope:             scope.Scope,
+			Pattern:           scope.Pattern,
+			ConfirmationToken: token,
+			ExpiresAt:         expires,
+		})
+	}
+
+	// The sample data goes in with the reset, so a failure leaves the
+	// database as it was. The password is hashed before the transaction
+	// starts, to keep it short.
+	var refill func(tx store.Tx) error
+	var seeded seed.Counts
+	if opts != nil {
+		hashed, err := auth.HashPassword(r.Context(), seed.DefaultPassword)
+		if err != nil {
+			return fmt.Errorf("hashing password: %w", err)
+		}
+		data := seed.Generate(*opts)
+		refill = func(tx store.Tx) error {
+			var err error
+			seeded, err = data.InsertTx(r.Context(), tx, hashed)
+			return err
+		}
+	}
+
+	counts, err := cfg.svc.Reset(r.Context(), admin.ID, scope, params.Confirm, refill)
+	if err != nil {
+		return err
+	}
+	summary := api.ResetSummary{
+		Scope:   scope.Scope,
+		Pattern: scope.Pattern,
+		Deleted: api.ResetCounts{Users: counts.Users, Chirps: counts.Chirps, RefreshTokens: counts.RefreshTokens},
+	}
+	if scope.Scope == service.ResetAll || scope.Scope == service.ResetMetrics {
+		summary.Metrics = &api.ResetMetrics{FileserverHits: cfg.fileserverHits.Swap(0)}
+	}
+	if opts != nil {
+		summary.Seeded = &api.Seeded{
+			Seed:      opts.Seed,
+			Users:     seeded.Users,
+			ChirpyRed: seeded.ChirpyRed,
+			Follows:   seeded.Follows,
+			Chirps:    seeded.Chirps,
+			Password:  seed.DefaultPassword,
+		}
+	}
+	return respondWithJSON(w, http.StatusOK, summary)
+}
+
+// seedOptions parses the query parameters of /admin/reset?seed=.
+func seedOptions(seedParam, usersParam, chirpsParam string) (seed.Options, error) {
+	var fields []fieldError
+	o := seed.Options{Users: resetSeedUsers, Chirps: resetSeedChirps}
+	n, err := strconv.ParseUint(seedParam, 10, 64)
+	if err != nil {
+		fields = append(fields, fieldError{Field: "seed", Code: "invalid_number", Message: "seed must be a non-negative integer"})
+	}
+	o.Seed = n
+	count := func(name, param string, max int, into *int) {
+		if param == "" {
+			return
+		}
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 0 || n > max {
+			fields = append(fields, fieldError{
+				Field:   name,
+				Code:    "out_of_range",
+				Message: fmt.Sprintf("%s must be a number from 0 to %d", name, max),
+			})
+			return
+		}
+		*into = n
+	}
+	count("users", usersParam, resetMaxSeedUsers, &o.Users)
+	count("chirps", chirpsParam, resetMaxSeedChirps, &o.Chirps)
+	if len(fields) > 0 {
+		return seed.Options{}, errFields(fields...)
 	}
-	cfg.fileserverHits.Store(0)
-	w.WriteHeader(http.StatusOK)
+	return o, nil
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Vikuuu/Chirpy/internal/auth"
+	"github.com/Vikuuu/Chirpy/internal/config"
+	"github.com/Vikuuu/Chirpy/internal/seed"
+)
+
+// runSeed fills a dev database with sample users, follows and chirps
+// generated from -seed, so that every run with the same flags on the same
+// day makes the same data. Users that already exist are left alone, so it
+// can be run again safely.
+func runSeed(ctx context.Context, inv *invocation) error {
+	seedFlag := inv.flags.Uint64("seed", seed.DefaultSeed, "random seed the sample data is generated from")
+	users := inv.flags.Int("users", 25, "number of sample users")
+	chirps := inv.flags.Int("chirps", 200, "number of sample chirps, spread across the users")
+	password := inv.flags.String("password", seed.DefaultPassword, "password of the sample users")
+	if err := inv.noArgs(); err != nil {
+		return err
+	}
+	if inv.conf.Platform != config.PlatformDev {
+		return errors.New("seeding is only allowed on the dev platform")
+	}
+	if *users < 0 || *chirps < 0 {
+		return usageError{"-users and -chirps must not be negative"}
+	}
+	if _, err := checkCredentials("seed@example.com", *password); err != nil {
+		return err
+	}
+
+	st, err := inv.openStore(ctx)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	hashed, err := auth.HashPassword(ctx, *password)
+	if err != nil {
+		return fmt.Errorf("hashing password: %w", err)
+	}
+	data := seed.Generate(seed.Options{Seed: *seedFlag, Users: *users, Chirps: *chirps})
+	counts, err := data.Insert(ctx, st, hashed)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(inv.stdout, "created %d users (%d Chirpy Red), %d follows and %d chirps\n",
+		counts.Users, counts.ChirpyRed, counts.Follows, counts.Chirps)
+	if len(data.Users) > 0 {
+		fmt.Fprintf(inv.stdout, "sample users sign in with %q, for example as %s\n", *password, data.Users[0].Email)
+	}
+	return nil
+}
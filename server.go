@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// shutdownOptions control how the server stops.
+type shutdownOptions struct {
+	// Delay is how long the failing readiness check is served before the
+	// listener closes, so that load balancers notice first.
+	Delay time.Duration
+	// Timeout bounds how long in-flight requests may take to finish.
+	Timeout time.Duration
+}
+
+// serve runs srv on ln until ctx is done, then fails readiness and drains
+// in-flight requests. It returns once the server has stopped.
+func (cfg *apiConfig) serve(ctx context.Context, srv *http.Server, ln net.Listener, opts shutdownOptions) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down, draining requests", "timeout", opts.Timeout)
+	cfg.draining.Store(true)
+	time.Sleep(opts.Delay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// Whatever is still running is cut off.
+		srv.Close()
+		return fmt.Errorf("draining requests: %w", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// workers runs background jobs and stops them together.
+type workers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	stopped []string
+}
+
+func newWorkers() *workers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &workers{ctx: ctx, cancel: cancel}
+}
+
+// Go runs job until Stop is called. A job that returns or panics before then
+// is reported by Alive.
+func (w *workers) Go(name string, job func(ctx context.Context)) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer func() {
+			if v := recover(); v != nil {
+				slog.Error("worker panicked", "worker", name, "panic", v)
+			}
+			if w.ctx.Err() == nil {
+				w.mu.Lock()
+				w.stopped = append(w.stopped, name)
+				w.mu.Unlock()
+			}
+		}()
+		job(w.ctx)
+	}()
+}
+
+// Alive reports the jobs that stopped without being asked to.
+func (w *workers) Alive() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.stopped) > 0 {
+		return fmt.Errorf("stopped unexpectedly: %s", strings.Join(w.stopped, ", "))
+	}
+	return nil
+}
+
+// Stop cancels every job and waits for them to return.
+func (w *workers) Stop() {
+	w.cancel()
+	w.wg.Wait()
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	cfg := newTestConfig(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/healthz", cfg.health.ReadyHandler())
+	mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+	srv := &http.Server{Handler: mux}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	base := "http://" + ln.Addr().String()
+
+	ctx, shutdown := context.WithCancel(context.Background())
+	defer shutdown()
+	served := make(chan error, 1)
+	go func() {
+		served <- cfg.serve(ctx, srv, ln, shutdownOptions{Delay: 200 * time.Millisecond, Timeout: 5 * time.Second})
+	}()
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	slow := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(base + "/slow")
+		if err != nil {
+			slow <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		slow <- result{status: resp.StatusCode, body: string(body), err: err}
+	}()
+	<-started
+
+	shutdown()
+
+	// While the delay runs the listener is still open but readiness fails.
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get(base + "/api/healthz")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusServiceUnavailable
+	}, time.Second, 10*time.Millisecond)
+
+	select {
+	case err := <-served:
+		t.Fatalf("server stopped with a request in flight: %v", err)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	close(release)
+
+	res := <-slow
+	require.NoError(t, res.err)
+	assert.Equal(t, http.StatusOK, res.status)
+	assert.Equal(t, "done", res.body)
+
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after draining")
+	}
+
+	_, err = http.Get(base + "/api/healthz")
+	assert.Error(t, err, "new connections should be refused after shutdown")
+}
+
+func TestServeShutdownTimeout(t *testing.T) {
+	cfg := newTestConfig(t)
+	started := make(chan struct{})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /stuck", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+	srv := &http.Server{Handler: mux}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, shutdown := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- cfg.serve(ctx, srv, ln, shutdownOptions{Timeout: 100 * time.Millisecond})
+	}()
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/stuck")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	shutdown()
+
+	select {
+	case err := <-served:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not give up on a stuck request")
+	}
+}
+
+func TestWorkersStop(t *testing.T) {
+	w := newWorkers()
+	stopped := make(chan struct{})
+	w.Go("waiter", func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	})
+	w.Go("quitter", func(ctx context.Context) {})
+	w.Go("panicker", func(ctx context.Context) { panic("boom") })
+
+	assert.Eventually(t, func() bool {
+		err := w.Alive()
+		return err != nil && strings.Contains(err.Error(), "quitter") && strings.Contains(err.Error(), "panicker")
+	}, time.Second, 10*time.Millisecond)
+	assert.NotContains(t, w.Alive().Error(), "waiter")
+
+	w.Stop()
+
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("Stop returned before the worker did")
+	}
+}
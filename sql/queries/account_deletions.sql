@@ -0,0 +1,22 @@
+-- name: ScheduleAccountDeletion :one
+INSERT INTO account_deletions (user_id, requested_at, delete_after)
+VALUES ($1, NOW(), $2)
+ON CONFLICT (user_id) DO UPDATE
+SET requested_at = excluded.requested_at, delete_after = excluded.delete_after
+RETURNING user_id, requested_at, delete_after;
+
+-- name: GetAccountDeletion :one
+SELECT user_id, requested_at, delete_after
+FROM account_deletions
+WHERE user_id = $1;
+
+-- name: CancelAccountDeletion :execrows
+DELETE FROM account_deletions
+WHERE user_id = $1;
+
+-- name: ListDueAccountDeletions :many
+SELECT user_id, requested_at, delete_after
+FROM account_deletions
+WHERE delete_after <= $1
+ORDER BY delete_after ASC
+LIMIT $2;
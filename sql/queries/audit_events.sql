@@ -0,0 +1,20 @@
+-- name: CreateAuditEvent :one
+INSERT INTO audit_events (id, created_at, action, actor_id, target_type, target_id, ip, user_agent, request_id, details)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3, $4, $5, $6, $7, $8
+)
+RETURNING id, created_at, action, actor_id, target_type, target_id, ip, user_agent, request_id, details;
+
+-- name: ListAuditEvents :many
+SELECT id, created_at, action, actor_id, target_type, target_id, ip, user_agent, request_id, details
+FROM audit_events
+WHERE (sqlc.narg('action')::text IS NULL OR action = sqlc.narg('action'))
+    AND (sqlc.narg('actor_id')::uuid IS NULL OR actor_id = sqlc.narg('actor_id'))
+    AND (sqlc.narg('target_type')::text IS NULL OR target_type = sqlc.narg('target_type'))
+    AND (sqlc.narg('target_id')::text IS NULL OR target_id = sqlc.narg('target_id'))
+    AND (sqlc.narg('since')::timestamp IS NULL OR created_at >= sqlc.narg('since'))
+    AND (sqlc.narg('until')::timestamp IS NULL OR created_at < sqlc.narg('until'))
+    AND (sqlc.narg('before_created_at')::timestamp IS NULL
+        OR (created_at, id) < (sqlc.narg('before_created_at'), sqlc.narg('before_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,17 @@
+-- name: ListBannedWords :many
+SELECT word, action, created_at, updated_at
+FROM banned_words
+ORDER BY word ASC;
+
+-- name: UpsertBannedWord :one
+INSERT INTO banned_words (word, action, created_at, updated_at)
+VALUES (
+    $1, $2, NOW(), NOW()
+)
+ON CONFLICT (word) DO UPDATE
+SET action = EXCLUDED.action, updated_at = NOW()
+RETURNING word, action, created_at, updated_at;
+
+-- name: DeleteBannedWord :execrows
+DELETE FROM banned_words
+WHERE word = $1;
@@ -3,35 +3,75 @@ INSERT INTO chirp (id, created_at, updated_at, body, user_id)
 VALUES (
     gen_random_uuid(), NOW(), NOW(), $1, $2
 )
-RETURNING id, created_at, updated_at, body, user_id;
+RETURNING id, created_at, updated_at, body, user_id, hidden_at;
 
 -- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT chirp.id, chirp.created_at, chirp.updated_at, chirp.body, chirp.user_id, chirp.hidden_at
 FROM chirp
-ORDER BY created_at ASC;
+JOIN users ON users.id = chirp.user_id
+WHERE chirp.hidden_at IS NULL
+    AND (users.status <> 'shadow_banned' OR chirp.user_id = sqlc.narg('viewer_id'))
+ORDER BY chirp.created_at ASC;
 
 -- name: GetChirpsForAuthor :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT chirp.id, chirp.created_at, chirp.updated_at, chirp.body, chirp.user_id, chirp.hidden_at
 FROM chirp
-WHERE user_id = $1
-ORDER BY created_at ASC;
+JOIN users ON users.id = chirp.user_id
+WHERE chirp.user_id = sqlc.arg('user_id') AND chirp.hidden_at IS NULL
+    AND (users.status <> 'shadow_banned' OR chirp.user_id = sqlc.narg('viewer_id'))
+ORDER BY chirp.created_at ASC;
 
 -- name: GetSortedChirps :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT chirp.id, chirp.created_at, chirp.updated_at, chirp.body, chirp.user_id, chirp.hidden_at
 FROM chirp
-ORDER BY created_at $1;
+JOIN users ON users.id = chirp.user_id
+WHERE chirp.hidden_at IS NULL
+    AND (users.status <> 'shadow_banned' OR chirp.user_id = sqlc.narg('viewer_id'))
+ORDER BY chirp.created_at $2;
 
 -- name: GetSortedChirpsForAuthor :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT chirp.id, chirp.created_at, chirp.updated_at, chirp.body, chirp.user_id, chirp.hidden_at
 FROM chirp
-WHERE user_id = $1
-ORDER BY created_at $2;
+JOIN users ON users.id = chirp.user_id
+WHERE chirp.user_id = sqlc.arg('user_id') AND chirp.hidden_at IS NULL
+    AND (users.status <> 'shadow_banned' OR chirp.user_id = sqlc.narg('viewer_id'))
+ORDER BY chirp.created_at $3;
 
 -- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, hidden_at
 FROM chirp
 WHERE id = $1;
 
 -- name: DeleteChirp :exec
 DELETE FROM chirp
 WHERE user_id = $1 AND id = $2;
+
+-- name: HideChirp :exec
+UPDATE chirp
+SET hidden_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetRecentChirpBodiesForUser :many
+SELECT body
+FROM chirp
+WHERE user_id = $1 AND created_at > $2
+ORDER BY created_at DESC
+LIMIT 50;
+
+-- name: DeleteChirpsForUser :execrows
+DELETE FROM chirp
+WHERE user_id = $1;
+
+-- name: SeedChirp :execrows
+INSERT INTO chirp (id, created_at, updated_at, body, user_id)
+VALUES ($1, $2, $2, $3, $4)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteAllChirps :execrows
+DELETE FROM chirp;
+
+-- name: ListChirpsForUser :many
+SELECT id, created_at, updated_at, body, user_id, hidden_at
+FROM chirp
+WHERE user_id = $1
+ORDER BY created_at ASC;
@@ -0,0 +1,34 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports (id, user_id, status, created_at)
+VALUES (
+    gen_random_uuid(), $1, 'pending', NOW()
+)
+RETURNING id, user_id, status, created_at, completed_at, expires_at, archive, error;
+
+-- name: GetDataExport :one
+SELECT id, user_id, status, created_at, completed_at, expires_at, archive, error
+FROM data_exports
+WHERE id = $1;
+
+-- name: GetPendingDataExportForUser :one
+SELECT id, user_id, status, created_at, completed_at, expires_at, archive, error
+FROM data_exports
+WHERE user_id = $1 AND status = 'pending'
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ListPendingDataExports :many
+SELECT id, user_id, status, created_at, completed_at, expires_at, archive, error
+FROM data_exports
+WHERE status = 'pending'
+ORDER BY created_at ASC
+LIMIT $1;
+
+-- name: FinishDataExport :execrows
+UPDATE data_exports
+SET status = $2, completed_at = NOW(), expires_at = $3, archive = $4, error = $5
+WHERE id = $1 AND status = 'pending';
+
+-- name: DeleteExpiredDataExports :execrows
+DELETE FROM data_exports
+WHERE expires_at <= $1;
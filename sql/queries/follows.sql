@@ -0,0 +1,10 @@
+-- name: CreateFollow :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: ListFollowees :many
+SELECT followee_id
+FROM follows
+WHERE follower_id = $1
+ORDER BY created_at ASC, followee_id ASC;
@@ -0,0 +1,12 @@
+-- name: CreateModerationAction :one
+INSERT INTO moderation_actions (id, created_at, report_id, moderator_id, action, target_user_id, target_chirp_id, reason, expires_at)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING id, created_at, report_id, moderator_id, action, target_user_id, target_chirp_id, reason, expires_at;
+
+-- name: ListModerationActionsForUser :many
+SELECT id, created_at, report_id, moderator_id, action, target_user_id, target_chirp_id, reason, expires_at
+FROM moderation_actions
+WHERE target_user_id = $1
+ORDER BY created_at DESC;
@@ -0,0 +1,22 @@
+-- name: TakeRateLimitToken :one
+-- A new bucket starts full. An existing one is refilled from the level it
+-- has stored, in the conflict branch, which holds the row's lock; so
+-- concurrent takes on a key, its first ones included, are applied one
+-- after the other.
+INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+VALUES (
+    sqlc.arg('key'),
+    GREATEST(sqlc.arg('burst')::DOUBLE PRECISION - 1, 0),
+    sqlc.arg('burst')::DOUBLE PRECISION >= 1,
+    NOW()
+)
+ON CONFLICT (key) DO UPDATE
+SET tokens = LEAST(sqlc.arg('burst')::DOUBLE PRECISION, b.tokens + EXTRACT(EPOCH FROM (NOW() - b.updated_at)) * sqlc.arg('rate')::DOUBLE PRECISION)
+        - CASE WHEN LEAST(sqlc.arg('burst')::DOUBLE PRECISION, b.tokens + EXTRACT(EPOCH FROM (NOW() - b.updated_at)) * sqlc.arg('rate')::DOUBLE PRECISION) >= 1 THEN 1 ELSE 0 END,
+    allowed = LEAST(sqlc.arg('burst')::DOUBLE PRECISION, b.tokens + EXTRACT(EPOCH FROM (NOW() - b.updated_at)) * sqlc.arg('rate')::DOUBLE PRECISION) >= 1,
+    updated_at = NOW()
+RETURNING tokens, allowed;
+
+-- name: DeleteIdleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < NOW() - sqlc.arg('idle_seconds')::DOUBLE PRECISION * INTERVAL '1 second';
@@ -13,3 +13,21 @@ WHERE token = $1;
 UPDATE refresh_tokens
 SET revoked_at = $1, updated_at = $2
 WHERE token = $3;
+
+-- name: RevokeAllRefreshTokensForUser :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: DeleteRefreshTokensForUser :execrows
+DELETE FROM refresh_tokens
+WHERE user_id = $1;
+
+-- name: DeleteAllRefreshTokens :execrows
+DELETE FROM refresh_tokens;
+
+-- name: ListRefreshTokensForUser :many
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at
+FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at DESC;
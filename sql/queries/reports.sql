@@ -0,0 +1,29 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, updated_at, reporter_id, target_type, chirp_id, user_id, reason, details)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6
+)
+RETURNING id, created_at, updated_at, reporter_id, target_type, chirp_id, user_id, reason, details, status, resolved_by, resolved_at, resolution;
+
+-- name: GetReport :one
+SELECT id, created_at, updated_at, reporter_id, target_type, chirp_id, user_id, reason, details, status, resolved_by, resolved_at, resolution
+FROM reports
+WHERE id = $1;
+
+-- name: ListReportsByStatus :many
+SELECT id, created_at, updated_at, reporter_id, target_type, chirp_id, user_id, reason, details, status, resolved_by, resolved_at, resolution
+FROM reports
+WHERE status = $1
+ORDER BY created_at ASC;
+
+-- name: ListReportsForReporter :many
+SELECT id, created_at, updated_at, reporter_id, target_type, chirp_id, user_id, reason, details, status, resolved_by, resolved_at, resolution
+FROM reports
+WHERE reporter_id = $1
+ORDER BY created_at DESC;
+
+-- name: ResolveReport :one
+UPDATE reports
+SET status = $1, resolved_by = $2, resolution = $3, resolved_at = NOW(), updated_at = NOW()
+WHERE id = $4 AND status = 'open'
+RETURNING id, created_at, updated_at, reporter_id, target_type, chirp_id, user_id, reason, details, status, resolved_by, resolved_at, resolution;
@@ -5,22 +5,55 @@ VALUES (
 )
 RETURNING id, created_at, updated_at, email, is_chirpy_red; 
 
--- name: DeleteAllUsers :exec
+-- name: DeleteAllUsers :execrows
 DELETE FROM users;
 
 -- name: GetUser :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, status, suspended_until
 FROM users
 WHERE email = $1;
 
+-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, status, suspended_until
+FROM users
+WHERE id = $1;
+
 -- name: EditUser :one
 UPDATE users
 SET email = $1, hashed_password = $2, updated_at = $3
 WHERE id = $4
 RETURNING email;
 
--- name: UpgradeUserToRed :exec
+-- name: UpgradeUserToRed :execrows
 UPDATE users
 SET is_chirpy_red = TRUE
-WHERE id = $1
-RETURNING is_chirpy_red;
+WHERE id = $1;
+
+-- name: SetUserStatus :execrows
+UPDATE users
+SET status = $1, suspended_until = $2, updated_at = NOW()
+WHERE id = $3;
+
+-- name: ListUsers :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, status, suspended_until
+FROM users
+ORDER BY created_at ASC;
+
+-- name: SetUserRole :execrows
+UPDATE users
+SET role = $1, updated_at = NOW()
+WHERE id = $2;
+
+-- name: SetUserPassword :execrows
+UPDATE users
+SET hashed_password = $1, updated_at = NOW()
+WHERE id = $2;
+
+-- name: SeedUser :execrows
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red)
+VALUES ($1, $2, $2, $3, $4, $5)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1;
@@ -0,0 +1,18 @@
+-- +goose Up 
+CREATE TABLE banned_words (
+    word TEXT PRIMARY KEY,
+    action TEXT NOT NULL DEFAULT 'mask',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT banned_words_action_check
+        CHECK (action IN ('mask', 'reject', 'flag'))
+);
+
+INSERT INTO banned_words (word, action, created_at, updated_at)
+VALUES
+    ('kerfuffle', 'mask', NOW(), NOW()),
+    ('sharbert', 'mask', NOW(), NOW()),
+    ('fornax', 'mask', NOW(), NOW());
+
+-- +goose Down
+DROP TABLE banned_words;
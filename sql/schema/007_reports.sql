@@ -0,0 +1,32 @@
+-- +goose Up
+-- Chirps the profanity filter flags for review are filed as reports without
+-- a reporter, so that the moderation queue can take them over.
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    reporter_id UUID,
+    target_type TEXT NOT NULL,
+    chirp_id UUID,
+    user_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    CONSTRAINT fk_reporter
+        FOREIGN KEY (reporter_id)
+        REFERENCES users(id)
+        ON DELETE SET NULL,
+    CONSTRAINT fk_chirp
+        FOREIGN KEY (chirp_id)
+        REFERENCES chirp(id)
+        ON DELETE SET NULL,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX reports_status_created_at_idx ON reports (status, created_at);
+
+-- +goose Down
+DROP TABLE reports;
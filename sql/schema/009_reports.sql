@@ -0,0 +1,26 @@
+-- +goose Up
+-- Reports filed by users are resolved by moderators.
+ALTER TABLE reports
+    ADD COLUMN resolved_by UUID,
+    ADD COLUMN resolved_at TIMESTAMP,
+    ADD COLUMN resolution TEXT NOT NULL DEFAULT '',
+    ADD CONSTRAINT fk_resolved_by
+        FOREIGN KEY (resolved_by)
+        REFERENCES users(id)
+        ON DELETE SET NULL,
+    ADD CONSTRAINT reports_target_type_check
+        CHECK (target_type IN ('chirp', 'user')),
+    ADD CONSTRAINT reports_reason_check
+        CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'impersonation', 'profanity', 'other')),
+    ADD CONSTRAINT reports_status_check
+        CHECK (status IN ('open', 'actioned', 'dismissed'));
+
+-- +goose Down
+ALTER TABLE reports
+    DROP CONSTRAINT reports_status_check,
+    DROP CONSTRAINT reports_reason_check,
+    DROP CONSTRAINT reports_target_type_check,
+    DROP CONSTRAINT fk_resolved_by,
+    DROP COLUMN resolution,
+    DROP COLUMN resolved_at,
+    DROP COLUMN resolved_by;
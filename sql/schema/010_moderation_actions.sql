@@ -0,0 +1,29 @@
+-- +goose Up 
+CREATE TABLE moderation_actions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    report_id UUID,
+    moderator_id UUID,
+    action TEXT NOT NULL,
+    target_user_id UUID NOT NULL,
+    target_chirp_id UUID,
+    reason TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    CONSTRAINT fk_report
+        FOREIGN KEY (report_id)
+        REFERENCES reports(id)
+        ON DELETE SET NULL,
+    CONSTRAINT fk_moderator
+        FOREIGN KEY (moderator_id)
+        REFERENCES users(id)
+        ON DELETE SET NULL,
+    CONSTRAINT fk_target_user
+        FOREIGN KEY (target_user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    CONSTRAINT moderation_actions_action_check
+        CHECK (action IN ('hide_chirp', 'delete_chirp', 'warn', 'suspend', 'ban'))
+);
+
+-- +goose Down
+DROP TABLE moderation_actions;
@@ -0,0 +1,7 @@
+-- +goose Up 
+ALTER TABLE chirp
+ADD COLUMN hidden_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE chirp
+DROP COLUMN hidden_at;
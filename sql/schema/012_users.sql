@@ -0,0 +1,38 @@
+-- +goose Up 
+ALTER TABLE users
+ADD COLUMN status TEXT NOT NULL DEFAULT 'active'
+    CONSTRAINT users_status_check CHECK (status IN ('active', 'suspended', 'banned', 'shadow_banned')),
+ADD COLUMN suspended_until TIMESTAMP;
+
+-- Apply bans and suspensions moderators have already recorded.
+UPDATE users
+SET status = 'banned'
+WHERE id IN (SELECT target_user_id FROM moderation_actions WHERE action = 'ban');
+
+UPDATE users
+SET status = 'suspended', suspended_until = s.expires_at
+FROM (
+    SELECT target_user_id, MAX(expires_at) AS expires_at
+    FROM moderation_actions
+    WHERE action = 'suspend'
+    GROUP BY target_user_id
+) AS s
+WHERE users.id = s.target_user_id AND users.status = 'active' AND s.expires_at > NOW();
+
+ALTER TABLE moderation_actions
+DROP CONSTRAINT moderation_actions_action_check,
+ADD CONSTRAINT moderation_actions_action_check
+    CHECK (action IN ('hide_chirp', 'delete_chirp', 'warn', 'suspend', 'ban', 'shadow_ban', 'reinstate'));
+
+-- +goose Down
+DELETE FROM moderation_actions
+WHERE action IN ('shadow_ban', 'reinstate');
+
+ALTER TABLE moderation_actions
+DROP CONSTRAINT moderation_actions_action_check,
+ADD CONSTRAINT moderation_actions_action_check
+    CHECK (action IN ('hide_chirp', 'delete_chirp', 'warn', 'suspend', 'ban'));
+
+ALTER TABLE users
+DROP COLUMN suspended_until,
+DROP COLUMN status;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- An account deletion waits out a grace period, during which signing in
+-- cancels it, before the account is deleted for good.
+CREATE TABLE account_deletions (
+    user_id UUID PRIMARY KEY,
+    requested_at TIMESTAMP NOT NULL,
+    delete_after TIMESTAMP NOT NULL,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX account_deletions_delete_after_idx ON account_deletions (delete_after);
+
+-- +goose Down
+DROP TABLE account_deletions;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- A data export is built in the background. The archive is kept in the
+-- database until it expires, so that any instance can serve the download.
+CREATE TABLE data_exports (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+    created_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    archive BYTEA,
+    error TEXT NOT NULL DEFAULT '',
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX data_exports_user_id_idx ON data_exports (user_id, created_at);
+CREATE INDEX data_exports_status_idx ON data_exports (status, created_at);
+
+-- +goose Down
+DROP TABLE data_exports;
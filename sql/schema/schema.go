@@ -0,0 +1,10 @@
+// Package schema embeds the goose migrations in this directory so that the
+// binary can apply them itself.
+package schema
+
+import "embed"
+
+// FS holds the migrations, named <version>_<name>.sql.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/Vikuuu/Chirpy/internal/config"
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/migrate"
+	"github.com/Vikuuu/Chirpy/internal/store"
+)
+
+// newStore opens the configured store. For Postgres it applies pending
+// migrations if c.AutoMigrate is set, refuses a schema this build doesn't
+// match, and returns the migrator too; queries go through instrument, if
+// given. The other stores make their own schema and have no migrator.
+func newStore(ctx context.Context, c config.Database, instrument func(database.DBTX) database.DBTX) (store.Store, *migrate.Migrator, error) {
+	switch c.Store {
+	case config.StoreMemory:
+		return store.NewMemory(), nil, nil
+	case config.StoreSQLite:
+		s, err := store.OpenSQLite(ctx, c.Path)
+		return s, nil, err
+	}
+
+	db, err := sql.Open("postgres", c.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connecting to DB: %w", err)
+	}
+	migrator, err := preparePostgres(ctx, db, c)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return store.NewPostgres(db, instrument), migrator, nil
+}
+
+// preparePostgres migrates db if c asks for it and checks its schema, which
+// may only be ahead of this build's if c allows it.
+func preparePostgres(ctx context.Context, db *sql.DB, c config.Database) (*migrate.Migrator, error) {
+	migrator, err := migrate.New(db)
+	if err != nil {
+		return nil, err
+	}
+	migrator.AllowNewer = c.AllowNewerSchema
+	if c.AutoMigrate {
+		results, err := migrator.Up(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range results {
+			slog.Info("applied migration", "version", r.Source.Version, "duration", r.Duration)
+		}
+	}
+	return migrator, migrator.Check(ctx)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Vikuuu/Chirpy/internal/config"
+)
+
+// queryTimeouts holds the timeout for each named route; the "" key is the
+// default.
+type queryTimeouts map[string]time.Duration
+
+func (t queryTimeouts) get(route string) time.Duration {
+	if d, ok := t[route]; ok {
+		return d
+	}
+	return t[""]
+}
+
+// newQueryTimeouts builds the per-route timeouts from c.
+func newQueryTimeouts(c config.Queries) queryTimeouts {
+	timeouts := queryTimeouts{"": c.Timeout}
+	for route, d := range c.Routes {
+		timeouts[route] = d
+	}
+	return timeouts
+}
+
+// route adapts h for the named route: its queries run under the route's
+// timeout, what it audits is attributed to the request's origin, and its
+// errors are answered by handle.
+func (cfg *apiConfig) route(name string, h apiHandler) http.HandlerFunc {
+	return withTimeout(cfg.queryTimeouts.get(name), cfg.withOrigin(cfg.handle(h)))
+}
+
+// streamRoute adapts h as route does, but for a handler streaming a response
+// too long to bound as a whole: h runs without a request-wide timeout, and
+// is given the route's to bound each of its queries by.
+func (cfg *apiConfig) streamRoute(name string, h func(queryTimeout time.Duration) apiHandler) http.HandlerFunc {
+	return cfg.withOrigin(cfg.handle(h(cfg.queryTimeouts.get(name))))
+}
+
+// withTimeout cancels the request context, and so any query still running
+// under it, after d.
+func withTimeout(d time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	if d <= 0 {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Vikuuu/Chirpy/internal/config"
+	"github.com/Vikuuu/Chirpy/internal/metrics"
+)
+
+func TestHandleCountsOutcomes(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		cancel    bool
+		expected  int
+		failed    float64
+		cancelled float64
+		timedOut  float64
+	}{
+		{name: "Failure", err: errors.New("pq: connection refused"), expected: http.StatusInternalServerError, failed: 1},
+		{name: "Client Cancelled", err: fmt.Errorf("query: %w", context.Canceled), expected: statusClientClosedRequest, cancelled: 1},
+		{name: "Driver Error After Cancel", err: fmt.Errorf("pq: canceling statement due to user request"), cancel: true, expected: statusClientClosedRequest, cancelled: 1},
+		{name: "Timed Out", err: fmt.Errorf("query: %w", context.DeadlineExceeded), expected: http.StatusServiceUnavailable, timedOut: 1},
+		{name: "Validation Is Not Counted", err: errValidation("bad"), expected: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &apiConfig{metrics: metrics.New()}
+			h := cfg.handle(func(w http.ResponseWriter, r *http.Request) error {
+				return tt.err
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+			rec := httptest.NewRecorder()
+
+			h(rec, httptest.NewRequest("GET", "/api/chirps", nil).WithContext(ctx))
+
+			assert.Equal(t, tt.expected, rec.Code)
+			assert.Equal(t, tt.failed, cfg.metrics.Total("chirpy_http_request_errors_total", "kind", metrics.ErrorFailed))
+			assert.Equal(t, tt.cancelled, cfg.metrics.Total("chirpy_http_request_errors_total", "kind", metrics.ErrorCancelled))
+			assert.Equal(t, tt.timedOut, cfg.metrics.Total("chirpy_http_request_errors_total", "kind", metrics.ErrorTimedOut))
+		})
+	}
+}
+
+func TestRouteTimeout(t *testing.T) {
+	cfg := &apiConfig{metrics: metrics.New(), queryTimeouts: queryTimeouts{"": time.Hour, "chirps_list": 10 * time.Millisecond}}
+	h := cfg.route("chirps_list", func(w http.ResponseWriter, r *http.Request) error {
+		<-r.Context().Done()
+		return r.Context().Err()
+	})
+	rec := httptest.NewRecorder()
+
+	h(rec, httptest.NewRequest("GET", "/api/chirps", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, 1.0, cfg.metrics.Total("chirpy_http_request_errors_total", "kind", metrics.ErrorTimedOut))
+}
+
+func TestNewQueryTimeouts(t *testing.T) {
+	timeouts := newQueryTimeouts(config.Queries{
+		Timeout: 3 * time.Second,
+		Routes:  map[string]time.Duration{"chirps_list": 500 * time.Millisecond},
+	})
+
+	assert.Equal(t, 3*time.Second, timeouts.get("login"))
+	assert.Equal(t, 500*time.Millisecond, timeouts.get("chirps_list"))
+}
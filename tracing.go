@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Vikuuu/Chirpy/internal/config"
+	"github.com/Vikuuu/Chirpy/internal/tracing"
+)
+
+// tracer spans work done in this package outside the handlers' own spans.
+var tracer = otel.Tracer("github.com/Vikuuu/Chirpy")
+
+// newTracerProvider builds the provider for the configured exporter; the
+// console exporter writes spans to stdout.
+func newTracerProvider(ctx context.Context, c config.Tracing) (*sdktrace.TracerProvider, error) {
+	exp, err := tracing.NewExporter(ctx, c.Exporter, c.OTLPEndpoint, os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	return tracing.NewProvider(exp), nil
+}
+
+// recordError marks the current span as failed with err.
+func recordError(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Vikuuu/Chirpy/internal/api"
+	"github.com/Vikuuu/Chirpy/internal/database"
+	"github.com/Vikuuu/Chirpy/internal/metrics"
+	"github.com/Vikuuu/Chirpy/internal/service"
+)
+
+func TestCreateUser(t *testing.T) {
+	h := newHarness(t)
+
+	u := h.register("walt@example.com")
+	assert.NotZero(t, u.ID)
+	assert.Equal(t, "walt@example.com", u.Email)
+	assert.False(t, u.IsChirpyRed)
+
+	tests := []struct {
+		name     string
+		body     any
+		expected int
+		code     string
+	}{
+		{name: "Duplicate Email", body: api.CreateUserRequest{Email: "walt@example.com", Password: testPassword}, expected: http.StatusConflict, code: codeConflict},
+		{name: "Invalid Email", body: api.CreateUserRequest{Email: "walt", Password: testPassword}, expected: http.StatusBadRequest, code: codeValidationFailed},
+		{name: "Password Over 72 Bytes", body: api.CreateUserRequest{Email: "skyler@example.com", Password: strings.Repeat("é", 40)}, expected: http.StatusBadRequest, code: codeValidationFailed},
+		{name: "Malformed Body", body: `{"email":`, expected: http.StatusBadRequest, code: codeMalformedBody},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := h.do("POST", "/api/users").json(tt.body).expect(tt.expected).problem()
+			assert.Equal(t, tt.code, p.Code)
+		})
+	}
+
+	// Clients from before passwords were validated may use short ones.
+	h.do("POST", "/api/users").json(api.CreateUserRequest{Email: "jesse@example.com", Password: "short"}).expect(http.StatusCreated)
+}
+
+func TestLogin(t *testing.T) {
+	h := newHarness(t)
+	u := h.signUp("walt@example.com")
+	assert.NotEmpty(t, u.Token)
+	assert.NotEmpty(t, u.RefreshToken)
+	assert.Equal(t, "walt@example.com", u.Email)
+
+	tests := []struct {
+		name     string
+		body     api.LoginRequest
+		expected int
+		code     string
+	}{
+		{name: "Wrong Password", body: api.LoginRequest{Email: "walt@example.com", Password: "wrong-password"}, expected: http.StatusUnauthorized, code: codeUnauthorized},
+		{name: "Unknown Email", body: api.LoginRequest{Email: "jesse@example.com", Password: testPassword}, expected: http.StatusUnauthorized, code: codeUnauthorized},
+		{name: "Missing Password", body: api.LoginRequest{Email: "walt@example.com"}, expected: http.StatusBadRequest, code: codeValidationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := h.do("POST", "/api/login").json(tt.body).expect(tt.expected).problem()
+			assert.Equal(t, tt.code, p.Code)
+		})
+	}
+
+	assert.Equal(t, 1.0, h.cfg.metrics.Total("chirpy_logins_total", "outcome", metrics.LoginSuccess))
+	assert.Equal(t, 2.0, h.cfg.metrics.Total("chirpy_logins_total", "outcome", metrics.LoginFailure))
+}
+
+func TestLoginRestrictedAccount(t *testing.T) {
+	h := newHarness(t)
+	u := h.signUp("walt@example.com")
+
+	tests := []struct {
+		name   string
+		status string
+		until  sql.NullTime
+		detail string
+	}{
+		{name: "Banned", status: service.StatusBanned, detail: "account is banned"},
+		{name: "Suspended", status: service.StatusSuspended, until: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true}, detail: "account is suspended until"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := h.cfg.db.SetUserStatus(context.Background(), database.SetUserStatusParams{Status: tt.status, SuspendedUntil: tt.until, ID: u.ID})
+			require.NoError(t, err)
+
+			for _, req := range []*request{
+				h.do("POST", "/api/login").json(api.LoginRequest{Email: u.Email, Password: testPassword}),
+				h.do("POST", "/api/refresh").bearer(u.RefreshToken),
+				u.do("POST", "/api/chirps").json(api.CreateChirpRequest{Body: "hello"}),
+			} {
+				p := req.expect(http.StatusForbidden).problem()
+				assert.Equal(t, codeAccountRestricted, p.Code)
+				assert.Contains(t, p.Detail, tt.detail)
+			}
+		})
+	}
+}
+
+func TestRefreshAndRevoke(t *testing.T) {
+	h := newHarness(t)
+	u := h.signUp("walt@example.com")
+
+	var refreshed api.Refresh
+	h.do("POST", "/api/refresh").bearer(u.RefreshToken).expect(http.StatusOK).decode(&refreshed)
+	require.NotEmpty(t, refreshed.Token)
+	u.Token = refreshed.Token
+	u.post("written with a refreshed token")
+
+	h.do("POST", "/api/refresh").expect(http.StatusUnauthorized)
+	h.do("POST", "/api/refresh").bearer("not-a-token").expect(http.StatusUnauthorized)
+
+	h.do("POST", "/api/revoke").bearer(u.RefreshToken).expect(http.StatusNoContent)
+	p := h.do("POST", "/api/refresh").bearer(u.RefreshToken).expect(http.StatusUnauthorized).problem()
+	assert.Equal(t, "refresh token expired", p.Detail)
+
+	h.do("POST", "/api/revoke").expect(http.StatusUnauthorized)
+}
+
+func TestUpdateUser(t *testing.T) {
+	h := newHarness(t)
+	u := h.signUp("walt@example.com")
+	h.register("jesse@example.com")
+
+	var updated api.UpdatedUser
+	u.do("PUT", "/api/users").
+		json(api.UpdateUserRequest{Email: "heisenberg@example.com", Password: "new-password"}).
+		expect(http.StatusOK).
+		decode(&updated)
+	assert.Equal(t, "heisenberg@example.com", updated.Email)
+
+	h.do("POST", "/api/login").json(api.LoginRequest{Email: "walt@example.com", Password: testPassword}).expect(http.StatusUnauthorized)
+	h.do("POST", "/api/login").json(api.LoginRequest{Email: "heisenberg@example.com", Password: "new-password"}).expect(http.StatusOK)
+
+	p := u.do("PUT", "/api/users").
+		json(api.UpdateUserRequest{Email: "jesse@example.com", Password: "new-password"}).
+		expect(http.StatusConflict).
+		problem()
+	assert.Equal(t, codeConflict, p.Code)
+
+	p = u.do("PUT", "/api/users").
+		json(api.UpdateUserRequest{Email: "heisenberg@example.com", Password: strings.Repeat("é", 40)}).
+		expect(http.StatusBadRequest).
+		problem()
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, fieldError{Field: "password", Code: "too_long", Message: "password must be at most 72 bytes"}, p.Errors[0])
+
+	h.do("PUT", "/api/users").json(api.UpdateUserRequest{Email: "x@example.com", Password: "new-password"}).expect(http.StatusUnauthorized)
+	h.do("PUT", "/api/users").bearer("not-a-jwt").json(api.UpdateUserRequest{Email: "x@example.com", Password: "new-password"}).expect(http.StatusUnauthorized)
+}
+
+func TestDeleteUser(t *testing.T) {
+	ctx := context.Background()
+	h := newHarness(t, func(cfg *apiConfig) { cfg.deletionGrace = 24 * time.Hour })
+	admin := h.signUp("admin@example.com").as(service.RoleAdmin)
+	u := h.signUp("walt@example.com")
+	c := u.post("say my name")
+
+	p := u.do("DELETE", "/api/users").json(api.DeleteUserRequest{Password: "not-it"}).expect(http.StatusBadRequest).problem()
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, fieldError{Field: "password", Code: "incorrect_password", Message: "password is incorrect"}, p.Errors[0])
+	u.do("DELETE", "/api/users").json(api.DeleteUserRequest{}).expect(http.StatusBadRequest)
+	h.do("DELETE", "/api/users").json(api.DeleteUserRequest{Password: testPassword}).expect(http.StatusUnauthorized)
+
+	var deleted api.DeletedUser
+	u.do("DELETE", "/api/users").json(api.DeleteUserRequest{Password: testPassword}).expect(http.StatusAccepted).decode(&deleted)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), deleted.DeleteAfter, time.Minute)
+	h.do("POST", "/api/refresh").bearer(u.RefreshToken).expect(http.StatusUnauthorized)
+	p = u.do("POST", "/api/chirps").json(api.CreateChirpRequest{Body: "still here"}).expect(http.StatusUnauthorized).problem()
+	assert.Contains(t, p.Detail, "scheduled for deletion", "access tokens stop working too")
+
+	// Logging in during the grace period restores the account.
+	u.login()
+	n, err := h.cfg.svc.PurgeDeletedAccounts(ctx, time.Now().Add(48*time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	h.do("GET", "/api/chirps/"+c.ID.String()).expect(http.StatusOK)
+
+	u.do("DELETE", "/api/users").json(api.DeleteUserRequest{Password: testPassword}).expect(http.StatusAccepted)
+	n, err = h.cfg.svc.PurgeDeletedAccounts(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, n, "not due yet")
+	n, err = h.cfg.svc.PurgeDeletedAccounts(ctx, time.Now().Add(25*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	h.do("GET", "/api/chirps/"+c.ID.String()).expect(http.StatusNotFound)
+	h.do("POST", "/api/login").json(api.LoginRequest{Email: "walt@example.com", Password: testPassword}).expect(http.StatusUnauthorized)
+	tokens, err := h.cfg.db.ListRefreshTokensForUser(ctx, u.ID)
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+
+	events := admin.auditEvents(url.Values{"target_type": {service.AuditTargetUser}, "target_id": {u.ID.String()}}).Events
+	assert.Equal(t, []string{
+		service.AuditUserDelete,
+		service.AuditUserDeleteRequested,
+		service.AuditUserDeletionCancelled,
+		service.AuditUserDeleteRequested,
+	}, actions(events))
+	assert.Nil(t, events[0].ActorID, "the grace period ran out")
+	assert.JSONEq(t, `{"chirps": 1, "refresh_tokens": 2}`, string(events[0].Details))
+}
+
+func TestDeleteUserWithoutGracePeriod(t *testing.T) {
+	h := newHarness(t)
+	u := h.signUp("walt@example.com")
+	c := u.post("say my name")
+
+	u.do("DELETE", "/api/users").json(api.DeleteUserRequest{Password: testPassword}).expect(http.StatusNoContent)
+
+	h.do("GET", "/api/chirps/"+c.ID.String()).expect(http.StatusNotFound)
+	h.do("POST", "/api/login").json(api.LoginRequest{Email: "walt@example.com", Password: testPassword}).expect(http.StatusUnauthorized)
+	u.do("DELETE", "/api/users").json(api.DeleteUserRequest{Password: testPassword}).expect(http.StatusUnauthorized)
+}
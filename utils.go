@@ -2,53 +2,27 @@ package main
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
 	"net/http"
+	"reflect"
 )
 
-func respondWithError(w http.ResponseWriter, code int, msg string) {
-	type respError struct {
-		Error string `json:"error"`
-	}
-	respErr := respError{
-		Error: msg,
-	}
-	errData, err := json.Marshal(respErr)
-	if err != nil {
-		log.Printf("Error marshalling JSON: %s", err)
-		w.WriteHeader(500)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	w.Write(errData)
-}
-
-func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+// respondWithJSON encodes payload as the response body. A nil slice is sent
+// as [] so that clients never have to handle null for an empty list.
+func respondWithJSON[T any](w http.ResponseWriter, code int, payload T) error {
 	var dat []byte
-	var err error
-
-	switch v := payload.(type) {
-	case respBody:
-		// Single respBody case
-		dat, err = json.Marshal(v)
-
-	case []respBody:
-		// Slice of respBody structs
-		dat, err = json.Marshal(v)
-
-	default:
-		// Unsupported type
-		log.Printf("Invalid Payload type")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	if err != nil {
-		log.Printf("Error marshalling JSON: %s", err)
-		w.WriteHeader(500)
-		return
+	if v := reflect.ValueOf(payload); v.Kind() == reflect.Slice && v.IsNil() {
+		dat = []byte("[]")
+	} else {
+		var err error
+		dat, err = json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("marshaling JSON: %w", err)
+		}
 	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 	w.Write(dat)
+	return nil
 }
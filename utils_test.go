@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRespondWithJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  any
+		expected string
+	}{
+		{name: "Nil Slice", payload: []string(nil), expected: `[]`},
+		{name: "Empty Slice", payload: []int{}, expected: `[]`},
+		{name: "Struct", payload: struct {
+			Name string `json:"name"`
+		}{Name: "chirpy"}, expected: `{"name":"chirpy"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			require.NoError(t, respondWithJSON(rec, http.StatusOK, tt.payload))
+
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.expected, rec.Body.String())
+		})
+	}
+}
+
+func TestRespondWithJSONUnencodable(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	err := respondWithJSON(rec, http.StatusOK, map[string]any{"c": make(chan int)})
+
+	assert.Error(t, err)
+	assert.Empty(t, rec.Body.String())
+}
@@ -1,62 +1,62 @@
 package main
 
 import (
-	"context"
-	"database/sql"
-	"encoding/json"
-	"log"
 	"net/http"
 
-	"github.com/google/uuid"
-
+	"github.com/Vikuuu/Chirpy/internal/api"
 	"github.com/Vikuuu/Chirpy/internal/auth"
+	"github.com/Vikuuu/Chirpy/internal/service"
 )
 
-type polkaParams struct {
-	Event string `json:"event"`
-	Data  struct {
-		UserID uuid.UUID `json:"user_id"`
-	} `json:"data"`
-}
+// Webhook outcomes, for the metrics.
+const (
+	webhookUpgraded     = "upgraded"
+	webhookIgnored      = "ignored"
+	webhookUnauthorized = "unauthorized"
+	webhookInvalid      = "invalid"
+	webhookUnknownUser  = "unknown_user"
+	webhookError        = "error"
+)
+
+func (cfg *apiConfig) handlerPolkaWebhook(w http.ResponseWriter, r *http.Request) error {
+	event, outcome := "unknown", webhookError
+	defer func() {
+		cfg.metrics.Webhook(event, outcome)
+	}()
 
-func (cfg *apiConfig) handlerPolkaWebhook(w http.ResponseWriter, r *http.Request) {
 	apiKey, err := auth.GetAPIKey(r.Header)
 	if err != nil {
-		log.Printf("error getting Api Key: %s", err)
-		respondWithError(w, 401, "Unauthorized")
-		return
+		outcome = webhookUnauthorized
+		return errUnauthorized("Unauthorized", err)
 	}
 
 	if apiKey != cfg.polkaKey {
-		respondWithError(w, 401, "Unauthorized")
-		return
+		outcome = webhookUnauthorized
+		return errUnauthorized("Unauthorized", nil)
 	}
 
-	decoder := json.NewDecoder(r.Body)
-	payload := polkaParams{}
-	err = decoder.Decode(&payload)
-	if err != nil {
-		log.Fatalf("error decoding JSON: %s", err)
-		w.WriteHeader(500)
-		return
+	payload := api.PolkaWebhook{}
+	if err := decodeLenientJSON(w, r, &payload); err != nil {
+		outcome = webhookInvalid
+		return err
 	}
 
 	if payload.Event != "user.upgraded" {
-		respondWithError(w, 204, "No Content")
-		return
+		// Polka may send any event; only a fixed set becomes a label.
+		event, outcome = "other", webhookIgnored
+		w.WriteHeader(http.StatusNoContent)
+		return nil
 	}
+	event = payload.Event
 
-	err = cfg.db.UpgradeUserToRed(context.Background(), payload.Data.UserID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			respondWithError(w, 404, "User Not Found")
-			return
-		} else {
-			log.Fatalf("error updating user: %s", err)
-			w.WriteHeader(500)
-			return
+	if err := cfg.svc.UpgradeToRed(r.Context(), payload.Data.UserID); err != nil {
+		if service.KindOf(err) == service.NotFound {
+			outcome = webhookUnknownUser
 		}
+		return err
 	}
 
+	outcome = webhookUpgraded
 	w.WriteHeader(http.StatusNoContent)
+	return nil
 }
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Vikuuu/Chirpy/internal/api"
+	"github.com/Vikuuu/Chirpy/internal/profanity"
+	"github.com/Vikuuu/Chirpy/internal/service"
+)
+
+// loadBannedWords refreshes the profanity filter from the banned_words table.
+func (cfg *apiConfig) loadBannedWords(ctx context.Context) error {
+	words, err := cfg.db.ListBannedWords(ctx)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]profanity.Entry, 0, len(words))
+	for _, w := range words {
+		entries = append(entries, profanity.Entry{
+			Word:   w.Word,
+			Action: profanity.Action(w.Action),
+		})
+	}
+	cfg.filter.Load(entries)
+	return nil
+}
+
+// reloadBannedWords reloads the profanity filter every
+// cfg.wordsReloadInterval until ctx is done, since other instances edit the
+// banned words too.
+func (cfg *apiConfig) reloadBannedWords(ctx context.Context) {
+	ticker := time.NewTicker(cfg.wordsReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := cfg.loadBannedWords(ctx); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "reloading banned words", "err", err)
+			}
+		}
+	}
+}
+
+func (cfg *apiConfig) handlerListWords(w http.ResponseWriter, r *http.Request) error {
+	if _, err := cfg.authorizeRole(r, service.RoleAdmin); err != nil {
+		return err
+	}
+
+	words, err := cfg.db.ListBannedWords(r.Context())
+	if err != nil {
+		return fmt.Errorf("listing banned words: %w", err)
+	}
+
+	return respondWithJSON(w, http.StatusOK, api.NewWords(words))
+}
+
+func (cfg *apiConfig) handlerPutWord(w http.ResponseWriter, r *http.Request) error {
+	admin, err := cfg.authorizeRole(r, service.RoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	params := api.WordRequest{}
+	if err := decodeJSON(w, r, &params); err != nil {
+		return err
+	}
+
+	word := profanity.Normalize(params.Word)
+	if word == "" {
+		return errFields(fieldError{Field: "word", Code: "required", Message: "word is required"})
+	}
+	if params.Action == "" {
+		params.Action = string(profanity.ActionMask)
+	}
+	action, err := profanity.ParseAction(params.Action)
+	if err != nil {
+		return errFields(fieldError{Field: "action", Code: "invalid_choice", Message: "action must be one of mask, reject or flag"})
+	}
+
+	dat, err := cfg.svc.PutBannedWord(r.Context(), admin.ID, word, string(action))
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.loadBannedWords(r.Context()); err != nil {
+		slog.ErrorContext(r.Context(), "reloading banned words", "err", err)
+	}
+
+	return respondWithJSON(w, http.StatusOK, api.NewWord(dat))
+}
+
+func (cfg *apiConfig) handlerDeleteWord(w http.ResponseWriter, r *http.Request) error {
+	admin, err := cfg.authorizeRole(r, service.RoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	word := profanity.Normalize(r.PathValue("word"))
+	if err := cfg.svc.DeleteBannedWord(r.Context(), admin.ID, word); err != nil {
+		return err
+	}
+
+	if err := cfg.loadBannedWords(r.Context()); err != nil {
+		slog.ErrorContext(r.Context(), "reloading banned words", "err", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}